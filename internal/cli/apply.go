@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/persist"
 	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/profile"
 	"github.com/krisk248/tuner/internal/tune"
@@ -20,28 +21,34 @@ var applyCmd = &cobra.Command{
 }
 
 var (
-	applyProfile string
-	applyAuto    bool
+	applyProfile  string
+	applyWorkload string
+	applyAuto     bool
+	applyDryRun   bool
+	applyRollback bool
 )
 
 func init() {
-	applyCmd.Flags().StringVar(&applyProfile, "profile", "", "profile to apply (server, desktop, laptop)")
+	applyCmd.Flags().StringVar(&applyProfile, "profile", "", "profile to apply (server, desktop, laptop, or a profile file name)")
+	applyCmd.Flags().StringVar(&applyWorkload, "workload", "", workloadFlagHelp)
 	applyCmd.Flags().BoolVar(&applyAuto, "auto", false, "apply without confirmation")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "print every file write instead of applying")
+	applyCmd.Flags().BoolVar(&applyRollback, "rollback-on-failure", true, "restore already-written values if any change fails")
 	rootCmd.AddCommand(applyCmd)
 }
 
 func runApply(cmd *cobra.Command, args []string) error {
-	platform.RequireRoot("apply")
+	if !applyDryRun {
+		platform.RequireRoot("apply")
+	}
 
-	var p profile.Profile
-	if applyProfile != "" {
-		p = profile.ForType(profile.Type(applyProfile))
-	} else {
-		p = profile.AutoDetect()
+	p, err := loadProfile(applyProfile, applyWorkload)
+	if err != nil {
+		return err
 	}
 
 	bold := color.New(color.Bold)
-	bold.Printf("Profile: %s\n", p.Type)
+	bold.Printf("Profile: %s\n", profileLabel(p))
 	if p.Type == profile.Laptop {
 		fmt.Printf("Power state: %s\n", p.PowerState)
 	}
@@ -54,6 +61,12 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if applyDryRun {
+		fmt.Printf("\nDry run: %d changes, nothing written.\n\n", len(changes))
+		printWrites(changes)
+		return nil
+	}
+
 	fmt.Printf("\n%d changes to apply:\n\n", len(changes))
 	for _, c := range changes {
 		fmt.Printf("  [%s] %s: %s → %s\n", c.Subsystem, c.Parameter, c.OldValue, c.NewValue)
@@ -70,14 +83,86 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// A plan gone stale since it was computed writes nothing, so it
+	// gets no restore point either.
+	if applyRollback {
+		if f := tune.Check(changes); f != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%s: %v; nothing was applied", f.Change.Parameter, f.Err)
+		}
+	}
+
+	// Record original values first so reset can undo this apply even
+	// if the process dies part way through.
+	gen, err := persist.SaveBackup(persist.BackupData{
+		Command:  "apply",
+		Profile:  p.Name,
+		Workload: string(p.Workload),
+		Values:   tune.Backup(changes),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+	fmt.Printf("Restore point %d saved (undo with: sudo tuner reset --to %d)\n", gen.ID, gen.ID)
+
 	fmt.Println()
-	success, failed := engine.Apply(changes, applyAuto)
+	res := engine.Apply(changes, applyAuto, applyRollback)
 
-	fmt.Printf("\nApplied: %d succeeded, %d failed\n", success, failed)
+	fmt.Printf("\nApplied: %d succeeded, %d failed\n", len(res.Applied), len(res.Failed))
+	for _, f := range res.Failed {
+		color.Red("  %s: %v", f.Change.Parameter, f.Err)
+	}
 
-	if failed > 0 {
+	if res.RolledBack {
+		fmt.Printf("\nRolled back %d writes:\n", len(res.Reverted))
+		for _, w := range res.Reverted {
+			path, old := w.Restore()
+			fmt.Printf("  %s ← %q\n", path, old)
+		}
+		for _, err := range res.RevertErrs {
+			color.Red("  %v", err)
+		}
+		cmd.SilenceUsage = true
+		if len(res.RevertErrs) > 0 {
+			return fmt.Errorf("apply failed and %d values could not be restored; run 'tuner reset'", len(res.RevertErrs))
+		}
+		return fmt.Errorf("apply failed; all changes were rolled back")
+	}
+
+	// The kernel takes any huge page count but reserves only what it
+	// finds contiguous memory for.
+	if short := tune.HugepageShortfalls(res.Applied); len(short) > 0 {
+		fmt.Println()
+		for _, s := range short {
+			color.Yellow("  %s", s)
+		}
+		color.Yellow("Memory is too fragmented to reserve every huge page. Retry after 'echo 1 | sudo tee /proc/sys/vm/compact_memory', or reserve them at boot (hugepagesz=1G hugepages=N on the kernel command line for 1 GB pages).")
+	}
+
+	if len(res.Failed) > 0 {
+		if applyRollback {
+			// Apply's own check failed: nothing was written.
+			cmd.SilenceUsage = true
+			if err := persist.RemoveBackupsFrom(gen.ID); err != nil {
+				return fmt.Errorf("apply failed before any write; could not remove restore point %d: %w", gen.ID, err)
+			}
+			return fmt.Errorf("apply failed before any write; restore point %d removed", gen.ID)
+		}
 		color.Yellow("Some changes failed.")
 	}
 
 	return nil
 }
+
+// printWrites lists each change with the exact bytes it writes to each
+// path, in the order Apply performs them.
+func printWrites(changes []tune.Change) {
+	dim := color.New(color.Faint)
+	for _, c := range changes {
+		fmt.Printf("  [%s] %s: %s → %s\n", c.Subsystem, c.Parameter, c.OldValue, c.NewValue)
+		for _, w := range c.Writes {
+			fmt.Printf("      write %s %q", w.Path, w.Value)
+			dim.Printf("  (was %q)\n", w.Old)
+		}
+	}
+}
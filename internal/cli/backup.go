@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/persist"
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage restore points saved by apply and save",
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List restore points",
+	RunE:  runBackupList,
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old restore points",
+	Long: `Delete old restore points.
+
+The newest restore point and the pristine record (the value of every path
+before tuner first changed it) are always kept, so 'tuner reset' can still
+return the system to its original state.`,
+	RunE: runBackupPrune,
+}
+
+var (
+	pruneKeep      int
+	pruneOlderThan time.Duration
+)
+
+func init() {
+	backupPruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "keep only this many newest restore points")
+	backupPruneCmd.Flags().DurationVar(&pruneOlderThan, "older-than", 0, "delete restore points older than this (e.g. 720h)")
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	gens, err := persist.ListBackups()
+	if err != nil {
+		return err
+	}
+	if len(gens) == 0 {
+		fmt.Printf("No restore points in %s\n", persist.BackupsDir)
+		return nil
+	}
+
+	bold := color.New(color.Bold)
+	bold.Printf("%-4s  %-25s  %-7s  %-20s  %s\n", "ID", "Saved", "Command", "Profile", "Values")
+	for _, g := range gens {
+		name := g.Profile
+		if g.Workload != "" {
+			name += "+" + g.Workload
+		}
+		fmt.Printf("%-4d  %-25s  %-7s  %-20s  %d\n", g.ID, g.Timestamp, g.Command, name, len(g.Values))
+	}
+
+	if pristine, err := persist.LoadPristine(); err == nil {
+		fmt.Printf("\nPristine state: %d values, first recorded %s\n", len(pristine.Values), pristine.Timestamp)
+	}
+	fmt.Println("Restore with: sudo tuner reset --to <id>   (or 'tuner reset' for the pristine state)")
+	return nil
+}
+
+func runBackupPrune(cmd *cobra.Command, args []string) error {
+	if pruneKeep == 0 && pruneOlderThan == 0 {
+		return fmt.Errorf("nothing to prune: pass --keep and/or --older-than")
+	}
+	platform.RequireRoot("backup prune")
+
+	removed, err := persist.PruneBackups(pruneKeep, pruneOlderThan)
+	for _, id := range removed {
+		fmt.Printf("  Removed restore point %d\n", id)
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("No restore points to prune.")
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/daemon"
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/persist"
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep a profile enforced in the background",
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Check for drift every interval and re-apply drifted values",
+	Long: `Check for drift every interval and re-apply drifted values.
+
+Runs in the foreground; 'tuner daemon install' runs it under systemd.
+Each correction is logged to stdout with a syslog priority prefix that
+journald understands. SIGHUP reloads the profile files.`,
+	RunE: runDaemonRun,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what the running daemon enforces and has corrected",
+	RunE:  runDaemonStatus,
+}
+
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install, enable and start " + persist.DaemonUnitName,
+	RunE:  runDaemonInstall,
+}
+
+var daemonUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop, disable and remove " + persist.DaemonUnitName,
+	RunE:  runDaemonUninstall,
+}
+
+var (
+	daemonProfile  string
+	daemonWorkload string
+	daemonInterval time.Duration
+	daemonDryRun   bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{daemonRunCmd, daemonInstallCmd} {
+		c.Flags().StringVar(&daemonProfile, "profile", "", "profile to enforce (server, desktop, laptop, or a profile file name)")
+		c.Flags().StringVar(&daemonWorkload, "workload", "", workloadFlagHelp)
+		c.Flags().DurationVar(&daemonInterval, "interval", daemon.DefaultInterval, "how often to check for drift")
+	}
+	daemonInstallCmd.Flags().BoolVar(&daemonDryRun, "dry-run", false, "print the unit instead of installing it")
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonInstallCmd)
+	daemonCmd.AddCommand(daemonUninstallCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemonRun(cmd *cobra.Command, args []string) error {
+	platform.RequireRoot("daemon run")
+
+	// Log lines go to the journal, where colors are noise.
+	color.NoColor = true
+
+	d, err := daemon.New(daemon.Options{
+		Profile:  daemonProfile,
+		Workload: daemonWorkload,
+		Interval: daemonInterval,
+	}, os.Stdout)
+	if err != nil {
+		return err
+	}
+	return d.Run()
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	s, err := daemon.ReadStatus()
+	if os.IsNotExist(err) {
+		fmt.Println("Daemon is not running.")
+		if persist.DaemonUnitExists() {
+			fmt.Printf("%s is installed; check: systemctl status %s\n", persist.DaemonUnitName, persist.DaemonUnitName)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state, stateStatus := "running", output.StatusGood
+	if !s.Running() {
+		state, stateStatus = "not running (stale status file)", output.StatusWarn
+	}
+	name := s.Profile
+	if s.Workload != "" {
+		name += " (workload: " + s.Workload + ")"
+	}
+
+	sec := output.Section{Title: "Daemon"}
+	sec.Fields = append(sec.Fields,
+		output.Field{Key: "State", Value: state, Status: stateStatus},
+		output.Field{Key: "PID", Value: fmt.Sprintf("%d", s.PID)},
+		output.Field{Key: "Profile", Value: name},
+		output.Field{Key: "Interval", Value: s.Interval},
+	)
+	if s.PowerState != "" {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Power state", Value: s.PowerState})
+	}
+	sec.Fields = append(sec.Fields,
+		output.Field{Key: "Started", Value: s.Started},
+		output.Field{Key: "Last reload", Value: s.LastReload},
+		output.Field{Key: "Last check", Value: s.LastCheck},
+		output.Field{Key: "Checks", Value: fmt.Sprintf("%d", s.Checks)},
+		output.Field{Key: "Corrections", Value: fmt.Sprintf("%d", s.Corrections)},
+	)
+	if s.LastError != "" {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Last error", Value: s.LastError, Status: output.StatusBad})
+	}
+	sections := []output.Section{sec}
+
+	if len(s.Recent) > 0 {
+		recent := output.Section{Title: "Recent Corrections"}
+		for _, c := range s.Recent {
+			f := output.Field{
+				Key:    fmt.Sprintf("%s [%s] %s", c.Time, c.Subsystem, c.Parameter),
+				Value:  fmt.Sprintf("%s → %s", c.From, c.To),
+				Status: output.StatusWarn,
+			}
+			if c.Error != "" {
+				f.Value += " failed: " + c.Error
+				f.Status = output.StatusBad
+			}
+			recent.Fields = append(recent.Fields, f)
+		}
+		sections = append(sections, recent)
+	}
+
+	return output.NewFormatter(outFormat, noColor).Format(os.Stdout, sections)
+}
+
+func runDaemonInstall(cmd *cobra.Command, args []string) error {
+	if !daemonDryRun {
+		platform.RequireRoot("daemon install")
+	}
+
+	// Fail now rather than in a restart loop under systemd.
+	if _, err := loadProfile(daemonProfile, daemonWorkload); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	unit := persist.DaemonArgs{
+		Exe:      exe,
+		Profile:  daemonProfile,
+		Workload: daemonWorkload,
+		Interval: daemonInterval,
+	}
+
+	if daemonDryRun {
+		printFile(persist.DaemonUnitPath(), persist.RenderDaemonUnit(unit))
+		return nil
+	}
+
+	if err := persist.WriteDaemonUnit(unit); err != nil {
+		return fmt.Errorf("failed to write daemon unit: %w", err)
+	}
+	fmt.Printf("  Written %s\n", persist.DaemonUnitPath())
+	if err := persist.StartDaemonUnit(); err != nil {
+		return err
+	}
+	color.Green("Daemon enabled and started. Check it with: tuner daemon status")
+	return nil
+}
+
+func runDaemonUninstall(cmd *cobra.Command, args []string) error {
+	platform.RequireRoot("daemon uninstall")
+	if !persist.DaemonUnitExists() {
+		fmt.Printf("%s is not installed.\n", persist.DaemonUnitName)
+		return nil
+	}
+	return removeDaemonUnit()
+}
+
+// removeDaemonUnit stops the daemon and removes its unit, so it no
+// longer re-applies the profile.
+func removeDaemonUnit() error {
+	if err := persist.StopDaemonUnit(); err != nil {
+		color.Yellow("Warning: failed to stop %s: %v", persist.DaemonUnitName, err)
+	}
+	if err := persist.RemoveDaemonUnit(); err != nil {
+		return fmt.Errorf("failed to remove daemon unit: %w", err)
+	}
+	fmt.Printf("  Removed %s\n", persist.DaemonUnitPath())
+	return persist.ReloadSystemd()
+}
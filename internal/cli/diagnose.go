@@ -26,6 +26,7 @@ var (
 	diagKernel   bool
 	diagGPU      bool
 	diagProfile  string
+	diagSnapshot string
 )
 
 func init() {
@@ -38,10 +39,19 @@ func init() {
 	diagnoseCmd.Flags().BoolVar(&diagKernel, "kernel", false, "show kernel info only")
 	diagnoseCmd.Flags().BoolVar(&diagGPU, "gpu", false, "show GPU info only")
 	diagnoseCmd.Flags().StringVar(&diagProfile, "profile", "", "filter output for profile (laptop, desktop, server, auto)")
+	diagnoseCmd.Flags().StringVar(&diagSnapshot, "from-snapshot", "", "diagnose a snapshot captured with 'tuner snapshot'")
 	rootCmd.AddCommand(diagnoseCmd)
 }
 
 func runDiagnose(cmd *cobra.Command, args []string) error {
+	if diagSnapshot != "" {
+		done, err := replaySnapshot(diagSnapshot)
+		if err != nil {
+			return err
+		}
+		defer done()
+	}
+
 	// Resolve the effective mode
 	mode := resolveMode(diagProfile)
 
@@ -58,15 +68,21 @@ func runDiagnose(cmd *cobra.Command, args []string) error {
 	}
 	if showAll || diagCPU {
 		sections = append(sections, detect.CPUSection(detect.DetectCPU()))
+		sections = append(sections, detect.IdleSection(detect.DetectIdle()))
 	}
 	if showAll || diagMemory {
 		sections = append(sections, detect.MemorySection(detect.DetectMemory()))
+		sections = append(sections, detect.NUMASection(detect.DetectNUMA()))
+		sections = append(sections, detect.HugePagesSection(detect.DetectHugePages()))
 	}
 	if showAll || diagStorage {
-		sections = append(sections, detect.StorageSection(detect.DetectStorage()))
+		sections = append(sections, detect.StorageSection(detect.WithSMART(detect.DetectStorage())))
 	}
 	if showAll || diagNetwork {
 		sections = append(sections, detect.NetworkSection(detect.DetectNetwork(), mode))
+		if irq := detect.DetectIRQ(); len(irq.NICs) > 0 {
+			sections = append(sections, detect.IRQSection(irq))
+		}
 	}
 	if (showAll && mode != output.ModeServer) || diagPower {
 		sections = append(sections, detect.PowerSection(detect.DetectPower()))
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/krisk248/tuner/internal/profile"
@@ -14,7 +15,34 @@ var profileCmd = &cobra.Command{
 	RunE:  runProfile,
 }
 
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List built-in and file-defined profiles",
+	RunE:  runProfileList,
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the values a profile sets",
+	Long: `Show the values a profile sets.
+
+By default only the keys the profile itself defines are printed. With
+--resolved, the full merged profile is printed, following its extends
+chain, along with the built-in profile or file line each value came from.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileShow,
+}
+
+var (
+	profileShowResolved bool
+	profileShowWorkload string
+)
+
 func init() {
+	profileShowCmd.Flags().BoolVar(&profileShowResolved, "resolved", false, "print every value after inheritance, with its source")
+	profileShowCmd.Flags().StringVar(&profileShowWorkload, "workload", "", workloadFlagHelp)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
 	rootCmd.AddCommand(profileCmd)
 }
 
@@ -32,3 +60,130 @@ func runProfile(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	bold := color.New(color.Bold)
+
+	bold.Println("Built-in profiles:")
+	for _, t := range []profile.Type{profile.Server, profile.Desktop, profile.Laptop} {
+		fmt.Printf("  %s\n", t)
+	}
+
+	fmt.Println()
+	bold.Println("Workloads (--workload):")
+	for _, w := range profile.Workloads {
+		fmt.Printf("  %s\n", w)
+	}
+
+	files, errs := profile.ListFiles()
+	if len(files) > 0 {
+		fmt.Println()
+		bold.Println("Profile files:")
+		for _, f := range files {
+			base := string(f.Type)
+			if base == "" {
+				base = "auto"
+			}
+			if f.Extends != "" {
+				base = "extends " + f.Extends
+			}
+			line := fmt.Sprintf("  %-20s %s (type: %s)", f.Name, f.Path, base)
+			if f.Workload != "" {
+				line = fmt.Sprintf("  %-20s %s (type: %s, workload: %s)", f.Name, f.Path, base, f.Workload)
+			}
+			if f.Description != "" {
+				line += " - " + f.Description
+			}
+			fmt.Println(line)
+		}
+	}
+
+	for _, err := range errs {
+		color.Yellow("Warning: %v", err)
+	}
+	return nil
+}
+
+func runProfileShow(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	w, err := profile.ParseWorkload(profileShowWorkload)
+	if err != nil {
+		return err
+	}
+	p, settings, err := profile.Resolve(name, w)
+	if err != nil {
+		return err
+	}
+
+	bold := color.New(color.Bold)
+	bold.Printf("Profile: ")
+	fmt.Println(profileLabel(p))
+
+	var own string
+	if !profile.IsBuiltin(name) {
+		f, err := profile.FindFile(name)
+		if err != nil {
+			return err
+		}
+		own = f.Path + ":"
+		fmt.Printf("File:    %s\n", f.Path)
+		switch {
+		case f.Extends != "":
+			fmt.Printf("Extends: %s\n", f.Extends)
+		case f.Type != "":
+			fmt.Printf("Type:    %s\n", f.Type)
+		default:
+			fmt.Printf("Type:    auto (%s)\n", p.Type)
+		}
+	}
+	fmt.Println()
+
+	width := 0
+	for _, s := range settings {
+		width = max(width, len(s.Key))
+	}
+
+	dim := color.New(color.Faint)
+	shown := 0
+	for _, s := range settings {
+		set := own != "" && strings.HasPrefix(s.Source, own)
+		if !profileShowResolved && own != "" && !set {
+			continue
+		}
+		fmt.Printf("  %-*s = %s", width, s.Key, s.Value)
+		if profileShowResolved {
+			if set {
+				color.New(color.FgGreen).Printf("  [%s]", s.Source)
+			} else {
+				dim.Printf("  [%s]", s.Source)
+			}
+		}
+		fmt.Println()
+		shown++
+	}
+	if shown == 0 {
+		fmt.Println("  (no overrides; use --resolved to see inherited values)")
+	}
+	return nil
+}
+
+// loadProfile resolves the --profile and --workload flag values. An
+// empty name auto-detects the hardware profile; otherwise it names a
+// built-in or file profile.
+func loadProfile(name, workload string) (profile.Profile, error) {
+	w, err := profile.ParseWorkload(workload)
+	if err != nil {
+		return profile.Profile{}, err
+	}
+	return profile.Load(name, w)
+}
+
+// profileLabel names a profile and its workload for headings.
+func profileLabel(p profile.Profile) string {
+	if p.Workload == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (workload: %s)", p.Name, p.Workload)
+}
+
+const workloadFlagHelp = "workload preset to layer on the profile (database, latency, build-host, kvm-host, k8s-node)"
@@ -2,8 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/daemon"
 	"github.com/krisk248/tuner/internal/persist"
 	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/sysfs"
@@ -13,32 +16,109 @@ import (
 var resetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Revert tuning changes from backup",
-	Long:  "Restores original system values from backup and removes persisted configs.",
-	RunE:  runReset,
+	Long: `Restores original system values from backup and removes persisted configs.
+
+Without --to, every value goes back to what it was before tuner first
+changed it. With --to <id>, the system goes back to the state just before
+that restore point (see 'tuner backup list') and later points are dropped.`,
+	RunE: runReset,
 }
 
+var (
+	resetDryRun bool
+	resetTo     int
+)
+
 func init() {
+	resetCmd.Flags().BoolVar(&resetDryRun, "dry-run", false, "print the restore plan instead of restoring")
+	resetCmd.Flags().IntVar(&resetTo, "to", 0, "restore the state just before this restore point instead of the pristine state")
 	rootCmd.AddCommand(resetCmd)
 }
 
 func runReset(cmd *cobra.Command, args []string) error {
-	platform.RequireRoot("reset")
+	if !resetDryRun {
+		platform.RequireRoot("reset")
+	}
 
 	if !persist.BackupExists() {
-		return fmt.Errorf("no backup found at %s. Nothing to reset", persist.BackupFile)
+		return fmt.Errorf("no backup found in %s. Nothing to reset", persist.BackupsDir)
 	}
 
-	backup, err := persist.LoadBackup()
-	if err != nil {
-		return fmt.Errorf("failed to load backup: %w", err)
+	var (
+		label         string
+		values        map[string]string
+		removeConfigs = true
+	)
+	if resetTo == 0 {
+		pristine, err := persist.LoadPristine()
+		if err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		label = "pristine state (before tuner first changed each value)"
+		values = pristine.Values
+	} else {
+		gen, err := persist.LoadBackup(resetTo)
+		if err != nil {
+			return err
+		}
+		if values, err = persist.RestorePlan(resetTo); err != nil {
+			return fmt.Errorf("failed to load backup: %w", err)
+		}
+		label = fmt.Sprintf("restore point %d (%s of %s, %s)", gen.ID, gen.Command, gen.Profile, gen.Timestamp)
+
+		// Persisted configs only go away if a save is being undone.
+		gens, err := persist.ListBackups()
+		if err != nil {
+			return err
+		}
+		removeConfigs = false
+		for _, g := range gens {
+			if g.ID >= resetTo && g.Command == "save" {
+				removeConfigs = true
+			}
+		}
 	}
 
-	fmt.Printf("Restoring values from backup (profile: %s, saved: %s)\n\n", backup.Profile, backup.Timestamp)
+	if resetDryRun {
+		fmt.Printf("Dry run: restore plan for %s\n\n", label)
+		printRestorePlan(values)
+		fmt.Println()
+		if removeConfigs {
+			fmt.Printf("Would remove %s and %s\n", persist.SysctlPath(), persist.UdevPath())
+			fmt.Println("Then run: sysctl --system; udevadm control --reload-rules; udevadm trigger")
+			fmt.Printf("Would remove %s if present\n", persist.TmpfilesPath())
+			if persist.BootUnitExists() {
+				fmt.Printf("Would disable and remove %s\n", persist.BootUnitPath())
+			}
+		}
+		if persist.DaemonUnitExists() {
+			fmt.Printf("Would stop and remove %s\n", persist.DaemonUnitPath())
+		}
+		if resetTo == 0 {
+			fmt.Printf("Would remove all restore points in %s\n", persist.BackupsDir)
+		} else {
+			fmt.Printf("Would remove restore points %d and later\n", resetTo)
+		}
+		return nil
+	}
+
+	fmt.Printf("Restoring %s\n\n", label)
+
+	// The daemon would put the profile straight back.
+	if persist.DaemonUnitExists() {
+		if err := removeDaemonUnit(); err != nil {
+			color.Yellow("Warning: %v", err)
+		}
+		fmt.Println()
+	} else if s, err := daemon.ReadStatus(); err == nil && s.Running() {
+		color.Yellow("Warning: tuner daemon (pid %d) is running and will re-apply %s; stop it first.", s.PID, s.Profile)
+	}
 
 	// Restore original values
 	restored := 0
 	failed := 0
-	for path, value := range backup.Values {
+	for _, path := range sortedKeys(values) {
+		value := values[path]
 		if !sysfs.Exists(path) {
 			continue
 		}
@@ -54,28 +134,100 @@ func runReset(cmd *cobra.Command, args []string) error {
 
 	// Remove persisted configs
 	fmt.Println()
-	if err := persist.RemoveSysctl(); err != nil {
-		color.Yellow("Warning: failed to remove sysctl config: %v", err)
-	} else {
-		fmt.Printf("  Removed %s\n", persist.SysctlPath())
+	if removeConfigs {
+		if err := persist.RemoveSysctl(); err != nil {
+			color.Yellow("Warning: failed to remove sysctl config: %v", err)
+		} else {
+			fmt.Printf("  Removed %s\n", persist.SysctlPath())
+		}
+
+		if err := persist.RemoveUdev(); err != nil {
+			color.Yellow("Warning: failed to remove udev rules: %v", err)
+		} else {
+			fmt.Printf("  Removed %s\n", persist.UdevPath())
+		}
+
+		if err := persist.RemoveTmpfiles(); err != nil {
+			color.Yellow("Warning: failed to remove tmpfiles.d config: %v", err)
+		} else {
+			fmt.Printf("  Removed %s\n", persist.TmpfilesPath())
+		}
+
+		if persist.BootUnitExists() {
+			if err := persist.DisableBootUnit(); err != nil {
+				color.Yellow("Warning: failed to disable %s: %v", persist.BootUnitName, err)
+			}
+			if err := persist.RemoveBootUnit(); err != nil {
+				color.Yellow("Warning: failed to remove boot unit: %v", err)
+			} else {
+				fmt.Printf("  Removed %s\n", persist.BootUnitPath())
+			}
+			persist.ReloadSystemd()
+		}
+
+		// Reload
+		persist.ReloadSysctl()
+		persist.ReloadUdev()
+
+		if resetTo != 0 {
+			color.Yellow("  Persisted configs removed; run 'tuner save' to persist the restored state.")
+		}
 	}
 
-	if err := persist.RemoveUdev(); err != nil {
-		color.Yellow("Warning: failed to remove udev rules: %v", err)
+	// Drop the restore points that have now been undone
+	var err error
+	if resetTo == 0 {
+		err = persist.RemoveAllBackups()
 	} else {
-		fmt.Printf("  Removed %s\n", persist.UdevPath())
+		err = persist.RemoveBackupsFrom(resetTo)
 	}
-
-	// Reload
-	persist.ReloadSysctl()
-	persist.ReloadUdev()
-
-	// Remove backup
-	if err := persist.RemoveBackup(); err != nil {
-		color.Yellow("Warning: failed to remove backup: %v", err)
+	if err != nil {
+		color.Yellow("Warning: failed to remove backups: %v", err)
 	}
 
 	fmt.Printf("\nRestored %d values, %d failed\n", restored, failed)
 	color.Green("Reset complete.")
 	return nil
 }
+
+// printRestorePlan lists path → value writes in the order reset makes them.
+func printRestorePlan(values map[string]string) {
+	if len(values) == 0 {
+		fmt.Println("  (nothing)")
+		return
+	}
+	dim := color.New(color.Faint)
+	for _, path := range sortedKeys(values) {
+		fmt.Printf("  write %s %q", path, values[path])
+		if !sysfs.Exists(path) {
+			dim.Print("  (path missing, skipped)")
+		}
+		fmt.Println()
+	}
+}
+
+// sortedKeys orders restore paths by name, except that the P-state mode
+// comes first, as switching it resets every cpufreq policy, followed by
+// schedulers and governors, which reset nr_requests and EPP.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	rank := func(path string) int {
+		switch {
+		case path == sysfs.IntelPStateStatus || path == sysfs.AMDPStateStatus:
+			return 0
+		case strings.HasSuffix(path, "/queue/scheduler") || strings.HasSuffix(path, "/scaling_governor"):
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := rank(keys[i]), rank(keys[j]); ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/sysfs"
 	"github.com/spf13/cobra"
 )
 
@@ -9,6 +10,7 @@ var (
 	version   = "dev"
 	noColor   bool
 	outFormat string
+	sysroot   string
 )
 
 // SetVersion sets the version string from main.
@@ -24,12 +26,14 @@ var rootCmd = &cobra.Command{
 		if noColor {
 			color.NoColor = true
 		}
+		sysfs.SetRoot(sysroot)
 	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
 	rootCmd.PersistentFlags().StringVarP(&outFormat, "format", "f", "table", "output format (table, json, markdown)")
+	rootCmd.PersistentFlags().StringVar(&sysroot, "sysroot", "/", "read /sys and /proc from this directory instead of the live system")
 	rootCmd.Version = version
 }
 
@@ -2,8 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/persist"
 	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/profile"
@@ -18,37 +20,69 @@ var saveCmd = &cobra.Command{
 	RunE:  runSave,
 }
 
-var saveProfile string
+var (
+	saveProfile  string
+	saveWorkload string
+	saveDryRun   bool
+)
 
 func init() {
-	saveCmd.Flags().StringVar(&saveProfile, "profile", "", "profile to save (server, desktop, laptop)")
+	saveCmd.Flags().StringVar(&saveProfile, "profile", "", "profile to save (server, desktop, laptop, or a profile file name)")
+	saveCmd.Flags().StringVar(&saveWorkload, "workload", "", workloadFlagHelp)
+	saveCmd.Flags().BoolVar(&saveDryRun, "dry-run", false, "print the files that would be written instead of writing them")
 	rootCmd.AddCommand(saveCmd)
 }
 
 func runSave(cmd *cobra.Command, args []string) error {
-	platform.RequireRoot("save")
+	if !saveDryRun {
+		platform.RequireRoot("save")
+	}
 
-	var p profile.Profile
-	if saveProfile != "" {
-		p = profile.ForType(profile.Type(saveProfile))
-	} else {
-		p = profile.AutoDetect()
+	p, err := loadProfile(saveProfile, saveWorkload)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Saving tuning for profile: %s\n", p.Type)
+	fmt.Printf("Saving tuning for profile: %s\n", profileLabel(p))
 
 	// Backup current values before persisting
 	engine := tune.NewEngine(p)
 	changes := engine.ComputeChanges()
 	backup := tune.Backup(changes)
+	mem, numa := detect.DetectMemory(), detect.DetectNUMA()
 
-	if err := persist.SaveBackup(string(p.Type), backup); err != nil {
+	if saveDryRun {
+		fmt.Printf("\nDry run: nothing written.\n\n")
+		fmt.Printf("A new restore point in %s would record:\n", persist.BackupsDir)
+		printRestorePlan(backup)
+		printFile(persist.SysctlPath(), persist.RenderSysctl(p, mem, numa))
+		printFile(persist.UdevPath(), persist.RenderUdev(p))
+		if tmpfiles := persist.RenderTmpfiles(p); tmpfiles != "" {
+			printFile(persist.TmpfilesPath(), tmpfiles)
+		}
+		if unit := persist.RenderBootUnit(p); unit != "" {
+			printFile(persist.BootUnitPath(), unit)
+			fmt.Printf("\nThen run: sysctl --system; udevadm control --reload-rules; udevadm trigger; systemctl daemon-reload; systemctl enable %s\n", persist.BootUnitName)
+		} else {
+			fmt.Println("\nThen run: sysctl --system; udevadm control --reload-rules; udevadm trigger")
+		}
+		printCmdlineProposal(p)
+		return nil
+	}
+
+	gen, err := persist.SaveBackup(persist.BackupData{
+		Command:  "save",
+		Profile:  p.Name,
+		Workload: string(p.Workload),
+		Values:   backup,
+	})
+	if err != nil {
 		return fmt.Errorf("failed to save backup: %w", err)
 	}
-	fmt.Printf("  Backup saved to %s\n", persist.BackupFile)
+	fmt.Printf("  Restore point %d saved in %s\n", gen.ID, persist.BackupsDir)
 
 	// Write sysctl drop-in
-	if err := persist.WriteSysctl(p); err != nil {
+	if err := persist.WriteSysctl(p, mem, numa); err != nil {
 		return fmt.Errorf("failed to write sysctl config: %w", err)
 	}
 	fmt.Printf("  Written %s\n", persist.SysctlPath())
@@ -59,6 +93,24 @@ func runSave(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("  Written %s\n", persist.UdevPath())
 
+	// Write tmpfiles.d entries for THP and zswap
+	wroteTmpfiles, err := persist.WriteTmpfiles(p)
+	if err != nil {
+		return fmt.Errorf("failed to write tmpfiles.d config: %w", err)
+	}
+	if wroteTmpfiles {
+		fmt.Printf("  Written %s\n", persist.TmpfilesPath())
+	}
+
+	// Write boot unit for CPU values
+	wroteUnit, err := persist.WriteBootUnit(p)
+	if err != nil {
+		return fmt.Errorf("failed to write boot unit: %w", err)
+	}
+	if wroteUnit {
+		fmt.Printf("  Written %s\n", persist.BootUnitPath())
+	}
+
 	// Reload
 	if err := persist.ReloadSysctl(); err != nil {
 		color.Yellow("Warning: failed to reload sysctl: %v", err)
@@ -66,7 +118,43 @@ func runSave(cmd *cobra.Command, args []string) error {
 	if err := persist.ReloadUdev(); err != nil {
 		color.Yellow("Warning: failed to reload udev: %v", err)
 	}
+	if wroteUnit {
+		if err := persist.EnableBootUnit(); err != nil {
+			color.Yellow("Warning: failed to enable %s: %v", persist.BootUnitName, err)
+		}
+	}
 
 	color.Green("Tuning persisted successfully.")
+	printCmdlineProposal(p)
 	return nil
 }
+
+// printCmdlineProposal shows kernel parameters that only take full
+// effect at boot, with the distribution's way to set them.
+func printCmdlineProposal(p profile.Profile) {
+	prop := persist.ProposeCmdline(p)
+	if prop.Empty() {
+		return
+	}
+
+	bold := color.New(color.Bold)
+	fmt.Println()
+	bold.Println("Proposed kernel command line (not applied):")
+	color.Red("  - %s", prop.Current)
+	color.Green("  + %s", prop.Proposed)
+	fmt.Println()
+	for _, line := range persist.CmdlineInstructions(platform.DetectDistro(), prop) {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+// printFile shows the full contents a file would be written with.
+func printFile(path, content string) {
+	bold := color.New(color.Bold)
+	fmt.Println()
+	bold.Printf("--- %s\n", path)
+	fmt.Print(content)
+	if !strings.HasSuffix(content, "\n") {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture system state for offline diagnosis",
+	Long: "Archives every file and command output that diagnose and suggest read into a single tarball.\n" +
+		"Replay it elsewhere with 'tuner diagnose --from-snapshot FILE' or 'tuner suggest --from-snapshot FILE'.",
+	RunE: runSnapshot,
+}
+
+var snapshotOutput string
+
+func init() {
+	snapshotCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "", "output file (default tuner-snapshot-<host>-<time>.tar.gz)")
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) error {
+	file := snapshotOutput
+	if file == "" {
+		host, _ := os.Hostname()
+		file = fmt.Sprintf("tuner-snapshot-%s-%s.tar.gz", host, time.Now().Format("20060102-150405"))
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer f.Close()
+
+	m, err := snapshot.Capture(f, version)
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("failed to capture snapshot: %w", err)
+	}
+
+	color.Green("Snapshot written to %s", file)
+	fmt.Printf("  %d files, %d commands captured from %s\n", m.Files, m.Commands, m.Hostname)
+	return nil
+}
+
+// replaySnapshot switches detection to a captured snapshot. The returned
+// function restores the live system and must be called when done.
+func replaySnapshot(file string) (func(), error) {
+	if sysroot != "/" {
+		return nil, fmt.Errorf("--from-snapshot cannot be combined with --sysroot")
+	}
+
+	s, err := snapshot.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	s.Activate()
+
+	bold := color.New(color.Bold)
+	bold.Fprintf(os.Stderr, "Replaying snapshot of %s taken %s\n\n", s.Manifest.Hostname, s.Manifest.Created)
+
+	return func() { s.Close() }, nil
+}
@@ -3,12 +3,17 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/persist"
 	"github.com/krisk248/tuner/internal/profile"
 	"github.com/krisk248/tuner/internal/sysfs"
+	"github.com/krisk248/tuner/internal/tune"
 	"github.com/spf13/cobra"
 )
 
@@ -18,21 +23,43 @@ var suggestCmd = &cobra.Command{
 	RunE:  runSuggest,
 }
 
-var suggestProfile string
+var (
+	suggestProfile  string
+	suggestWorkload string
+	suggestSnapshot string
+	suggestFstab    bool
+)
 
 func init() {
-	suggestCmd.Flags().StringVar(&suggestProfile, "profile", "", "profile to suggest for (server, desktop, laptop)")
+	suggestCmd.Flags().StringVar(&suggestProfile, "profile", "", "profile to suggest for (server, desktop, laptop, or a profile file name)")
+	suggestCmd.Flags().StringVar(&suggestWorkload, "workload", "", workloadFlagHelp)
+	suggestCmd.Flags().StringVar(&suggestSnapshot, "from-snapshot", "", "suggest changes for a snapshot captured with 'tuner snapshot'")
+	suggestCmd.Flags().BoolVar(&suggestFstab, "fstab-diff", false, "print the proposed /etc/fstab mount option changes as a diff (never applied)")
 	rootCmd.AddCommand(suggestCmd)
 }
 
 func runSuggest(cmd *cobra.Command, args []string) error {
-	var p profile.Profile
-	if suggestProfile != "" {
-		p = profile.ForType(profile.Type(suggestProfile))
-	} else {
-		p = profile.AutoDetect()
+	if suggestSnapshot != "" {
+		done, err := replaySnapshot(suggestSnapshot)
+		if err != nil {
+			return err
+		}
+		defer done()
+	}
+
+	p, err := loadProfile(suggestProfile, suggestWorkload)
+	if err != nil {
+		return err
+	}
+	if suggestFstab {
+		return printFstabDiff(p)
+	}
+	if suggestProfile == "" {
 		bold := color.New(color.Bold)
 		bold.Printf("Auto-detected profile: %s\n", p.Type)
+		if p.Workload != "" {
+			bold.Printf("Workload: %s\n", p.Workload)
+		}
 		if p.PowerState != "" {
 			bold.Printf("Power state: %s\n", p.PowerState)
 		}
@@ -65,7 +92,7 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 
 	// Server-specific suggestions
 	if p.Type == profile.Server {
-		if sec := suggestServer(); len(sec.Fields) > 0 {
+		if sec := suggestServer(p.Values); len(sec.Fields) > 0 {
 			sections = append(sections, sec)
 		}
 	}
@@ -184,7 +211,9 @@ func buildSuggestions(p profile.Profile, skipCPU bool) []output.Section {
 	for _, sec := range []output.Section{
 		suggestMemory(p),
 		suggestStorage(p),
+		suggestMounts(p),
 		suggestNetwork(p),
+		suggestKernel(p),
 	} {
 		if len(sec.Fields) > 0 {
 			sections = append(sections, sec)
@@ -231,40 +260,45 @@ func suggestCPU(p profile.Profile) output.Section {
 	sec := output.Section{Title: "CPU Changes"}
 	v := p.Values
 
-	if gov, err := sysfs.ReadString(sysfs.CPUGovernor); err == nil && gov != v.Governor {
-		s := suggestion{
-			key: "Governor", current: gov, target: v.Governor,
-		}
-		switch {
-		case v.Governor == "schedutil":
-			s.reason = "Dynamic scaling saves power when CPU is idle"
-			s.benefit = "~10-15% battery improvement, similar peak performance"
-		case v.Governor == "performance":
-			s.reason = "Fixed max frequency for maximum throughput"
-			s.benefit = "Lowest latency, best for servers and heavy workloads"
-		case v.Governor == "powersave":
-			s.reason = "Minimum frequency to extend battery life"
-			s.benefit = "Maximum battery savings on battery power"
-		}
+	for _, problem := range tune.CPUProblems(v) {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Profile", Value: problem, Status: output.StatusWarn})
+	}
+
+	pstate := detect.DetectPState()
+	policies := detect.DetectCPUPolicies()
+	if mode := v.PStateMode; mode != "" && mode != pstate.Status && slices.Contains(pstate.Modes(), mode) {
+		hwp := slices.ContainsFunc(policies, func(p detect.CPUPolicy) bool { return p.EPP != "" })
+		s := suggestion{key: "P-state Mode", current: pstate.Driver + " " + pstate.Status, target: mode}
+		s.reason, s.benefit = pstateModeReason(pstate.Driver, mode, hwp)
 		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
-	if epp, err := sysfs.ReadString(sysfs.CPUEPP); err == nil && epp != v.EPP {
-		s := suggestion{
-			key: "Energy Perf Pref", current: epp, target: v.EPP,
+	for _, class := range detect.CoreClasses(policies) {
+		group := detect.PoliciesOf(policies, class)
+		c := v.Cores(class)
+		if !tune.GovernorAvailable(v, pstate, group[0], c.Governor) {
+			c.Governor = "" // reported above
 		}
-		switch {
-		case v.EPP == "balance_performance":
-			s.reason = "Balanced mode for mixed workloads"
-			s.benefit = "Better thermal management, longer battery"
-		case v.EPP == "balance_power":
-			s.reason = "Favor power savings over raw speed"
-			s.benefit = "Significant battery improvement on laptop"
-		case v.EPP == "performance":
-			s.reason = "Maximum CPU performance priority"
-			s.benefit = "Best throughput for compute-heavy tasks"
+		if v.PStateMode != "" && v.PStateMode != "active" && slices.Contains(pstate.Modes(), v.PStateMode) {
+			c.EPP = "" // gone once the driver leaves active mode
+		}
+		sec.Fields = append(sec.Fields, suggestCores(class, group, c)...)
+	}
+
+	if pstate.PerfPctKnown {
+		for _, pct := range []struct {
+			key, reason string
+			cur         int
+			want        profile.OptInt
+		}{
+			{"Max Perf Percent", "Caps every core at this share of its top P-state", pstate.MaxPerfPct, v.MaxPerfPct},
+			{"Min Perf Percent", "Keeps every core at or above this share of its top P-state", pstate.MinPerfPct, v.MinPerfPct},
+		} {
+			if pct.want.Set && pct.want.Value != pct.cur {
+				s := suggestion{key: pct.key, current: fmt.Sprintf("%d%%", pct.cur), target: fmt.Sprintf("%d%%", pct.want.Value), reason: pct.reason}
+				sec.Fields = append(sec.Fields, s.fields()...)
+			}
 		}
-		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
 	// Turbo
@@ -294,9 +328,139 @@ func suggestCPU(p profile.Profile) output.Section {
 		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
+	if v.IdleMaxLatency.Set {
+		if deep := deepIdleStates(v.IdleMaxLatency.Value); len(deep) > 0 {
+			s := suggestion{
+				key:     "C-states",
+				current: strings.Join(deep, ", ") + " enabled",
+				target:  fmt.Sprintf("disabled (max %dus exit latency)", v.IdleMaxLatency.Value),
+				reason:  "Deep idle states add wake-up latency to every interrupt",
+				benefit: "Predictable latency at the cost of idle power",
+			}
+			sec.Fields = append(sec.Fields, s.fields()...)
+		}
+	}
+
 	return sec
 }
 
+// pstateModeReason explains a P-state mode switch. Active mode lets the
+// CPU's own P-state control (HWP on Intel, CPPC on AMD) pick frequencies;
+// passive mode hands them to the kernel's governors, which is what
+// schedutil needs and usually the better choice without HWP.
+func pstateModeReason(driver, mode string, hwp bool) (reason, benefit string) {
+	switch mode {
+	case "passive":
+		reason = "schedutil picks frequencies from the scheduler's per-CPU load and raises them as soon as a task wakes"
+		benefit = "Makes schedutil available; suits bursty and latency-sensitive loads"
+		if driver == "intel_pstate" && !hwp {
+			benefit = "Without HWP, active mode is a coarse software heuristic; schedutil reacts faster and idles lower"
+		}
+	case "active":
+		reason = "The CPU's own P-state control picks frequencies, steered by EPP"
+		benefit = "Fastest frequency changes and EPP hints; the better default on CPUs with HWP/CPPC"
+	case "guided":
+		reason = "Firmware picks frequencies within the min/max limits the kernel sets"
+		benefit = "Platform-tuned scaling with kernel-controlled bounds"
+	}
+	return reason, benefit
+}
+
+// suggestCores compares the governor, EPP and frequency limits of one
+// class of cores with the profile's. Policies that already match are
+// left out of the current value.
+func suggestCores(class string, group []detect.CPUPolicy, c profile.CoreValues) []output.Field {
+	var fields []output.Field
+	suggest := func(key, target string, get func(detect.CPUPolicy) string) *suggestion {
+		var off []detect.CPUPolicy
+		for _, p := range group {
+			if cur := get(p); cur != "" && cur != target {
+				off = append(off, p)
+			}
+		}
+		if target == "" || len(off) == 0 {
+			return nil
+		}
+		if class != "" {
+			key = fmt.Sprintf("%s (%s cores)", key, class)
+		}
+		current := strings.Join(detect.PolicyValues(off, get), ", ")
+		if len(off) < len(group) {
+			current += " on cpus " + sysfs.FormatCPUList(detect.PolicyCPUs(off))
+		}
+		return &suggestion{key: key, current: current, target: target}
+	}
+
+	if s := suggest("Governor", c.Governor, func(p detect.CPUPolicy) string { return p.Governor }); s != nil {
+		switch c.Governor {
+		case "schedutil":
+			s.reason = "Dynamic scaling saves power when CPU is idle"
+			s.benefit = "~10-15% battery improvement, similar peak performance"
+		case "performance":
+			s.reason = "Fixed max frequency for maximum throughput"
+			s.benefit = "Lowest latency, best for servers and heavy workloads"
+		case "powersave":
+			s.reason = "Minimum frequency to extend battery life"
+			s.benefit = "Maximum battery savings on battery power"
+		}
+		fields = append(fields, s.fields()...)
+	}
+
+	if s := suggest("Energy Perf Pref", c.EPP, func(p detect.CPUPolicy) string { return p.EPP }); s != nil {
+		switch c.EPP {
+		case "balance_performance":
+			s.reason = "Balanced mode for mixed workloads"
+			s.benefit = "Better thermal management, longer battery"
+		case "balance_power":
+			s.reason = "Favor power savings over raw speed"
+			s.benefit = "Significant battery improvement on laptop"
+		case "performance":
+			s.reason = "Maximum CPU performance priority"
+			s.benefit = "Best throughput for compute-heavy tasks"
+		}
+		fields = append(fields, s.fields()...)
+	}
+
+	for _, limit := range []struct {
+		key, reason, benefit string
+		mhz                  profile.OptInt
+		cur                  func(detect.CPUPolicy) int
+	}{
+		{"Max Frequency", "Caps the clock of these cores", "Less power and heat under sustained load",
+			c.MaxFreqMHz, func(p detect.CPUPolicy) int { return p.MaxFreqMHz }},
+		{"Min Frequency", "Keeps these cores from clocking all the way down", "Faster response to bursts of work",
+			c.MinFreqMHz, func(p detect.CPUPolicy) int { return p.MinFreqMHz }},
+	} {
+		if !limit.mhz.Set {
+			continue
+		}
+		target := fmt.Sprintf("%d MHz", group[0].ClampFreq(limit.mhz.Value)/1000)
+		get := func(p detect.CPUPolicy) string {
+			if limit.cur(p) == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%d MHz", limit.cur(p))
+		}
+		if s := suggest(limit.key, target, get); s != nil {
+			s.reason, s.benefit = limit.reason, limit.benefit
+			fields = append(fields, s.fields()...)
+		}
+	}
+	return fields
+}
+
+// deepIdleStates names the cpuidle states still enabled on some CPU
+// whose exit latency exceeds maxLatency microseconds.
+func deepIdleStates(maxLatency int) []string {
+	var names []string
+	for _, s := range detect.DetectIdle().States {
+		if s.LatencyUS > maxLatency && s.Disabled < s.CPUs {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
 func suggestMemory(p profile.Profile) output.Section {
 	sec := output.Section{Title: "Memory Changes"}
 	v := p.Values
@@ -350,16 +514,99 @@ func suggestMemory(p profile.Profile) output.Section {
 		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
+	var hugepages profile.OptInt
+	if v.WantsHugepages() {
+		if mem := detect.DetectMemory(); !v.HasHugepagePool(mem.HugePageSizeKB) {
+			hugepages = profile.Int(v.NrHugepages(mem.TotalKB, mem.HugePageSizeKB))
+		}
+	}
+	sec.Fields = appendOptSuggestions(sec.Fields, []optSuggestion{
+		{"Dirty BG Bytes", sysfs.VMDirtyBgBytes, v.DirtyBgBytes,
+			"Start background writeback after a fixed amount of dirty data", "Small, steady flushes instead of large bursts on big-RAM hosts"},
+		{"Dirty Bytes", sysfs.VMDirtyBytes, v.DirtyBytes,
+			"Cap dirty data at a fixed size rather than a share of RAM", "Bounded write stalls regardless of memory size"},
+		{"Huge Pages", sysfs.VMNrHugepages, hugepages,
+			"Reserve huge pages for guests and databases that map them", "Fewer TLB misses, no THP compaction for reserved memory"},
+		{"KSM", sysfs.KSMRun, v.KSM,
+			"Merge identical memory pages between virtual machines", "Higher VM density at the cost of some CPU"},
+	})
+	var pools []optSuggestion
+	for _, h := range v.HugepagePools {
+		pools = append(pools, optSuggestion{"Huge Pages " + h.String(),
+			detect.HugePagePoolDir(int64(h.SizeKB), h.NodeID()) + "/nr_hugepages", profile.Int(h.Count),
+			"Reserve huge pages of this size where the workload maps them", "Memory set aside before fragmentation makes it unavailable"})
+	}
+	defrag := optSuggestion{"Khugepaged Defrag", sysfs.KhugepagedBase + "/defrag", v.Khugepaged.Defrag,
+		"Let khugepaged compact memory to build transparent huge pages", "Compaction happens in the background instead of at page faults"}
+	if v.Khugepaged.Defrag.Value == 0 {
+		defrag.reason = "Stop khugepaged from compacting memory"
+		defrag.benefit = "No background compaction competing with latency-sensitive work"
+	}
+	sec.Fields = appendOptSuggestions(sec.Fields, append(pools, defrag))
+
+	if detect.DetectNUMA().MultiNode() {
+		sec.Fields = append(sec.Fields, suggestNUMA(p)...)
+	}
+
 	return sec
 }
 
+// suggestNUMA explains the NUMA policy the profile sets. The trade-off
+// differs by workload: a database sizes and places its own buffer pool,
+// a general server relies on the kernel to keep memory near its users.
+func suggestNUMA(p profile.Profile) []output.Field {
+	v := p.Values
+	var fields []output.Field
+	if cur, err := sysfs.ReadInt(sysfs.NUMABalancing); err == nil && v.NUMABalancing.Set && cur != v.NUMABalancing.Value {
+		s := suggestion{key: "NUMA Balancing", current: strconv.Itoa(cur), target: strconv.Itoa(v.NUMABalancing.Value)}
+		switch {
+		case v.NUMABalancing.Value != 0:
+			s.reason = "The kernel samples page accesses and moves memory and tasks to the node that uses them"
+			s.benefit = "Better locality for unpinned processes and VMs, at the cost of hinting faults"
+		case p.Workload == profile.Database:
+			s.reason = "Balancing unmaps pages to sample accesses and migrates them; a database's buffer pool is large and shared by all its threads, so the faults and migrations cost more than they gain"
+			s.benefit = "No latency spikes from hinting faults; place the pool with numactl --interleave=all"
+		default:
+			s.reason = "Stops hinting faults and page migration"
+			s.benefit = "Steadier latency for processes pinned with numactl or cpusets"
+		}
+		fields = append(fields, s.fields()...)
+	}
+	if cur, err := sysfs.ReadInt(sysfs.VMZoneReclaim); err == nil && v.ZoneReclaim.Set && cur != v.ZoneReclaim.Value {
+		s := suggestion{key: "Zone Reclaim", current: detect.ZoneReclaimStr(cur), target: detect.ZoneReclaimStr(v.ZoneReclaim.Value)}
+		switch {
+		case v.ZoneReclaim.Value != 0:
+			s.reason = "A full node drops its own page cache before allocating on another node"
+			s.benefit = "Node-local memory for jobs partitioned per node that fit in one node"
+		case p.Workload == profile.Database:
+			s.reason = "With zone reclaim a full node evicts cached table and index pages while the other node has free memory"
+			s.benefit = "The whole machine's RAM caches data; no reclaim stalls under load"
+		default:
+			s.reason = "A full node allocates on another node instead of evicting its page cache"
+			s.benefit = "File caching uses all of RAM; remote access costs less than re-reading from disk"
+		}
+		fields = append(fields, s.fields()...)
+	}
+	return fields
+}
+
 func suggestStorage(p profile.Profile) output.Section {
 	sec := output.Section{Title: "Storage Changes"}
 	v := p.Values
 
 	storageInfo := detect.DetectStorage()
 	for _, disk := range storageInfo.Disks {
-		recommended := v.IOScheduler(disk.Type)
+		dv := v.ForDisk(disk.ID())
+		rule := ""
+		ruleQueue := make(map[string]bool)
+		if dv.Rule > 0 {
+			rule = fmt.Sprintf("Storage rule %d (%s)", dv.Rule, v.StorageRules[dv.Rule-1])
+			for _, a := range v.StorageRules[dv.Rule-1].Attrs() {
+				ruleQueue[a.Name] = true
+			}
+		}
+
+		recommended := dv.Scheduler
 		if disk.Scheduler != recommended {
 			s := suggestion{
 				key:     fmt.Sprintf("%s scheduler", disk.Name),
@@ -380,13 +627,187 @@ func suggestStorage(p profile.Profile) output.Section {
 				s.reason = "Deadline guarantees prevent request starvation"
 				s.benefit = "Predictable latency for mixed read/write workloads"
 			}
+			if rule != "" {
+				s.reason = rule
+			}
+			sec.Fields = append(sec.Fields, s.fields()...)
+		}
+
+		if rule != "" && dv.ReadAhead > 0 && disk.ReadAhead != dv.ReadAhead {
+			sec.Fields = append(sec.Fields, suggestion{
+				key:     fmt.Sprintf("%s read_ahead_kb", disk.Name),
+				current: fmt.Sprintf("%d", disk.ReadAhead),
+				target:  fmt.Sprintf("%d", dv.ReadAhead),
+				reason:  rule,
+			}.fields()...)
+		}
+		for _, a := range dv.Queue.Attrs() {
+			cur, ok := disk.Queue[a.Name]
+			target := a.Value
+			if a.Name == "max_sectors_kb" && disk.MaxHWSectorsKB > 0 {
+				target = min(target, disk.MaxHWSectorsKB)
+			}
+			if !ok || cur == target {
+				continue
+			}
+			s := suggestion{
+				key:     fmt.Sprintf("%s %s", disk.Name, a.Name),
+				current: fmt.Sprintf("%d", cur),
+				target:  fmt.Sprintf("%d", target),
+			}
+			s.reason, s.benefit = queueReason(a.Name, target)
+			if ruleQueue[a.Name] {
+				s.reason = rule
+			}
 			sec.Fields = append(sec.Fields, s.fields()...)
 		}
 	}
 
+	for _, dev := range storageInfo.Stack {
+		if ra := v.StackReadAhead(storageInfo.DiskIDs(dev)); dev.TopLevel() && ra > 0 && dev.ReadAhead != ra {
+			sec.Fields = append(sec.Fields, suggestion{
+				key:     fmt.Sprintf("%s read_ahead_kb", dev.Label()),
+				current: fmt.Sprintf("%d", dev.ReadAhead),
+				target:  fmt.Sprintf("%d", ra),
+				reason:  fmt.Sprintf("Filesystems read through the %s device, not the disks under it", dev.Kind),
+			}.fields()...)
+		}
+		if v.MDStripeCache.Set && dev.StripeCache > 0 && dev.StripeCache != v.MDStripeCache.Value {
+			sec.Fields = append(sec.Fields, suggestion{
+				key:     fmt.Sprintf("%s stripe_cache_size", dev.Name),
+				current: fmt.Sprintf("%d", dev.StripeCache),
+				target:  fmt.Sprintf("%d", v.MDStripeCache.Value),
+				reason:  "A larger stripe cache avoids read-modify-write cycles on parity RAID",
+				benefit: "Higher sequential write throughput, at one page per member disk per entry",
+			}.fields()...)
+		}
+	}
+
 	return sec
 }
 
+// suggestMounts covers mount options, fstrim.timer and swap placement.
+// Mount options only change through /etc/fstab, which tuner never
+// edits; --fstab-diff prints the edit.
+func suggestMounts(p profile.Profile) output.Section {
+	sec := output.Section{Title: "Mount Changes"}
+	v := p.Values
+	info := detect.DetectStorage()
+
+	changes := persist.ProposeFstab(v, info)
+	for _, c := range changes {
+		target := c.Add
+		if target == "" {
+			target = "off"
+		}
+		sec.Fields = append(sec.Fields, suggestion{
+			key:     fmt.Sprintf("%s %s", c.MountPoint, c.Key),
+			current: c.Current,
+			target:  target,
+			reason:  c.Reason,
+			benefit: c.Benefit,
+		}.fields()...)
+	}
+
+	flash := false
+	for _, m := range info.Mounts {
+		flash = flash || (m.Tunable() && m.OnFlash())
+	}
+	if v.Trim == "fstrim" && flash && info.Fstrim.Installed && !info.Fstrim.Enabled {
+		sec.Fields = append(sec.Fields, suggestion{
+			key:     "fstrim.timer",
+			current: "disabled",
+			target:  "enabled",
+			reason:  "Flash filesystems are mounted without discard and nothing trims them",
+			benefit: "Sustained write speed; enable with: systemctl enable --now fstrim.timer",
+		}.fields()...)
+	}
+
+	flashDisk := false
+	for _, d := range info.Disks {
+		flashDisk = flashDisk || !d.Rotational
+	}
+	for _, sw := range info.Swaps {
+		if !sw.Rotational {
+			continue
+		}
+		s := suggestion{
+			key:     "swap " + sw.Device,
+			current: "on hdd",
+			target:  "on flash",
+			reason:  "Every page-in from a rotational disk waits for a seek",
+			benefit: "Much faster recovery under memory pressure",
+		}
+		if !flashDisk {
+			s.target = "zswap in front"
+			s.benefit = "Compressed pages stay in RAM and reach the disk less often"
+		}
+		if flashDisk || v.Zswap == "" {
+			sec.Fields = append(sec.Fields, s.fields()...)
+		}
+	}
+
+	if len(changes) > 0 {
+		sec.Fields = append(sec.Fields, output.Field{
+			Key:    "fstab",
+			Value:  "see 'tuner suggest --fstab-diff', then remount; tuner never edits /etc/fstab",
+			Status: output.StatusInfo,
+		})
+	}
+	return sec
+}
+
+// printFstabDiff prints the fstab edit for the profile's mount options.
+func printFstabDiff(p profile.Profile) error {
+	info := detect.DetectStorage()
+	diff := persist.FstabDiff(info, persist.ProposeFstab(p.Values, info))
+	if diff == "" {
+		fmt.Fprintf(os.Stderr, "No mount option changes for profile %s.\n", profileLabel(p))
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// queueReason explains a queue attribute target.
+func queueReason(attr string, target int) (reason, benefit string) {
+	switch attr {
+	case "nr_requests":
+		return "A deeper queue gives the scheduler more requests to merge and reorder",
+			"Higher throughput under heavy parallel I/O"
+	case "max_sectors_kb":
+		return "Larger requests cut per-I/O overhead on sequential transfers",
+			"Higher streaming throughput"
+	case "rq_affinity":
+		if target == 2 {
+			return "Completes each request on the CPU that submitted it",
+				"Warm caches and less cross-CPU traffic at high IOPS"
+		}
+		return "Completes requests on a CPU in the submitting CPU's group", ""
+	case "nomerges":
+		if target == 0 {
+			return "Lets the block layer merge adjacent requests", "Fewer, larger I/Os"
+		}
+		return "Skips merge lookups that rarely succeed on random I/O",
+			"Less CPU per I/O on fast devices"
+	case "add_random":
+		return "Disk timings add little to the entropy pool and cost a little per I/O", ""
+	case "iostats":
+		if target == 0 {
+			return "I/O accounting costs CPU at very high IOPS",
+				"Lower per-I/O overhead; iostat shows nothing for this disk"
+		}
+		return "Keeps per-disk I/O statistics for iostat and monitoring", ""
+	case "wbt_lat_usec":
+		if target == 0 {
+			return "Disables writeback throttling, which can cap write throughput", ""
+		}
+		return "Writeback throttling keeps background writes from delaying reads",
+			"More consistent read latency during heavy writeback"
+	}
+	return "", ""
+}
+
 func suggestNetwork(p profile.Profile) output.Section {
 	sec := output.Section{Title: "Network Changes"}
 	v := p.Values
@@ -435,14 +856,116 @@ func suggestNetwork(p profile.Profile) output.Section {
 		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
+	sec.Fields = appendOptSuggestions(sec.Fields, []optSuggestion{
+		{"Somaxconn", sysfs.Somaxconn, v.Somaxconn,
+			"Higher listen backlog for connection bursts", "Fewer dropped SYNs under load"},
+		{"Conntrack Max", sysfs.ConntrackMax, v.ConntrackMax,
+			"Track more concurrent connections", "Prevents conntrack table overflow under load"},
+		{"Busy Poll", sysfs.NetCoreBusyPoll, v.BusyPoll,
+			"Spin on the NIC queue in poll/select instead of sleeping", "Lower and more stable network latency"},
+		{"Busy Read", sysfs.NetCoreBusyRead, v.BusyRead,
+			"Spin on the NIC queue in blocking reads", "Lower receive latency for request/response traffic"},
+	})
+
+	if v.IRQAffinity != "" {
+		sec.Fields = append(sec.Fields, suggestIRQAffinity(detect.DetectIRQ())...)
+	}
+
 	return sec
 }
 
-func suggestServer() output.Section {
+// suggestIRQAffinity shows the NIC queue IRQs apply would pin, or why it
+// cannot while irqbalance runs.
+func suggestIRQAffinity(info detect.IRQInfo) []output.Field {
+	if info.IRQBalance.Active || info.IRQBalance.Enabled {
+		return []output.Field{
+			{Key: "IRQ Affinity", Value: "skipped: irqbalance is running or enabled and would move the IRQs back", Status: output.StatusWarn},
+			{Key: "", Value: "Run: sudo systemctl disable --now irqbalance", Status: output.StatusNone},
+		}
+	}
+	var fields []output.Field
+	for _, n := range info.NICs {
+		targets := n.SpreadTargets()
+		if targets == nil {
+			continue
+		}
+		moved := 0
+		for i, q := range n.IRQs {
+			if !slices.Equal(q.Affinity, []int{targets[i]}) {
+				moved++
+			}
+		}
+		if moved == 0 {
+			continue
+		}
+		s := suggestion{
+			key:     "IRQ Affinity " + n.Name,
+			current: fmt.Sprintf("%d of %d queue IRQs unpinned or elsewhere", moved, len(n.IRQs)),
+			target:  "pinned, spread over CPUs " + sysfs.FormatCPUList(n.LocalCPUs),
+			reason:  "Each queue's interrupts and softirq work stay on one core of the NIC's NUMA node",
+			benefit: "No cross-node memory traffic for packets, warm caches, steadier latency",
+		}
+		fields = append(fields, s.fields()...)
+	}
+	return fields
+}
+
+func suggestKernel(p profile.Profile) output.Section {
+	sec := output.Section{Title: "Kernel Limit Changes"}
+	v := p.Values
+
+	sec.Fields = appendOptSuggestions(sec.Fields, []optSuggestion{
+		{"Inotify Watches", sysfs.InotifyMaxWatches, v.InotifyWatches,
+			"File watchers and kubelet watch many paths", "Avoids 'no space left on device' from inotify"},
+		{"Inotify Instances", sysfs.InotifyMaxInstances, v.InotifyInstances,
+			"Each container or build tool opens its own inotify instance", "Avoids 'too many open files' from watchers"},
+		{"PID Max", sysfs.PidMax, v.PidMax,
+			"Parallel builds and pods create many processes and threads", "Avoids fork failures when PIDs run out"},
+	})
+
+	return sec
+}
+
+// optSuggestion describes an optional integer setting for
+// appendOptSuggestions.
+type optSuggestion struct {
+	key     string
+	path    string
+	want    profile.OptInt
+	reason  string
+	benefit string
+}
+
+// appendOptSuggestions adds a suggestion for each set value that differs
+// from the running system.
+func appendOptSuggestions(fields []output.Field, opts []optSuggestion) []output.Field {
+	for _, o := range opts {
+		if !o.want.Set {
+			continue
+		}
+		cur, err := sysfs.ReadInt(o.path)
+		if err != nil || cur == o.want.Value {
+			continue
+		}
+		s := suggestion{
+			key:     o.key,
+			current: fmt.Sprintf("%d", cur),
+			target:  fmt.Sprintf("%d", o.want.Value),
+			reason:  o.reason,
+			benefit: o.benefit,
+		}
+		fields = append(fields, s.fields()...)
+	}
+	return fields
+}
+
+// suggestServer gives generic server advice. Values the profile sets
+// explicitly are suggested with the network changes instead.
+func suggestServer(v profile.Values) output.Section {
 	sec := output.Section{Title: "Server Tuning"}
 	info := detect.DetectServer()
 
-	if info.Somaxconn < 4096 {
+	if !v.Somaxconn.Set && info.Somaxconn < 4096 {
 		s := suggestion{
 			key:     "Somaxconn",
 			current: fmt.Sprintf("%d", info.Somaxconn),
@@ -480,7 +1003,7 @@ func suggestServer() output.Section {
 		}
 	}
 
-	if info.ConntrackMax >= 0 && info.ConntrackMax < 262144 {
+	if !v.ConntrackMax.Set && info.ConntrackMax >= 0 && info.ConntrackMax < 262144 {
 		s := suggestion{
 			key:     "Conntrack Max",
 			current: fmt.Sprintf("%d", info.ConntrackMax),
@@ -491,7 +1014,9 @@ func suggestServer() output.Section {
 		sec.Fields = append(sec.Fields, s.fields()...)
 	}
 
-	if info.IRQBalance.Installed && !info.IRQBalance.Active {
+	// irq_affinity pins IRQs itself and is suggested with the network
+	// changes; irqbalance would fight it.
+	if v.IRQAffinity == "" && info.IRQBalance.Installed && !info.IRQBalance.Active {
 		sec.Fields = append(sec.Fields,
 			output.Field{
 				Key:    "IRQ Balance",
@@ -505,6 +1030,21 @@ func suggestServer() output.Section {
 			},
 		)
 	}
+	if v.IRQAffinity == "" && !info.IRQBalance.Active {
+		for _, n := range detect.DetectNICQueues() {
+			if n.RemoteIRQs() == 0 {
+				continue
+			}
+			s := suggestion{
+				key:     "IRQ Affinity " + n.Name,
+				current: fmt.Sprintf("%d of %d queue IRQs off the NIC's node", n.RemoteIRQs(), len(n.IRQs)),
+				target:  "CPUs " + sysfs.FormatCPUList(n.LocalCPUs),
+				reason:  "Packets are processed on a different NUMA node than the NIC's memory",
+				benefit: `Set irq_affinity = "numa-local" in the profile, or enable irqbalance`,
+			}
+			sec.Fields = append(sec.Fields, s.fields()...)
+		}
+	}
 
 	powerInfo := detect.DetectPower()
 	if powerInfo.Tuned.Installed && !powerInfo.Tuned.Active {
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/persist"
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/tune"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that the live system still matches the last applied profile",
+	Long: `Check that the live system still matches the last applied profile.
+
+The profile and workload are read from the newest restore point written by
+'tuner apply' or 'tuner save', and every parameter is compared with the live
+value. Anything another tool (tuned, TLP, config management, a kernel
+update) changed since is reported as drift.
+
+Exits 1 if any parameter drifted, 0 if none did or --fix restored them all.`,
+	RunE: runVerify,
+}
+
+var (
+	verifyProfile  string
+	verifyWorkload string
+	verifyFix      bool
+)
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyProfile, "profile", "", "profile to verify against (default: the last applied profile)")
+	verifyCmd.Flags().StringVar(&verifyWorkload, "workload", "", workloadFlagHelp+" (default: the last applied workload)")
+	verifyCmd.Flags().BoolVar(&verifyFix, "fix", false, "re-apply drifted parameters")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyReport is the JSON shape of 'tuner verify -f json'.
+type verifyReport struct {
+	Profile      string       `json:"profile"`
+	Workload     string       `json:"workload,omitempty"`
+	RestorePoint int          `json:"restore_point,omitempty"`
+	Drifted      []driftEntry `json:"drifted"`
+	Fixed        bool         `json:"fixed,omitempty"`
+}
+
+type driftEntry struct {
+	Subsystem string `json:"subsystem"`
+	Parameter string `json:"parameter"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+	FixError  string `json:"fix_error,omitempty"`
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	if verifyFix {
+		platform.RequireRoot("verify --fix")
+	}
+
+	name, workload := verifyProfile, verifyWorkload
+	report := verifyReport{}
+	if name == "" {
+		gens, err := persist.ListBackups()
+		if err != nil {
+			return err
+		}
+		if len(gens) == 0 {
+			return fmt.Errorf("no restore points found; run 'tuner apply' or 'tuner save' first, or pass --profile")
+		}
+		last := gens[len(gens)-1]
+		name = last.Profile
+		if workload == "" {
+			workload = last.Workload
+		}
+		report.RestorePoint = last.ID
+	}
+
+	p, err := loadProfile(name, workload)
+	if err != nil {
+		return err
+	}
+	report.Profile = p.Name
+	report.Workload = string(p.Workload)
+
+	engine := tune.NewEngine(p)
+	changes := engine.ComputeChanges()
+	report.Drifted = make([]driftEntry, len(changes))
+	for i, c := range changes {
+		report.Drifted[i] = driftEntry{
+			Subsystem: c.Subsystem,
+			Parameter: c.Parameter,
+			Expected:  c.NewValue,
+			Actual:    c.OldValue,
+		}
+	}
+
+	if verifyFix && len(changes) > 0 {
+		if err := fixDrift(engine, p, changes, &report); err != nil {
+			return err
+		}
+	}
+
+	if outFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printVerifyReport(profileLabel(p), report)
+	}
+
+	if len(report.Drifted) > 0 && !report.Fixed {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = outFormat == "json"
+		return fmt.Errorf("%d parameters drifted from profile %s", len(report.Drifted), p.Name)
+	}
+	return nil
+}
+
+// fixDrift re-applies the drifted changes with rollback, after saving a
+// restore point like apply does.
+func fixDrift(engine *tune.Engine, p profile.Profile, changes []tune.Change, report *verifyReport) error {
+	if _, err := persist.SaveBackup(persist.BackupData{
+		Command:  "verify",
+		Profile:  p.Name,
+		Workload: string(p.Workload),
+		Values:   tune.Backup(changes),
+	}); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	res := engine.Apply(changes, true, true)
+	failed := make(map[string]error)
+	for _, f := range res.Failed {
+		failed[f.Change.Parameter] = f.Err
+	}
+	for i := range report.Drifted {
+		if err, ok := failed[report.Drifted[i].Parameter]; ok {
+			report.Drifted[i].FixError = err.Error()
+		} else if res.RolledBack {
+			report.Drifted[i].FixError = "rolled back"
+		}
+	}
+	report.Fixed = len(res.Failed) == 0 && !res.RolledBack
+	return nil
+}
+
+func printVerifyReport(label string, r verifyReport) {
+	bold := color.New(color.Bold)
+	bold.Printf("Profile: %s\n", label)
+	if r.RestorePoint > 0 {
+		fmt.Printf("From restore point %d\n", r.RestorePoint)
+	}
+	fmt.Println()
+
+	if len(r.Drifted) == 0 {
+		color.Green("No drift: the system matches the profile.")
+		return
+	}
+
+	sec := output.Section{Title: "Drift"}
+	for _, d := range r.Drifted {
+		status := output.StatusBad
+		value := fmt.Sprintf("%s (expected %s)", d.Actual, d.Expected)
+		switch {
+		case d.FixError != "":
+			value += " - fix failed: " + d.FixError
+		case r.Fixed:
+			status = output.StatusGood
+			value = fmt.Sprintf("%s → %s (fixed)", d.Actual, d.Expected)
+		}
+		sec.Fields = append(sec.Fields, output.Field{
+			Key:    fmt.Sprintf("[%s] %s", d.Subsystem, d.Parameter),
+			Value:  value,
+			Status: status,
+		})
+	}
+	output.NewFormatter(outFormat, noColor).Format(os.Stdout, []output.Section{sec})
+
+	if !r.Fixed {
+		fmt.Println("\nRe-apply with: sudo tuner verify --fix")
+	}
+}
@@ -0,0 +1,270 @@
+// Package daemon keeps a profile enforced: it periodically recomputes
+// the changes tune.Engine would make and re-applies any that drifted.
+//
+// sysfs and procfs attributes do not generate inotify events when the
+// kernel or another process changes them, so drift is found by polling.
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/krisk248/tuner/internal/persist"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/tune"
+)
+
+// DefaultInterval is how often the daemon checks for drift.
+const DefaultInterval = time.Minute
+
+// Options select the profile the daemon enforces.
+type Options struct {
+	Profile  string // empty to auto-detect
+	Workload string
+	Interval time.Duration
+}
+
+// Daemon holds the loaded profile and the status reported by
+// 'tuner daemon status'.
+type Daemon struct {
+	opts   Options
+	engine *tune.Engine
+	status Status
+	log    io.Writer
+
+	// shortfalls holds the nr_hugepages files the kernel could not fill
+	// and the count it settled on. Reload clears it.
+	shortfalls map[string]int
+}
+
+// New loads the profile and returns a daemon that logs to w.
+func New(opts Options, w io.Writer) (*Daemon, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	d := &Daemon{
+		opts: opts,
+		log:  w,
+		status: Status{
+			PID:      os.Getpid(),
+			Interval: opts.Interval.String(),
+			Started:  now(),
+		},
+	}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload re-reads the profile files. On error the previous profile stays
+// in force.
+func (d *Daemon) Reload() error {
+	w, err := profile.ParseWorkload(d.opts.Workload)
+	if err != nil {
+		return err
+	}
+	p, err := profile.Load(d.opts.Profile, w)
+	if err != nil {
+		return err
+	}
+	d.engine = &tune.Engine{Profile: p, Quiet: true}
+	d.shortfalls = nil
+	d.status.Profile = p.Name
+	d.status.Workload = string(p.Workload)
+	d.status.PowerState = ""
+	if p.Type == profile.Laptop {
+		d.status.PowerState = string(p.PowerState)
+	}
+	d.status.LastReload = now()
+	d.status.LastError = ""
+	return nil
+}
+
+// Check re-applies every drifted parameter once and returns what it did.
+func (d *Daemon) Check() []Correction {
+	d.status.Checks++
+	d.status.LastCheck = now()
+
+	changes := d.skipShortfalls(d.engine.ComputeChanges())
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := d.recordNewPaths(changes); err != nil {
+		d.logf(prioWarning, "could not record original values, reset may miss them: %v", err)
+	}
+
+	res := d.engine.Apply(changes, true, false)
+	failed := make(map[string]error)
+	for _, f := range res.Failed {
+		failed[f.Change.Parameter] = f.Err
+	}
+	short := make(map[string]tune.Shortfall)
+	for _, s := range tune.HugepageShortfalls(res.Applied) {
+		if d.shortfalls == nil {
+			d.shortfalls = make(map[string]int)
+		}
+		d.shortfalls[s.Path] = s.Got
+		short[s.Parameter] = s
+	}
+
+	var out []Correction
+	for _, c := range changes {
+		corr := Correction{
+			Time:      now(),
+			Subsystem: c.Subsystem,
+			Parameter: c.Parameter,
+			From:      c.OldValue,
+			To:        c.NewValue,
+		}
+		if err := failed[c.Parameter]; err != nil {
+			corr.Error = err.Error()
+			d.logf(prioErr, "[%s] %s drifted to %s, could not restore %s: %v", c.Subsystem, c.Parameter, c.OldValue, c.NewValue, err)
+		} else if s, ok := short[c.Parameter]; ok {
+			corr.Error = s.String()
+			d.logf(prioWarning, "[%s] %s; not retrying until the pool or the profile changes", c.Subsystem, s)
+		} else {
+			d.status.Corrections++
+			d.logf(prioNotice, "[%s] %s drifted to %s, restored %s", c.Subsystem, c.Parameter, c.OldValue, c.NewValue)
+		}
+		out = append(out, corr)
+	}
+	d.status.addRecent(out)
+	return out
+}
+
+// skipShortfalls drops the nr_hugepages changes the kernel already fell
+// short on. Rewriting them every interval would compact memory again
+// for the same result; they come back once the pool size moves.
+func (d *Daemon) skipShortfalls(changes []tune.Change) []tune.Change {
+	var out []tune.Change
+	for _, c := range changes {
+		settled := len(c.Writes) > 0
+		for _, w := range c.Writes {
+			if got, ok := d.shortfalls[w.Path]; !ok || w.Old != strconv.Itoa(got) {
+				settled = false
+			}
+		}
+		if !settled {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// recordNewPaths saves a restore point for paths tuner has never changed
+// before, such as a disk plugged in after 'tuner apply', so 'tuner reset'
+// can still restore them. Paths already in the pristine record are not
+// recorded again; otherwise a tool fighting the daemon would add a
+// restore point every interval.
+func (d *Daemon) recordNewPaths(changes []tune.Change) error {
+	values := tune.Backup(changes)
+	if pristine, err := persist.LoadPristine(); err == nil {
+		for path := range values {
+			if _, ok := pristine.Values[path]; ok {
+				delete(values, path)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	p := d.engine.Profile
+	_, err := persist.SaveBackup(persist.BackupData{
+		Command:  "daemon",
+		Profile:  p.Name,
+		Workload: string(p.Workload),
+		Values:   values,
+	})
+	return err
+}
+
+// Run checks every interval until SIGTERM or SIGINT, reloading the
+// profile on SIGHUP. On laptops it also switches between the AC and
+// battery values when the power source changes. The status file is kept
+// up to date and removed on exit.
+func (d *Daemon) Run() error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigs)
+	defer RemoveStatus()
+
+	d.logf(prioInfo, "enforcing profile %s every %s", d.label(), d.opts.Interval)
+	d.Check()
+	d.writeStatus()
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	// A nil channel never fires, so non-laptops skip power polling.
+	var powerTick <-chan time.Time
+	power := powerWatch{current: d.engine.Profile.PowerState}
+	if d.engine.Profile.Type == profile.Laptop {
+		t := time.NewTicker(PowerPollInterval)
+		defer t.Stop()
+		powerTick = t.C
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			d.Check()
+		case t := <-powerTick:
+			state := profile.DetectPowerState()
+			if !power.observe(state, t) {
+				continue
+			}
+			d.powerChanged(state)
+		case sig := <-sigs:
+			if sig != syscall.SIGHUP {
+				d.logf(prioInfo, "received %s, exiting", sig)
+				return nil
+			}
+			if err := d.Reload(); err != nil {
+				d.status.LastError = err.Error()
+				d.logf(prioErr, "reload failed, keeping profile %s: %v", d.label(), err)
+			} else {
+				d.logf(prioInfo, "reloaded profile %s", d.label())
+				power = powerWatch{current: d.engine.Profile.PowerState}
+				d.Check()
+			}
+		}
+		d.writeStatus()
+	}
+}
+
+func (d *Daemon) writeStatus() {
+	if err := WriteStatus(d.status); err != nil {
+		d.logf(prioWarning, "could not write %s: %v", StatusPath, err)
+	}
+}
+
+func (d *Daemon) label() string {
+	if d.status.Workload == "" {
+		return d.status.Profile
+	}
+	return d.status.Profile + "+" + d.status.Workload
+}
+
+// Syslog priorities. journald reads a "<N>" prefix on each stdout line
+// as the message priority.
+const (
+	prioErr     = 3
+	prioWarning = 4
+	prioNotice  = 5
+	prioInfo    = 6
+)
+
+func (d *Daemon) logf(prio int, format string, args ...any) {
+	fmt.Fprintf(d.log, "<%d>%s\n", prio, fmt.Sprintf(format, args...))
+}
+
+func now() string {
+	return time.Now().Format(time.RFC3339)
+}
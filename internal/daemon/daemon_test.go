@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/persist"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestCheckCorrectsDrift(t *testing.T) {
+	dir := t.TempDir()
+	full := filepath.Join(dir, sysfs.VMSwappiness)
+	os.MkdirAll(filepath.Dir(full), 0755)
+	if err := os.WriteFile(full, []byte("60\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	oldBackups, oldStatus := persist.BackupsDir, StatusPath
+	persist.BackupsDir = filepath.Join(t.TempDir(), "backups")
+	StatusPath = filepath.Join(t.TempDir(), "daemon.json")
+	defer func() { persist.BackupsDir, StatusPath = oldBackups, oldStatus }()
+
+	var log bytes.Buffer
+	d, err := New(Options{Profile: "server"}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := d.Check()
+	if len(got) != 1 || got[0].Parameter != "Swappiness" || got[0].From != "60" || got[0].To != "10" {
+		t.Fatalf("corrections = %+v, want Swappiness 60 → 10", got)
+	}
+	if want := "<5>[memory] Swappiness drifted to 60, restored 10\n"; log.String() != want {
+		t.Errorf("log = %q, want %q", log.String(), want)
+	}
+	if v, _ := sysfs.ReadInt(sysfs.VMSwappiness); v != 10 {
+		t.Errorf("swappiness = %d, want 10", v)
+	}
+
+	// The first correction of a path records its original value.
+	gens, _ := persist.ListBackups()
+	if len(gens) != 1 || gens[0].Command != "daemon" || gens[0].Values[sysfs.VMSwappiness] != "60" {
+		t.Errorf("backups = %+v, want one daemon generation with swappiness 60", gens)
+	}
+
+	// Drift again: corrected, but no new restore point.
+	os.WriteFile(full, []byte("30\n"), 0644)
+	if got := d.Check(); len(got) != 1 {
+		t.Errorf("second drift: corrections = %+v", got)
+	}
+	if gens, _ := persist.ListBackups(); len(gens) != 1 {
+		t.Errorf("got %d restore points after repeated drift, want 1", len(gens))
+	}
+	if got := d.Check(); len(got) != 0 {
+		t.Errorf("no drift: corrections = %+v", got)
+	}
+
+	d.writeStatus()
+	s, err := ReadStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Checks != 3 || s.Corrections != 2 || len(s.Recent) != 2 || !s.Running() {
+		t.Errorf("status = %+v", s)
+	}
+	if !strings.HasPrefix(s.Profile, "server") {
+		t.Errorf("status profile = %q", s.Profile)
+	}
+}
+
+func TestCheckSkipsHugepageShortfall(t *testing.T) {
+	dir := t.TempDir()
+	pool := detect.HugePagePoolDir(2048, -1) + "/nr_hugepages"
+	write := func(path, value string) {
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(pool, "700")
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	profiles := t.TempDir()
+	os.WriteFile(filepath.Join(profiles, "hugehost.toml"), []byte("type = \"server\"\n\n[[hugepages]]\nsize_kb = 2048\ncount = 1024\n"), 0644)
+	oldDirs, oldBackups, oldStatus := profile.ProfileDirs, persist.BackupsDir, StatusPath
+	profile.ProfileDirs = []string{profiles}
+	persist.BackupsDir = filepath.Join(t.TempDir(), "backups")
+	StatusPath = filepath.Join(t.TempDir(), "daemon.json")
+	defer func() { profile.ProfileDirs, persist.BackupsDir, StatusPath = oldDirs, oldBackups, oldStatus }()
+
+	var log bytes.Buffer
+	d, err := New(Options{Profile: "hugehost"}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An earlier check wrote 1024 and the kernel settled on 700.
+	d.shortfalls = map[string]int{pool: 700}
+	if got := d.Check(); len(got) != 0 {
+		t.Errorf("settled pool rewritten: corrections = %+v", got)
+	}
+
+	// The pool moved, so the count is worth writing again.
+	write(pool, "600")
+	if got := d.Check(); len(got) != 1 || got[0].From != "600" || got[0].Error != "" {
+		t.Errorf("moved pool: corrections = %+v", got)
+	}
+
+	// A reload forgets the shortfall.
+	write(pool, "700")
+	d.shortfalls = map[string]int{pool: 700}
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Check(); len(got) != 1 {
+		t.Errorf("after reload: corrections = %+v", got)
+	}
+	if d.status.Corrections != 2 {
+		t.Errorf("corrections = %d, want 2", d.status.Corrections)
+	}
+}
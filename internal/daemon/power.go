@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/krisk248/tuner/internal/profile"
+)
+
+const (
+	// PowerPollInterval is how often a laptop's power supplies are read.
+	// Reading a few sysfs files is cheap; uevents over netlink would need
+	// a socket per process and still miss changes made while starting up.
+	PowerPollInterval = 2 * time.Second
+
+	// PowerDebounce is how long a new power state must hold before the
+	// daemon switches values, so a loose plug or a dock handshake does not
+	// flip every setting back and forth.
+	PowerDebounce = 5 * time.Second
+)
+
+// powerWatch debounces AC/battery transitions.
+type powerWatch struct {
+	current profile.PowerState
+	pending profile.PowerState
+	since   time.Time
+}
+
+// observe records a power state read at t and returns true once a state
+// different from the current one has held for PowerDebounce.
+func (w *powerWatch) observe(state profile.PowerState, t time.Time) bool {
+	if state == w.current {
+		w.pending = ""
+		return false
+	}
+	if state != w.pending {
+		w.pending = state
+		w.since = t
+		return false
+	}
+	if t.Sub(w.since) < PowerDebounce {
+		return false
+	}
+	w.current = state
+	w.pending = ""
+	return true
+}
+
+// powerChanged re-resolves the profile for the new power state and
+// applies the resulting values.
+func (d *Daemon) powerChanged(state profile.PowerState) {
+	if err := d.Reload(); err != nil {
+		d.status.LastError = err.Error()
+		d.logf(prioErr, "power source changed to %s, reload failed: %v", state, err)
+		return
+	}
+	if d.engine.TLPOwnsCPU() {
+		d.logf(prioInfo, "power source changed to %s; TLP is enabled and switches CPU settings, applying the rest", state)
+	} else {
+		d.logf(prioInfo, "power source changed to %s, applying %s values", state, state)
+	}
+	d.Check()
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krisk248/tuner/internal/profile"
+)
+
+func TestPowerWatchDebounce(t *testing.T) {
+	start := time.Unix(0, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	w := powerWatch{current: profile.OnAC}
+	steps := []struct {
+		sec   int
+		state profile.PowerState
+		want  bool
+	}{
+		{0, profile.OnAC, false},
+		{2, profile.OnBattery, false}, // unplugged
+		{4, profile.OnAC, false},      // loose plug: back before the debounce
+		{6, profile.OnBattery, false},
+		{10, profile.OnBattery, false},
+		{12, profile.OnBattery, true}, // held for 6s
+		{14, profile.OnBattery, false},
+		{16, profile.OnAC, false},
+		{22, profile.OnAC, true},
+	}
+	for _, s := range steps {
+		if got := w.observe(s.state, at(s.sec)); got != s.want {
+			t.Errorf("t=%ds %s: observe = %v, want %v", s.sec, s.state, got, s.want)
+		}
+	}
+	if w.current != profile.OnAC {
+		t.Errorf("current = %s, want ac", w.current)
+	}
+}
@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// StatusPath is where a running daemon publishes its Status.
+var StatusPath = "/run/tuner/daemon.json"
+
+// maxRecent is how many corrections Status keeps.
+const maxRecent = 20
+
+// Status is what 'tuner daemon status' reports.
+type Status struct {
+	PID         int          `json:"pid"`
+	Profile     string       `json:"profile"`
+	Workload    string       `json:"workload,omitempty"`
+	PowerState  string       `json:"power_state,omitempty"` // laptops only
+	Interval    string       `json:"interval"`
+	Started     string       `json:"started"`
+	LastReload  string       `json:"last_reload"`
+	LastCheck   string       `json:"last_check,omitempty"`
+	Checks      int          `json:"checks"`
+	Corrections int          `json:"corrections"`
+	LastError   string       `json:"last_error,omitempty"`
+	Recent      []Correction `json:"recent,omitempty"` // newest last
+}
+
+// Correction is one drifted parameter the daemon re-applied.
+type Correction struct {
+	Time      string `json:"time"`
+	Subsystem string `json:"subsystem"`
+	Parameter string `json:"parameter"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (s *Status) addRecent(c []Correction) {
+	s.Recent = append(s.Recent, c...)
+	if n := len(s.Recent) - maxRecent; n > 0 {
+		s.Recent = s.Recent[n:]
+	}
+}
+
+// Running returns true if the process that wrote the status still exists.
+func (s *Status) Running() bool {
+	return s.PID > 0 && syscall.Kill(s.PID, 0) == nil
+}
+
+// ReadStatus reads the status file of the running daemon.
+func ReadStatus() (*Status, error) {
+	b, err := os.ReadFile(StatusPath)
+	if err != nil {
+		return nil, err
+	}
+	var s Status
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// WriteStatus replaces the status file atomically, so a concurrent
+// ReadStatus never sees a partial write.
+func WriteStatus(s Status) error {
+	if err := os.MkdirAll(filepath.Dir(StatusPath), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := StatusPath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, StatusPath)
+}
+
+// RemoveStatus removes the status file when the daemon exits.
+func RemoveStatus() error {
+	err := os.Remove(StatusPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -26,6 +26,8 @@ type CPUInfo struct {
 	TurboKnown   bool
 	Architecture string
 	Vendor       string
+	Policies     []CPUPolicy // one per cpufreq policy, in CPU order
+	PState       PStateInfo
 }
 
 // DetectCPU gathers CPU information from sysfs and procfs.
@@ -106,6 +108,9 @@ func DetectCPU() CPUInfo {
 		info.BaseFreqMHz = freq / 1000
 	}
 
+	info.Policies = DetectCPUPolicies()
+	info.PState = DetectPState()
+
 	// Turbo boost
 	if sysfs.Exists(sysfs.IntelNoTurbo) {
 		info.TurboKnown = true
@@ -130,12 +135,23 @@ func CPUSection(info CPUInfo) output.Section {
 		output.Field{Key: "Model", Value: info.Model, Status: output.StatusInfo},
 		output.Field{Key: "Cores / Threads", Value: fmt.Sprintf("%d / %d", info.Cores, info.Threads), Status: output.StatusInfo},
 		output.Field{Key: "Scaling Driver", Value: info.Driver, Status: output.StatusInfo},
-		output.Field{Key: "Governor", Value: info.Governor, Status: governorStatus(info.Governor)},
 	)
 
+	if len(info.Policies) == 0 {
+		sec.Fields = append(sec.Fields,
+			output.Field{Key: "Governor", Value: info.Governor, Status: governorStatus(info.Governor)},
+		)
+	} else {
+		sec.Fields = append(sec.Fields, coreFields(info.Policies)...)
+		sec.Fields = append(sec.Fields,
+			policyField("Governor", info.Policies, func(p CPUPolicy) string { return p.Governor }, governorStatus),
+		)
+	}
+
 	if info.EPP != "" {
 		sec.Fields = append(sec.Fields,
-			output.Field{Key: "Energy Perf Pref", Value: info.EPP, Status: output.StatusInfo},
+			policyField("Energy Perf Pref", info.Policies, func(p CPUPolicy) string { return p.EPP },
+				func(string) output.Status { return output.StatusInfo }),
 		)
 	}
 
@@ -145,6 +161,8 @@ func CPUSection(info CPUInfo) output.Section {
 		)
 	}
 
+	sec.Fields = append(sec.Fields, pstateFields(info.PState)...)
+
 	if info.TurboKnown {
 		turboStr := "disabled"
 		status := output.StatusWarn
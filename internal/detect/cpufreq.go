@@ -0,0 +1,332 @@
+package detect
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// Core classes on hybrid CPUs. CPUs whose cores are all alike have no
+// class.
+const (
+	CorePerformance = "performance"
+	CoreEfficiency  = "efficiency"
+)
+
+// CPUPolicy is one cpufreq policy: the CPUs that share a frequency,
+// usually a single core on x86 and a cluster on ARM.
+type CPUPolicy struct {
+	Dir          string // cpufreq directory holding scaling_governor etc.
+	CPUs         []int
+	Class        string // CorePerformance, CoreEfficiency, "" if cores are alike
+	Governor     string
+	AvailGovs    []string
+	EPP          string
+	MinFreqMHz   int // scaling limits
+	MaxFreqMHz   int
+	HWMinFreqMHz int // cpuinfo limits
+	HWMaxFreqMHz int
+	Capacity     int // cpu_capacity (ARM), 0 if unknown
+	PrefRanking  int // amd-pstate preferred core ranking, 0 if unknown
+}
+
+// DetectCPUPolicies enumerates the cpufreq policies in CPU order and
+// classifies their cores. Kernels without policy directories get one
+// policy per cpuN/cpufreq.
+func DetectCPUPolicies() []CPUPolicy {
+	var policies []CPUPolicy
+	entries, _ := sysfs.ReadDir(sysfs.CPUFreqPolicies)
+	for _, e := range entries {
+		n, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "policy"))
+		if err != nil || !strings.HasPrefix(e.Name(), "policy") {
+			continue
+		}
+		dir := sysfs.CPUFreqPolicies + "/" + e.Name()
+		cpus, err := sysfs.ReadCPUList(dir + "/related_cpus")
+		if err != nil || len(cpus) == 0 {
+			cpus = []int{n}
+		}
+		policies = append(policies, readPolicy(dir, cpus))
+	}
+	if len(policies) == 0 {
+		dirs, _ := sysfs.EachCPU("cpufreq")
+		for _, dir := range dirs {
+			name := strings.TrimSuffix(strings.TrimPrefix(dir, sysfs.CPUBase+"/cpu"), "/cpufreq")
+			if n, err := strconv.Atoi(name); err == nil {
+				policies = append(policies, readPolicy(dir, []int{n}))
+			}
+		}
+	}
+	slices.SortFunc(policies, func(a, b CPUPolicy) int { return a.CPUs[0] - b.CPUs[0] })
+	classifyCores(policies)
+	return policies
+}
+
+func readPolicy(dir string, cpus []int) CPUPolicy {
+	p := CPUPolicy{Dir: dir, CPUs: cpus}
+	p.Governor, _ = sysfs.ReadString(dir + "/scaling_governor")
+	p.AvailGovs, _ = sysfs.ReadFields(dir + "/scaling_available_governors")
+	p.EPP, _ = sysfs.ReadString(dir + "/energy_performance_preference")
+	for path, mhz := range map[string]*int{
+		"scaling_min_freq": &p.MinFreqMHz,
+		"scaling_max_freq": &p.MaxFreqMHz,
+		"cpuinfo_min_freq": &p.HWMinFreqMHz,
+		"cpuinfo_max_freq": &p.HWMaxFreqMHz,
+	} {
+		if khz, err := sysfs.ReadInt(dir + "/" + path); err == nil {
+			*mhz = khz / 1000
+		}
+	}
+	p.Capacity, _ = sysfs.ReadInt(fmt.Sprintf("%s/cpu%d/cpu_capacity", sysfs.CPUBase, cpus[0]))
+	p.PrefRanking, _ = sysfs.ReadInt(dir + "/amd_pstate_prefcore_ranking")
+	return p
+}
+
+// PStateInfo is the operating mode of the intel_pstate or amd-pstate
+// driver.
+type PStateInfo struct {
+	Driver       string // intel_pstate, amd-pstate, "" for neither
+	StatusPath   string
+	Status       string // active, passive, guided (amd-pstate), off/disable
+	PerfPctKnown bool   // intel_pstate min_perf_pct/max_perf_pct exist
+	MinPerfPct   int
+	MaxPerfPct   int
+}
+
+// DetectPState reads the P-state driver's mode and performance limits.
+func DetectPState() PStateInfo {
+	var info PStateInfo
+	for _, d := range []struct{ driver, path string }{
+		{"intel_pstate", sysfs.IntelPStateStatus},
+		{"amd-pstate", sysfs.AMDPStateStatus},
+	} {
+		if status, err := sysfs.ReadString(d.path); err == nil {
+			info.Driver, info.StatusPath, info.Status = d.driver, d.path, status
+			break
+		}
+	}
+	minPct, errMin := sysfs.ReadInt(sysfs.IntelMinPerfPct)
+	maxPct, errMax := sysfs.ReadInt(sysfs.IntelMaxPerfPct)
+	if errMin == nil && errMax == nil {
+		info.PerfPctKnown, info.MinPerfPct, info.MaxPerfPct = true, minPct, maxPct
+	}
+	return info
+}
+
+// Modes returns the statuses the driver can be switched to.
+func (p PStateInfo) Modes() []string {
+	switch p.Driver {
+	case "intel_pstate":
+		return []string{"active", "passive"}
+	case "amd-pstate":
+		return []string{"active", "passive", "guided"}
+	}
+	return nil
+}
+
+// Governors returns the governors a mode offers, or nil if any may be
+// available. In active mode the driver picks frequencies itself and
+// only offers performance and powersave; the generic governors such as
+// schedutil need passive (or, on amd-pstate, guided) mode.
+func (p PStateInfo) Governors(mode string) []string {
+	if p.Driver != "" && mode == "active" {
+		return []string{"performance", "powersave"}
+	}
+	return nil
+}
+
+// pstateFields shows the driver mode and, on intel_pstate, the
+// performance limits in percent of the maximum.
+func pstateFields(p PStateInfo) []output.Field {
+	var fields []output.Field
+	if p.Driver != "" {
+		fields = append(fields, output.Field{Key: "P-state Mode", Value: p.Driver + " " + p.Status, Status: output.StatusInfo})
+	}
+	if p.PerfPctKnown {
+		status := output.StatusInfo
+		if p.MaxPerfPct < 100 {
+			status = output.StatusWarn
+		}
+		fields = append(fields, output.Field{Key: "Perf Limits", Value: fmt.Sprintf("%d-%d%%", p.MinPerfPct, p.MaxPerfPct), Status: status})
+	}
+	return fields
+}
+
+// ClampFreq returns mhz in kHz, within the policy's cpuinfo range.
+func (p CPUPolicy) ClampFreq(mhz int) int {
+	if p.HWMaxFreqMHz > 0 {
+		mhz = min(max(mhz, p.HWMinFreqMHz), p.HWMaxFreqMHz)
+	}
+	return mhz * 1000
+}
+
+// classifyCores marks the core class of each policy on hybrid CPUs.
+// Intel lists its E-cores under /sys/devices/cpu_atom. Elsewhere, cores
+// whose capacity, or without one their top frequency, is below 80% of
+// the fastest are efficiency cores; the margin keeps AMD's preferred
+// cores, which boost a few hundred MHz higher, in one class.
+func classifyCores(policies []CPUPolicy) {
+	if atom, err := sysfs.ReadCPUList(sysfs.IntelAtomCPUs); err == nil && len(atom) > 0 {
+		for i := range policies {
+			policies[i].Class = CorePerformance
+			if slices.Contains(atom, policies[i].CPUs[0]) {
+				policies[i].Class = CoreEfficiency
+			}
+		}
+		return
+	}
+
+	rank := func(p CPUPolicy) int { return p.HWMaxFreqMHz }
+	if !slices.ContainsFunc(policies, func(p CPUPolicy) bool { return p.Capacity == 0 }) {
+		rank = func(p CPUPolicy) int { return p.Capacity }
+	}
+	top := 0
+	for _, p := range policies {
+		top = max(top, rank(p))
+	}
+	slow := func(p CPUPolicy) bool { return rank(p)*5 < top*4 }
+	if !slices.ContainsFunc(policies, slow) {
+		return
+	}
+	for i := range policies {
+		policies[i].Class = CorePerformance
+		if slow(policies[i]) {
+			policies[i].Class = CoreEfficiency
+		}
+	}
+}
+
+// CoreClasses returns the core classes present, performance first. It
+// is [""] on CPUs whose cores are all alike.
+func CoreClasses(policies []CPUPolicy) []string {
+	var classes []string
+	for _, class := range []string{"", CorePerformance, CoreEfficiency} {
+		if len(PoliciesOf(policies, class)) > 0 {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// PoliciesOf returns the policies of one core class.
+func PoliciesOf(policies []CPUPolicy, class string) []CPUPolicy {
+	var out []CPUPolicy
+	for _, p := range policies {
+		if p.Class == class {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// PolicyValues returns the distinct non-empty values get returns across
+// policies, in order.
+func PolicyValues(policies []CPUPolicy, get func(CPUPolicy) string) []string {
+	var vals []string
+	for _, p := range policies {
+		if v := get(p); v != "" && !slices.Contains(vals, v) {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// PolicyCPUs returns the CPUs of the policies, sorted.
+func PolicyCPUs(policies []CPUPolicy) []int {
+	var cpus []int
+	for _, p := range policies {
+		cpus = append(cpus, p.CPUs...)
+	}
+	slices.Sort(cpus)
+	return cpus
+}
+
+// coreFields describes the core classes of a hybrid CPU, with one entry
+// per cluster of cores that share a frequency range, and the preferred
+// cores amd-pstate ranks highest.
+func coreFields(policies []CPUPolicy) []output.Field {
+	var fields []output.Field
+	classes := CoreClasses(policies)
+	if classes[0] != "" {
+		var counts []string
+		for _, class := range classes {
+			counts = append(counts, fmt.Sprintf("%d %s", len(PolicyCPUs(PoliciesOf(policies, class))), class))
+		}
+		fields = append(fields, output.Field{Key: "Core Types", Value: strings.Join(counts, " + "), Status: output.StatusInfo})
+
+		for _, class := range classes {
+			group := PoliciesOf(policies, class)
+			var clusters []string
+			for _, r := range PolicyValues(group, freqRange) {
+				var cluster []CPUPolicy
+				for _, p := range group {
+					if freqRange(p) == r {
+						cluster = append(cluster, p)
+					}
+				}
+				clusters = append(clusters, fmt.Sprintf("cpus %s, %s", sysfs.FormatCPUList(PolicyCPUs(cluster)), r))
+			}
+			key := strings.ToUpper(class[:1]) + class[1:] + " Cores"
+			fields = append(fields, output.Field{Key: key, Value: strings.Join(clusters, "; "), Status: output.StatusInfo})
+		}
+	}
+
+	top := 0
+	for _, p := range policies {
+		top = max(top, p.PrefRanking)
+	}
+	var preferred []CPUPolicy
+	for _, p := range policies {
+		if p.PrefRanking == top {
+			preferred = append(preferred, p)
+		}
+	}
+	if top > 0 && len(preferred) < len(policies) {
+		fields = append(fields, output.Field{Key: "Preferred Cores", Value: "cpus " + sysfs.FormatCPUList(PolicyCPUs(preferred)), Status: output.StatusInfo})
+	}
+	return fields
+}
+
+func freqRange(p CPUPolicy) string {
+	return fmt.Sprintf("%d-%d MHz", p.HWMinFreqMHz, p.HWMaxFreqMHz)
+}
+
+// policyField summarises one cpufreq attribute across policies. Values
+// that differ within a core class are a misconfiguration and warn;
+// hybrid CPUs may run each class differently on purpose.
+func policyField(key string, policies []CPUPolicy, get func(CPUPolicy) string, status func(string) output.Status) output.Field {
+	vals := PolicyValues(policies, get)
+	if len(vals) <= 1 {
+		val := strings.Join(vals, "")
+		return output.Field{Key: key, Value: val, Status: status(val)}
+	}
+
+	var parts []string
+	mixed := false
+	for _, class := range CoreClasses(policies) {
+		group := PoliciesOf(policies, class)
+		classVals := PolicyValues(group, get)
+		if len(classVals) == 1 {
+			parts = append(parts, fmt.Sprintf("%s on %s cores", classVals[0], class))
+			continue
+		}
+		mixed = true
+		for _, v := range classVals {
+			var cpus []CPUPolicy
+			for _, p := range group {
+				if get(p) == v {
+					cpus = append(cpus, p)
+				}
+			}
+			parts = append(parts, fmt.Sprintf("%s on cpus %s", v, sysfs.FormatCPUList(PolicyCPUs(cpus))))
+		}
+	}
+	if mixed {
+		return output.Field{Key: key, Value: "mixed: " + strings.Join(parts, ", "), Status: output.StatusWarn}
+	}
+	return output.Field{Key: key, Value: strings.Join(parts, ", "), Status: output.StatusInfo}
+}
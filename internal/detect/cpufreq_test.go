@@ -0,0 +1,117 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// cpufreqFixture writes one policy directory per entry of policies, keyed
+// by policy name, plus any extra files.
+func cpufreqFixture(t *testing.T, policies map[string]map[string]string, extra map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{}
+	for policy, attrs := range policies {
+		for name, value := range attrs {
+			files[sysfs.CPUFreqPolicies+"/"+policy+"/"+name] = value
+		}
+	}
+	for path, value := range extra {
+		files[path] = value
+	}
+	for path, value := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sysfs.SetRoot(dir)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+}
+
+func policy(cpus, governor, maxKHz string) map[string]string {
+	return map[string]string{
+		"related_cpus":     cpus,
+		"scaling_governor": governor,
+		"cpuinfo_min_freq": "800000",
+		"cpuinfo_max_freq": maxKHz,
+	}
+}
+
+func TestDetectCPUPoliciesIntelHybrid(t *testing.T) {
+	cpufreqFixture(t, map[string]map[string]string{
+		"policy0":  policy("0", "performance", "5400000"),
+		"policy1":  policy("1", "powersave", "5400000"),
+		"policy2":  policy("2", "powersave", "4300000"),
+		"policy10": policy("10", "powersave", "4300000"),
+	}, map[string]string{sysfs.IntelAtomCPUs: "2-10"})
+
+	policies := DetectCPUPolicies()
+	var order []int
+	for _, p := range policies {
+		order = append(order, p.CPUs[0])
+	}
+	if !slices.Equal(order, []int{0, 1, 2, 10}) {
+		t.Fatalf("policy order = %v, want CPU order", order)
+	}
+	if got := CoreClasses(policies); !slices.Equal(got, []string{CorePerformance, CoreEfficiency}) {
+		t.Errorf("classes = %q", got)
+	}
+	if p := policies[2]; p.Class != CoreEfficiency || p.HWMaxFreqMHz != 4300 {
+		t.Errorf("policy2 = %+v, want an efficiency core up to 4300 MHz", p)
+	}
+
+	// The P-cores disagree; the E-cores agree with each other.
+	gov := policyField("Governor", policies, func(p CPUPolicy) string { return p.Governor }, governorStatus)
+	if gov.Status != output.StatusWarn || gov.Value != "mixed: performance on cpus 0, powersave on cpus 1, powersave on efficiency cores" {
+		t.Errorf("governor field = %+v", gov)
+	}
+}
+
+func TestDetectCPUPoliciesBigLittle(t *testing.T) {
+	cpufreqFixture(t, map[string]map[string]string{
+		"policy0": policy("0-3", "schedutil", "1800000"),
+		"policy4": policy("4-7", "schedutil", "2400000"),
+	}, map[string]string{
+		sysfs.CPUBase + "/cpu0/cpu_capacity": "446",
+		sysfs.CPUBase + "/cpu4/cpu_capacity": "1024",
+	})
+
+	policies := DetectCPUPolicies()
+	if len(policies) != 2 || policies[0].Class != CoreEfficiency || policies[1].Class != CorePerformance {
+		t.Fatalf("policies = %+v, want LITTLE then big", policies)
+	}
+	if got := PolicyCPUs(PoliciesOf(policies, CorePerformance)); !slices.Equal(got, []int{4, 5, 6, 7}) {
+		t.Errorf("big cores = %v", got)
+	}
+	gov := policyField("Governor", policies, func(p CPUPolicy) string { return p.Governor }, governorStatus)
+	if gov.Value != "schedutil" || gov.Status != output.StatusGood {
+		t.Errorf("governor field = %+v", gov)
+	}
+}
+
+func TestDetectCPUPoliciesPreferredCores(t *testing.T) {
+	p0 := policy("0", "performance", "5700000")
+	p0["amd_pstate_prefcore_ranking"] = "236"
+	p1 := policy("1", "performance", "5500000")
+	p1["amd_pstate_prefcore_ranking"] = "231"
+	cpufreqFixture(t, map[string]map[string]string{"policy0": p0, "policy1": p1}, nil)
+
+	// A few hundred MHz of extra boost does not make a core class.
+	policies := DetectCPUPolicies()
+	if got := CoreClasses(policies); !slices.Equal(got, []string{""}) {
+		t.Errorf("classes = %q, want cores alike", got)
+	}
+	fields := coreFields(policies)
+	if len(fields) != 1 || fields[0].Key != "Preferred Cores" || fields[0].Value != "cpus 0" {
+		t.Errorf("core fields = %+v", fields)
+	}
+}
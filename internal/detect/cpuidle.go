@@ -0,0 +1,141 @@
+package detect
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// IdleInfo holds cpuidle (C-state) data.
+type IdleInfo struct {
+	Driver   string // e.g. intel_idle, acpi_idle
+	Governor string // e.g. menu, teo
+	States   []IdleState
+
+	// DMALatencyUS is the CPU latency limit applications hold through
+	// /dev/cpu_dma_latency, -1 for none. Unknown without root.
+	DMALatencyUS    int
+	DMALatencyKnown bool
+}
+
+// IdleState is one C-state summed over the CPUs that have it. States
+// are matched by name: hybrid CPUs may number them differently.
+type IdleState struct {
+	Name        string
+	LatencyUS   int   // exit latency
+	ResidencyUS int   // target residency
+	Usage       int64 // times entered
+	TimeUS      int64 // time spent
+	CPUs        int   // CPUs that have the state
+	Disabled    int   // CPUs with the state disabled
+}
+
+// cpuLatencyNone is what /dev/cpu_dma_latency reads when nothing holds a
+// limit (PM_QOS_CPU_LATENCY_DEFAULT_VALUE).
+const cpuLatencyNone = 2000 * 1000 * 1000
+
+// DetectIdle reads the cpuidle states of every CPU and the PM QoS CPU
+// latency limit.
+func DetectIdle() IdleInfo {
+	info := IdleInfo{DMALatencyUS: -1}
+	info.Driver, _ = sysfs.ReadString(sysfs.CPUIdleDriver)
+	info.Governor, _ = sysfs.ReadString(sysfs.CPUIdleGovernor)
+
+	dirs, _ := sysfs.EachCPU("cpuidle")
+	index := make(map[string]int)
+	for _, dir := range dirs {
+		for _, state := range idleStateDirs(dir) {
+			name, err := sysfs.ReadString(state + "/name")
+			if err != nil {
+				continue
+			}
+			i, ok := index[name]
+			if !ok {
+				s := IdleState{Name: name}
+				s.LatencyUS, _ = sysfs.ReadInt(state + "/latency")
+				s.ResidencyUS, _ = sysfs.ReadInt(state + "/residency")
+				i = len(info.States)
+				index[name] = i
+				info.States = append(info.States, s)
+			}
+			s := &info.States[i]
+			s.CPUs++
+			if usage, err := sysfs.ReadInt64(state + "/usage"); err == nil {
+				s.Usage += usage
+			}
+			if t, err := sysfs.ReadInt64(state + "/time"); err == nil {
+				s.TimeUS += t
+			}
+			if disabled, _ := sysfs.ReadInt(state + "/disable"); disabled == 1 {
+				s.Disabled++
+			}
+		}
+	}
+
+	// The device holds a native-endian s32; reading it sets no limit.
+	if data, err := sysfs.ReadFile(sysfs.CPUDMALatency); err == nil && len(data) >= 4 {
+		info.DMALatencyKnown = true
+		if v := int32(binary.NativeEndian.Uint32(data)); v < cpuLatencyNone {
+			info.DMALatencyUS = int(v)
+		}
+	}
+	return info
+}
+
+// idleStateDirs returns the stateN directories under a CPU's cpuidle
+// directory, in state order.
+func idleStateDirs(dir string) []string {
+	var states []string
+	for n := 0; sysfs.Exists(fmt.Sprintf("%s/state%d", dir, n)); n++ {
+		states = append(states, fmt.Sprintf("%s/state%d", dir, n))
+	}
+	return states
+}
+
+// IdleSection formats cpuidle info as an output section: the share of
+// idle time each state got and how often it was entered since boot.
+func IdleSection(info IdleInfo) output.Section {
+	sec := output.Section{Title: "CPU Idle"}
+	if len(info.States) == 0 {
+		sec.Fields = append(sec.Fields, output.Field{Key: "C-states", Value: "not available (no cpuidle driver)", Status: output.StatusInfo})
+		return sec
+	}
+
+	driver := info.Driver
+	if info.Governor != "" {
+		driver += ", " + info.Governor + " governor"
+	}
+	sec.Fields = append(sec.Fields, output.Field{Key: "Idle Driver", Value: driver, Status: output.StatusInfo})
+
+	switch {
+	case !info.DMALatencyKnown:
+	case info.DMALatencyUS < 0:
+		sec.Fields = append(sec.Fields, output.Field{Key: "PM QoS Latency", Value: "no limit", Status: output.StatusInfo})
+	default:
+		sec.Fields = append(sec.Fields, output.Field{Key: "PM QoS Latency", Value: fmt.Sprintf("%dus (held through %s)", info.DMALatencyUS, sysfs.CPUDMALatency), Status: output.StatusInfo})
+	}
+
+	var total int64
+	for _, s := range info.States {
+		total += s.TimeUS
+	}
+	for _, s := range info.States {
+		share := 0.0
+		if total > 0 {
+			share = float64(s.TimeUS) * 100 / float64(total)
+		}
+		value := fmt.Sprintf("%dus exit, %.1f%% of idle time, %d entries", s.LatencyUS, share, s.Usage)
+		status := output.StatusInfo
+		switch {
+		case s.Disabled == s.CPUs:
+			value += ", disabled"
+		case s.Disabled > 0:
+			value += fmt.Sprintf(", disabled on %d of %d CPUs", s.Disabled, s.CPUs)
+			status = output.StatusWarn
+		}
+		sec.Fields = append(sec.Fields, output.Field{Key: s.Name, Value: value, Status: status})
+	}
+	return sec
+}
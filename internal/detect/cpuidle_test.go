@@ -0,0 +1,67 @@
+package detect
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestDetectIdle(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, data []byte) {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// cpu1 numbers C6 differently and has it disabled.
+	for state, attrs := range map[string][]string{
+		"cpu0/cpuidle/state0": {"POLL", "0", "100", "1000", "0"},
+		"cpu0/cpuidle/state1": {"C1", "2", "500", "3000", "0"},
+		"cpu0/cpuidle/state2": {"C6", "170", "400", "16000", "0"},
+		"cpu1/cpuidle/state0": {"POLL", "0", "100", "1000", "0"},
+		"cpu1/cpuidle/state1": {"C6", "170", "0", "0", "1"},
+	} {
+		base := sysfs.CPUBase + "/" + state
+		for i, name := range []string{"name", "latency", "usage", "time", "disable"} {
+			write(base+"/"+name, []byte(attrs[i]+"\n"))
+		}
+	}
+	write(sysfs.CPUIdleDriver, []byte("intel_idle\n"))
+	write(sysfs.CPUIdleGovernor, []byte("menu\n"))
+	latency := make([]byte, 4)
+	binary.NativeEndian.PutUint32(latency, 20)
+	write(sysfs.CPUDMALatency, latency)
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	info := DetectIdle()
+	if len(info.States) != 3 {
+		t.Fatalf("states = %+v, want POLL, C1, C6", info.States)
+	}
+	if c6 := info.States[2]; c6.Name != "C6" || c6.CPUs != 2 || c6.Disabled != 1 || c6.Usage != 400 {
+		t.Errorf("C6 = %+v, want it on 2 CPUs, disabled on one", c6)
+	}
+	if !info.DMALatencyKnown || info.DMALatencyUS != 20 {
+		t.Errorf("PM QoS latency = %d (known %v), want 20", info.DMALatencyUS, info.DMALatencyKnown)
+	}
+
+	fields := map[string]output.Field{}
+	for _, f := range IdleSection(info).Fields {
+		fields[f.Key] = f
+	}
+	if f := fields["C6"]; f.Value != "170us exit, 76.2% of idle time, 400 entries, disabled on 1 of 2 CPUs" || f.Status != output.StatusWarn {
+		t.Errorf("C6 field = %+v", f)
+	}
+	if f := fields["PM QoS Latency"]; f.Value != "20us (held through /dev/cpu_dma_latency)" {
+		t.Errorf("PM QoS field = %+v", f)
+	}
+}
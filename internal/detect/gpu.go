@@ -2,7 +2,6 @@ package detect
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -29,7 +28,7 @@ type GPUCard struct {
 func DetectGPU() GPUInfo {
 	info := GPUInfo{}
 
-	entries, err := os.ReadDir(sysfs.DRMBase)
+	entries, err := sysfs.ReadDir(sysfs.DRMBase)
 	if err != nil {
 		return info
 	}
@@ -47,7 +46,7 @@ func DetectGPU() GPUInfo {
 		card := GPUCard{Name: name}
 
 		// Driver via symlink
-		driverLink, err := os.Readlink(filepath.Join(deviceBase, "driver"))
+		driverLink, err := sysfs.Readlink(filepath.Join(deviceBase, "driver"))
 		if err == nil {
 			card.Driver = filepath.Base(driverLink)
 		}
@@ -0,0 +1,188 @@
+package detect
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// HugePageInfo holds the huge page pools, THP defrag and khugepaged.
+type HugePageInfo struct {
+	DefaultSizeKB int64
+	Pools         []HugePagePool // by size, the system-wide pool before its nodes
+	THPDefrag     string
+	Khugepaged    KhugepagedInfo
+}
+
+// HugePagePool is the huge page pool of one size, system-wide or on one
+// NUMA node.
+type HugePagePool struct {
+	SizeKB  int64
+	Node    int // -1 for the system-wide pool
+	Total   int // nr_hugepages
+	Free    int
+	Surplus int
+}
+
+// KhugepagedInfo is what khugepaged, the thread that collapses pages
+// into transparent huge pages, is set to do and has done.
+type KhugepagedInfo struct {
+	Known          bool
+	Defrag         bool // compact memory to find huge pages
+	PagesToScan    int
+	ScanSleepMS    int
+	AllocSleepMS   int
+	MaxPtesNone    int
+	FullScans      int64
+	PagesCollapsed int64
+}
+
+// HugePagePoolDir returns the sysfs directory of a huge page pool; node
+// -1 is the system-wide pool.
+func HugePagePoolDir(sizeKB int64, node int) string {
+	if node < 0 {
+		return fmt.Sprintf("%s/hugepages-%dkB", sysfs.HugepagesBase, sizeKB)
+	}
+	return fmt.Sprintf("%s/node%d/hugepages/hugepages-%dkB", sysfs.NodeBase, node, sizeKB)
+}
+
+// DetectHugePages reads every huge page pool, system-wide and per node.
+func DetectHugePages() HugePageInfo {
+	var info HugePageInfo
+	if lines, err := sysfs.ReadLines(sysfs.ProcMemInfo); err == nil {
+		for _, line := range lines {
+			if f := strings.Fields(line); len(f) >= 2 && f[0] == "Hugepagesize:" {
+				info.DefaultSizeKB, _ = strconv.ParseInt(f[1], 10, 64)
+			}
+		}
+	}
+	info.THPDefrag, _ = sysfs.ReadBracketedValue(sysfs.THPDefrag)
+
+	for _, size := range poolSizes(sysfs.HugepagesBase) {
+		info.Pools = append(info.Pools, readPool(size, -1))
+	}
+	nodes, _ := sysfs.ReadDir(sysfs.NodeBase)
+	for _, e := range nodes {
+		node, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "node"))
+		if err != nil || !strings.HasPrefix(e.Name(), "node") {
+			continue
+		}
+		for _, size := range poolSizes(fmt.Sprintf("%s/%s/hugepages", sysfs.NodeBase, e.Name())) {
+			info.Pools = append(info.Pools, readPool(size, node))
+		}
+	}
+	sort.Slice(info.Pools, func(i, j int) bool {
+		a, b := info.Pools[i], info.Pools[j]
+		if a.SizeKB != b.SizeKB {
+			return a.SizeKB < b.SizeKB
+		}
+		return a.Node < b.Node
+	})
+
+	k := &info.Khugepaged
+	if v, err := sysfs.ReadInt(sysfs.KhugepagedBase + "/pages_to_scan"); err == nil {
+		k.Known = true
+		k.PagesToScan = v
+		defrag, _ := sysfs.ReadInt(sysfs.KhugepagedBase + "/defrag")
+		k.Defrag = defrag == 1
+		k.ScanSleepMS, _ = sysfs.ReadInt(sysfs.KhugepagedBase + "/scan_sleep_millisecs")
+		k.AllocSleepMS, _ = sysfs.ReadInt(sysfs.KhugepagedBase + "/alloc_sleep_millisecs")
+		k.MaxPtesNone, _ = sysfs.ReadInt(sysfs.KhugepagedBase + "/max_ptes_none")
+		k.FullScans, _ = sysfs.ReadInt64(sysfs.KhugepagedBase + "/full_scans")
+		k.PagesCollapsed, _ = sysfs.ReadInt64(sysfs.KhugepagedBase + "/pages_collapsed")
+	}
+	return info
+}
+
+// poolSizes lists the page sizes of the hugepages-<size>kB directories
+// under dir.
+func poolSizes(dir string) []int64 {
+	entries, _ := sysfs.ReadDir(dir)
+	var sizes []int64
+	for _, e := range entries {
+		s := strings.TrimSuffix(strings.TrimPrefix(e.Name(), "hugepages-"), "kB")
+		if size, err := strconv.ParseInt(s, 10, 64); err == nil {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
+
+func readPool(sizeKB int64, node int) HugePagePool {
+	p := HugePagePool{SizeKB: sizeKB, Node: node}
+	dir := HugePagePoolDir(sizeKB, node)
+	p.Total, _ = sysfs.ReadInt(dir + "/nr_hugepages")
+	p.Free, _ = sysfs.ReadInt(dir + "/free_hugepages")
+	p.Surplus, _ = sysfs.ReadInt(dir + "/surplus_hugepages")
+	return p
+}
+
+// HugePageSizeStr formats a huge page size, e.g. "2 MB" or "1 GB".
+func HugePageSizeStr(kb int64) string {
+	switch {
+	case kb >= 1<<20 && kb%(1<<20) == 0:
+		return fmt.Sprintf("%d GB", kb>>20)
+	case kb >= 1<<10 && kb%(1<<10) == 0:
+		return fmt.Sprintf("%d MB", kb>>10)
+	default:
+		return fmt.Sprintf("%d kB", kb)
+	}
+}
+
+// HugePagesSection formats huge page info as an output section. Per-node
+// pools are listed under their size on machines with several nodes.
+func HugePagesSection(info HugePageInfo) output.Section {
+	sec := output.Section{Title: "Huge Pages"}
+	if info.DefaultSizeKB > 0 {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Default Size", Value: HugePageSizeStr(info.DefaultSizeKB), Status: output.StatusInfo})
+	}
+
+	nodes := make(map[int64]int)
+	for _, p := range info.Pools {
+		if p.Node >= 0 {
+			nodes[p.SizeKB]++
+		}
+	}
+	for _, p := range info.Pools {
+		key := HugePageSizeStr(p.SizeKB)
+		if p.Node >= 0 {
+			if nodes[p.SizeKB] < 2 {
+				continue
+			}
+			key = fmt.Sprintf("  node%d", p.Node)
+		}
+		value := fmt.Sprintf("%d reserved, %d free", p.Total, p.Free)
+		if p.Total == 0 {
+			value = "none reserved"
+		}
+		if p.Surplus > 0 {
+			value += fmt.Sprintf(", %d surplus", p.Surplus)
+		}
+		sec.Fields = append(sec.Fields, output.Field{Key: key, Value: value, Status: output.StatusInfo})
+	}
+
+	if info.THPDefrag != "" {
+		status := output.StatusInfo
+		if info.THPDefrag == "always" {
+			status = output.StatusWarn // direct compaction stalls the faulting task
+		}
+		sec.Fields = append(sec.Fields, output.Field{Key: "THP Defrag", Value: info.THPDefrag, Status: status})
+	}
+	if k := info.Khugepaged; k.Known {
+		defrag := "off"
+		if k.Defrag {
+			defrag = "on"
+		}
+		sec.Fields = append(sec.Fields, output.Field{
+			Key: "Khugepaged",
+			Value: fmt.Sprintf("defrag %s, %d pages every %d ms, %d collapsed in %d full scans",
+				defrag, k.PagesToScan, k.ScanSleepMS, k.PagesCollapsed, k.FullScans),
+			Status: output.StatusInfo,
+		})
+	}
+	return sec
+}
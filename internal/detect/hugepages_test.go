@@ -0,0 +1,74 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestDetectHugePages(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool := func(sizeKB int64, node int, total, free string) {
+		write(HugePagePoolDir(sizeKB, node)+"/nr_hugepages", total)
+		write(HugePagePoolDir(sizeKB, node)+"/free_hugepages", free)
+		write(HugePagePoolDir(sizeKB, node)+"/surplus_hugepages", "0\n")
+	}
+	pool(1048576, -1, "0\n", "0\n")
+	pool(2048, -1, "1024\n", "1000\n")
+	pool(2048, 1, "512\n", "500\n")
+	pool(2048, 0, "512\n", "500\n")
+	write(sysfs.NodeBase+"/possible", "0-1\n")
+	write(sysfs.ProcMemInfo, "MemTotal:       32768000 kB\nHugepagesize:       2048 kB\n")
+	write(sysfs.THPDefrag, "[always] defer defer+madvise madvise never\n")
+	for name, v := range map[string]string{
+		"defrag": "1", "pages_to_scan": "4096", "scan_sleep_millisecs": "10000",
+		"alloc_sleep_millisecs": "60000", "max_ptes_none": "511", "full_scans": "12", "pages_collapsed": "340",
+	} {
+		write(sysfs.KhugepagedBase+"/"+name, v+"\n")
+	}
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	info := DetectHugePages()
+	if info.DefaultSizeKB != 2048 || info.THPDefrag != "always" {
+		t.Errorf("default size %d, defrag %q", info.DefaultSizeKB, info.THPDefrag)
+	}
+	order := info.Pools
+	if len(order) != 4 || order[0].Node != -1 || order[0].SizeKB != 2048 || order[1].Node != 0 ||
+		order[2].Node != 1 || order[3].SizeKB != 1048576 {
+		t.Fatalf("pools = %+v", order)
+	}
+	if order[0].Total != 1024 || order[0].Free != 1000 {
+		t.Errorf("system 2 MB pool = %+v", order[0])
+	}
+	if k := info.Khugepaged; !k.Known || !k.Defrag || k.PagesToScan != 4096 || k.PagesCollapsed != 340 {
+		t.Errorf("khugepaged = %+v", k)
+	}
+
+	fields := make(map[string]output.Field)
+	for _, f := range HugePagesSection(info).Fields {
+		fields[f.Key] = f
+	}
+	if f := fields["  node1"]; f.Value != "512 reserved, 500 free" {
+		t.Errorf("node1 = %q", f.Value)
+	}
+	if f := fields["1 GB"]; f.Value != "none reserved" {
+		t.Errorf("1 GB = %q", f.Value)
+	}
+	if f := fields["THP Defrag"]; f.Status != output.StatusWarn {
+		t.Errorf("THP defrag always not warned: %+v", f)
+	}
+}
@@ -0,0 +1,323 @@
+package detect
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// IRQInfo holds the interrupt layout of the physical NICs.
+type IRQInfo struct {
+	IRQBalance ServiceState
+	NICs       []NICQueues
+}
+
+// NICQueues is one NIC's queue interrupts and steering masks.
+type NICQueues struct {
+	Name      string
+	NUMANode  int   // -1 if the device reports none
+	LocalCPUs []int // online CPUs of the NIC's NUMA node, or all online CPUs
+	IRQs      []QueueIRQ
+	RPS       [][]int // rps_cpus per rx queue, nil when off
+	XPS       [][]int // xps_cpus per tx queue, nil when off
+}
+
+// QueueIRQ is one queue interrupt vector of a NIC.
+type QueueIRQ struct {
+	IRQ       int
+	Name      string // action name, e.g. eth0-TxRx-0
+	Count     int64  // interrupts since boot, all CPUs
+	Affinity  []int  // smp_affinity_list
+	Effective []int  // effective_affinity_list: the CPUs that take it
+}
+
+// CPUs returns the CPUs the interrupt is delivered to.
+func (q QueueIRQ) CPUs() []int {
+	if len(q.Effective) > 0 {
+		return q.Effective
+	}
+	return q.Affinity
+}
+
+// DetectIRQ reads the queue interrupts of every physical NIC and whether
+// irqbalance runs.
+func DetectIRQ() IRQInfo {
+	return IRQInfo{IRQBalance: checkService("irqbalance"), NICs: DetectNICQueues()}
+}
+
+// DetectNICQueues reads the queue interrupts, their affinity and the
+// RPS/XPS masks of every physical, wired NIC.
+func DetectNICQueues() []NICQueues {
+	entries, err := sysfs.ReadDir(sysfs.NetBase)
+	if err != nil {
+		return nil
+	}
+	irqs := readInterrupts()
+	online, _ := sysfs.ReadCPUList(sysfs.CPUOnline)
+
+	var nics []NICQueues
+	for _, e := range entries {
+		base := filepath.Join(sysfs.NetBase, e.Name())
+		link, err := sysfs.Readlink(filepath.Join(base, "device"))
+		if err != nil || strings.Contains(link, "virtual") ||
+			sysfs.Exists(filepath.Join(base, "wireless")) || sysfs.Exists(filepath.Join(base, "phy80211")) {
+			continue
+		}
+
+		n := NICQueues{Name: e.Name(), NUMANode: -1, LocalCPUs: online}
+		if v, err := sysfs.ReadInt(filepath.Join(base, "device/numa_node")); err == nil {
+			n.NUMANode = v
+		}
+		if local, err := sysfs.ReadCPUList(filepath.Join(base, "device/local_cpulist")); err == nil && len(local) > 0 {
+			// The node mask includes offline CPUs.
+			if len(online) > 0 {
+				local = slices.DeleteFunc(local, func(cpu int) bool { return !slices.Contains(online, cpu) })
+			}
+			n.LocalCPUs = local
+		}
+		n.IRQs = nicIRQs(n.Name, base, irqs)
+		n.RPS = queueMasks(base, "rx-", "rps_cpus")
+		n.XPS = queueMasks(base, "tx-", "xps_cpus")
+		nics = append(nics, n)
+	}
+	return nics
+}
+
+// interrupt is one numbered line of /proc/interrupts.
+type interrupt struct {
+	name  string
+	count int64
+}
+
+// readInterrupts parses the numbered lines of /proc/interrupts: a count
+// column per CPU, then the chip, the hardware IRQ and the action name.
+func readInterrupts() map[int]interrupt {
+	lines, err := sysfs.ReadLines(sysfs.ProcInterrupts)
+	if err != nil || len(lines) == 0 {
+		return nil
+	}
+	ncpu := len(strings.Fields(lines[0]))
+	irqs := make(map[int]interrupt)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < ncpu+2 {
+			continue
+		}
+		irq, err := strconv.Atoi(strings.TrimSuffix(fields[0], ":"))
+		if err != nil {
+			continue
+		}
+		in := interrupt{name: fields[len(fields)-1]}
+		for _, c := range fields[1 : ncpu+1] {
+			n, _ := strconv.ParseInt(c, 10, 64)
+			in.count += n
+		}
+		irqs[irq] = in
+	}
+	return irqs
+}
+
+// nicIRQs returns the queue vectors of a NIC: its MSI vectors, or for
+// devices without MSI, the interrupts named after the interface.
+func nicIRQs(name, base string, irqs map[int]interrupt) []QueueIRQ {
+	var numbers []int
+	if entries, err := sysfs.ReadDir(filepath.Join(base, "device/msi_irqs")); err == nil {
+		for _, e := range entries {
+			if n, err := strconv.Atoi(e.Name()); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+	} else {
+		for n, in := range irqs {
+			if namedAfter(in.name, name) {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	sort.Ints(numbers)
+
+	var out []QueueIRQ
+	for _, n := range numbers {
+		in, ok := irqs[n]
+		if !ok || !isQueueVector(name, in.name) {
+			continue
+		}
+		q := QueueIRQ{IRQ: n, Name: in.name, Count: in.count}
+		dir := fmt.Sprintf("%s/%d", sysfs.ProcIRQBase, n)
+		q.Affinity, _ = sysfs.ReadCPUList(dir + "/smp_affinity_list")
+		q.Effective, _ = sysfs.ReadCPUList(dir + "/effective_affinity_list")
+		out = append(out, q)
+	}
+	return out
+}
+
+// namedAfter reports whether an interrupt action belongs to iface: the
+// bare name, or the name followed by "-", "@" or ":" as in eth1-rx-0,
+// so that eth1 does not claim the vectors of eth10.
+func namedAfter(action, iface string) bool {
+	rest, ok := strings.CutPrefix(action, iface)
+	return ok && (rest == "" || strings.ContainsAny(rest[:1], "-@:"))
+}
+
+// isQueueVector tells queue vectors (eth0-TxRx-0, mlx5_comp3@pci:...,
+// virtio0-input.0) from the link, admin and async vectors of a NIC.
+func isQueueVector(iface, action string) bool {
+	s := strings.ToLower(strings.ReplaceAll(action, iface, ""))
+	for _, marker := range []string{"rx", "tx", "comp", "input", "output", "queue", "-fp-"} {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// queueMasks reads a CPU mask file of every rx-N or tx-N queue, in
+// queue order.
+func queueMasks(base, prefix, file string) [][]int {
+	entries, err := sysfs.ReadDir(filepath.Join(base, "queues"))
+	if err != nil {
+		return nil
+	}
+	var index []int
+	for _, e := range entries {
+		if n, err := strconv.Atoi(strings.TrimPrefix(e.Name(), prefix)); err == nil && strings.HasPrefix(e.Name(), prefix) {
+			index = append(index, n)
+		}
+	}
+	sort.Ints(index)
+	masks := make([][]int, len(index))
+	for i, n := range index {
+		if s, err := sysfs.ReadString(fmt.Sprintf("%s/queues/%s%d/%s", base, prefix, n, file)); err == nil {
+			masks[i], _ = sysfs.ParseCPUMask(s)
+		}
+	}
+	return masks
+}
+
+// RemoteIRQs returns how many queue interrupts reach a CPU outside the
+// NIC's NUMA node.
+func (n NICQueues) RemoteIRQs() int {
+	remote := 0
+	for _, q := range n.IRQs {
+		for _, cpu := range q.CPUs() {
+			if !slices.Contains(n.LocalCPUs, cpu) {
+				remote++
+				break
+			}
+		}
+	}
+	return remote
+}
+
+// SpreadTargets returns the CPU to pin each of n.IRQs to: the NIC's
+// local CPUs in turn, one physical core each before any hyperthread
+// sibling, so that two busy queues share a core only when they must.
+func (n NICQueues) SpreadTargets() []int {
+	cpus := coresFirst(n.LocalCPUs)
+	if len(cpus) == 0 {
+		return nil
+	}
+	targets := make([]int, len(n.IRQs))
+	for i := range n.IRQs {
+		targets[i] = cpus[i%len(cpus)]
+	}
+	return targets
+}
+
+// coresFirst orders CPUs with the first thread of every core ahead of
+// the siblings.
+func coresFirst(cpus []int) []int {
+	var first, siblings []int
+	for _, cpu := range cpus {
+		threads, err := sysfs.ReadCPUList(fmt.Sprintf("%s/cpu%d/topology/thread_siblings_list", sysfs.CPUBase, cpu))
+		if err != nil || len(threads) == 0 || threads[0] == cpu {
+			first = append(first, cpu)
+		} else {
+			siblings = append(siblings, cpu)
+		}
+	}
+	return append(first, siblings...)
+}
+
+// IRQSection formats NIC queue interrupts as an output section.
+func IRQSection(info IRQInfo) output.Section {
+	sec := output.Section{Title: "IRQ Affinity"}
+	sec.Fields = append(sec.Fields, output.Field{Key: "IRQ Balance", Value: serviceStr(info.IRQBalance), Status: output.StatusInfo})
+
+	for _, n := range info.NICs {
+		node := "no NUMA node"
+		if n.NUMANode >= 0 {
+			node = fmt.Sprintf("NUMA node %d", n.NUMANode)
+		}
+		sec.Fields = append(sec.Fields, output.Field{
+			Key:    n.Name,
+			Value:  fmt.Sprintf("%d queue IRQs, %d rx / %d tx queues, %s (CPUs %s)", len(n.IRQs), len(n.RPS), len(n.XPS), node, sysfs.FormatCPUList(n.LocalCPUs)),
+			Status: output.StatusInfo,
+		})
+		if len(n.IRQs) > 0 {
+			sec.Fields = append(sec.Fields, affinityField(n))
+		}
+		for _, q := range n.IRQs {
+			value := fmt.Sprintf("IRQ %d, CPUs %s, %d interrupts", q.IRQ, sysfs.FormatCPUList(q.Affinity), q.Count)
+			if len(q.Effective) > 0 && !slices.Equal(q.Effective, q.Affinity) {
+				value += ", taken by " + sysfs.FormatCPUList(q.Effective)
+			}
+			sec.Fields = append(sec.Fields, output.Field{Key: "  " + q.Name, Value: value, Status: output.StatusInfo})
+		}
+		sec.Fields = append(sec.Fields,
+			output.Field{Key: "  RPS", Value: masksStr(n.RPS, "rx"), Status: output.StatusInfo},
+			output.Field{Key: "  XPS", Value: masksStr(n.XPS, "tx"), Status: output.StatusInfo},
+		)
+	}
+	return sec
+}
+
+// affinityField sums up where a NIC's queue interrupts land.
+func affinityField(n NICQueues) output.Field {
+	f := output.Field{Key: "  Affinity"}
+	var cpus []int
+	for _, q := range n.IRQs {
+		for _, cpu := range q.CPUs() {
+			if !slices.Contains(cpus, cpu) {
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	remote := n.RemoteIRQs()
+	switch {
+	case remote > 0:
+		f.Value = fmt.Sprintf("%d of %d IRQs reach CPUs outside the NIC's node", remote, len(n.IRQs))
+		f.Status = output.StatusWarn
+	case len(n.IRQs) > 1 && len(cpus) == 1:
+		f.Value = fmt.Sprintf("all on CPU %d", cpus[0])
+		f.Status = output.StatusWarn
+	default:
+		f.Value = fmt.Sprintf("on %d local CPUs", len(cpus))
+		f.Status = output.StatusGood
+	}
+	return f
+}
+
+// masksStr sums up the RPS or XPS masks of a NIC's queues.
+func masksStr(masks [][]int, kind string) string {
+	set := 0
+	for _, m := range masks {
+		if len(m) > 0 {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return "off"
+	case set == len(masks) && slices.IndexFunc(masks, func(m []int) bool { return !slices.Equal(m, masks[0]) }) < 0:
+		return fmt.Sprintf("CPUs %s on all %d %s queues", sysfs.FormatCPUList(masks[0]), len(masks), kind)
+	default:
+		return fmt.Sprintf("set per queue on %d of %d %s queues", set, len(masks), kind)
+	}
+}
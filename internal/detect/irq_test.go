@@ -0,0 +1,110 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestDetectNICQueues(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pci := "/sys/devices/pci0000:00/0000:03:00.0"
+	write(sysfs.ProcInterrupts, `           CPU0       CPU1       CPU2       CPU3
+  0:         16          0          0          0   IO-APIC   2-edge      timer
+ 24:          1          0          0          0   PCI-MSIX-0000:03:00.0   0-edge      eth0
+ 25:        100        200          0          0   PCI-MSIX-0000:03:00.0   1-edge      eth0-TxRx-0
+ 26:          0          0          0        300   PCI-MSIX-0000:03:00.0   2-edge      eth0-TxRx-1
+NMI:          0          0          0          0   Non-maskable interrupts
+`)
+	write(sysfs.CPUOnline, "0-3\n")
+	// cpu3 sits on another node; cpu0/cpu1 and cpu2/cpu3 are siblings.
+	write(pci+"/numa_node", "0\n")
+	write(pci+"/local_cpulist", "0-2\n")
+	for _, irq := range []string{"24", "25", "26"} {
+		write(pci+"/msi_irqs/"+irq, "msix\n")
+	}
+	for cpu, siblings := range []string{"0-1", "0-1", "2-3", "2-3"} {
+		write(fmt.Sprintf("%s/cpu%d/topology/thread_siblings_list", sysfs.CPUBase, cpu), siblings+"\n")
+	}
+	write(sysfs.ProcIRQBase+"/25/smp_affinity_list", "0-1\n")
+	write(sysfs.ProcIRQBase+"/25/effective_affinity_list", "1\n")
+	write(sysfs.ProcIRQBase+"/26/smp_affinity_list", "3\n")
+	write(sysfs.ProcIRQBase+"/26/effective_affinity_list", "3\n")
+	write(sysfs.NetBase+"/eth0/queues/rx-0/rps_cpus", "0\n")
+	write(sysfs.NetBase+"/eth0/queues/rx-1/rps_cpus", "0\n")
+	write(sysfs.NetBase+"/eth0/queues/tx-0/xps_cpus", "1\n")
+	write(sysfs.NetBase+"/eth0/queues/tx-1/xps_cpus", "4\n")
+	if err := os.Symlink("../../../devices/pci0000:00/0000:03:00.0", filepath.Join(dir, sysfs.NetBase, "eth0/device")); err != nil {
+		t.Fatal(err)
+	}
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	nics := DetectNICQueues()
+	if len(nics) != 1 {
+		t.Fatalf("nics = %+v, want eth0", nics)
+	}
+	n := nics[0]
+	if n.NUMANode != 0 || !slices.Equal(n.LocalCPUs, []int{0, 1, 2}) {
+		t.Errorf("node %d, local CPUs %v", n.NUMANode, n.LocalCPUs)
+	}
+	if len(n.IRQs) != 2 || n.IRQs[0].Name != "eth0-TxRx-0" || n.IRQs[0].Count != 300 {
+		t.Fatalf("IRQs = %+v, want the two TxRx vectors", n.IRQs)
+	}
+	if got := n.RemoteIRQs(); got != 1 {
+		t.Errorf("RemoteIRQs = %d, want 1", got)
+	}
+	// One IRQ per core before the siblings: cpu0, then cpu2.
+	if got := n.SpreadTargets(); !slices.Equal(got, []int{0, 2}) {
+		t.Errorf("SpreadTargets = %v, want [0 2]", got)
+	}
+
+	fields := map[string]output.Field{}
+	for _, f := range IRQSection(IRQInfo{NICs: nics}).Fields {
+		fields[f.Key] = f
+	}
+	if f := fields["  Affinity"]; f.Status != output.StatusWarn {
+		t.Errorf("Affinity = %+v, want a warning for the remote IRQ", f)
+	}
+	if f := fields["  RPS"]; f.Value != "off" {
+		t.Errorf("RPS = %q", f.Value)
+	}
+	if f := fields["  XPS"]; f.Value != "set per queue on 2 of 2 tx queues" {
+		t.Errorf("XPS = %q", f.Value)
+	}
+}
+
+func TestNICIRQsWithoutMSI(t *testing.T) {
+	dir := t.TempDir() // no msi_irqs: vectors are matched by name
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	irqs := map[int]interrupt{
+		40: {name: "eth1-rx-0"},
+		41: {name: "eth1-tx-0"},
+		42: {name: "eth10-rx-0"},
+		43: {name: "eth10-tx-0"},
+	}
+	var got []string
+	for _, q := range nicIRQs("eth1", sysfs.NetBase+"/eth1", irqs) {
+		got = append(got, q.Name)
+	}
+	if !slices.Equal(got, []string{"eth1-rx-0", "eth1-tx-0"}) {
+		t.Errorf("eth1 IRQs = %v, want only its own vectors", got)
+	}
+}
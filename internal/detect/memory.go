@@ -2,7 +2,6 @@ package detect
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -39,6 +38,7 @@ type MemoryInfo struct {
 	ZswapMaxPool int
 	ZramDevices []ZramInfo
 	HugePages   int
+	HugePageSizeKB int64
 }
 
 // DetectMemory gathers memory information.
@@ -66,6 +66,8 @@ func DetectMemory() MemoryInfo {
 				info.SwapFreeKB = val
 			case "HugePages_Total":
 				info.HugePages = int(val)
+			case "Hugepagesize":
+				info.HugePageSizeKB = val
 			}
 		}
 	}
@@ -110,7 +112,7 @@ func DetectMemory() MemoryInfo {
 	}
 
 	// Zram devices
-	entries, _ := os.ReadDir(sysfs.BlockBase)
+	entries, _ := sysfs.ReadDir(sysfs.BlockBase)
 	for _, e := range entries {
 		if strings.HasPrefix(e.Name(), "zram") {
 			zram := ZramInfo{Name: e.Name()}
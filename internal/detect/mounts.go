@@ -0,0 +1,331 @@
+package detect
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// MountInfo holds mount point info.
+type MountInfo struct {
+	Device     string
+	MountPoint string
+	FSType     string
+	Options    []string // as in effect, including kernel defaults
+	Disks      []string // physical disks under Device
+	Rotational bool     // any of Disks is rotational
+}
+
+// SwapInfo is one active swap area from /proc/swaps.
+type SwapInfo struct {
+	Device     string // partition or file
+	Type       string // partition, file
+	SizeKB     int64
+	Priority   int
+	Disks      []string
+	Rotational bool
+}
+
+// FstabEntry is one mount line of /etc/fstab.
+type FstabEntry struct {
+	Line       int    // 1-based
+	Raw        string // the line as written
+	Spec       string
+	MountPoint string
+	FSType     string
+	Options    []string
+}
+
+// Option returns the value of a mount option and whether it is set.
+// Flags such as noatime have an empty value.
+func (m MountInfo) Option(name string) (string, bool) {
+	return findOption(m.Options, name)
+}
+
+// OnFlash returns true if every disk under the mount is non-rotational.
+func (m MountInfo) OnFlash() bool {
+	return len(m.Disks) > 0 && !m.Rotational
+}
+
+// Tunable returns true for the filesystems the mount audit covers.
+func (m MountInfo) Tunable() bool {
+	return auditedFS[m.FSType]
+}
+
+// FstabFor returns the fstab entry for a mount point.
+func (info StorageInfo) FstabFor(mountPoint string) (FstabEntry, bool) {
+	for _, e := range info.Fstab {
+		if e.MountPoint == mountPoint {
+			return e, true
+		}
+	}
+	return FstabEntry{}, false
+}
+
+func findOption(opts []string, name string) (string, bool) {
+	for _, o := range opts {
+		k, v, _ := strings.Cut(o, "=")
+		if k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// detectMounts parses /proc/mounts, keeping filesystems on block
+// devices.
+func detectMounts(info StorageInfo) []MountInfo {
+	lines, err := sysfs.ReadLines(sysfs.ProcMounts)
+	if err != nil {
+		return nil
+	}
+	var mounts []MountInfo
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "/") {
+			continue
+		}
+		m := MountInfo{
+			Device:     unescapeMount(fields[0]),
+			MountPoint: unescapeMount(fields[1]),
+			FSType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		}
+		m.Disks = info.blockDisks(m.Device)
+		m.Rotational = info.anyRotational(m.Disks)
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// detectSwaps parses /proc/swaps. Swap files are placed on the disks of
+// the filesystem that holds them, so detectMounts must run first.
+func detectSwaps(info StorageInfo) []SwapInfo {
+	lines, err := sysfs.ReadLines(sysfs.ProcSwaps)
+	if err != nil {
+		return nil
+	}
+	var swaps []SwapInfo
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "Filename" {
+			continue
+		}
+		s := SwapInfo{Device: unescapeMount(fields[0]), Type: fields[1]}
+		s.SizeKB, _ = strconv.ParseInt(fields[2], 10, 64)
+		s.Priority, _ = strconv.Atoi(fields[4])
+		if s.Type == "file" {
+			if m, ok := info.mountOf(s.Device); ok {
+				s.Disks = m.Disks
+			}
+		} else {
+			s.Disks = info.blockDisks(s.Device)
+		}
+		s.Rotational = info.anyRotational(s.Disks)
+		swaps = append(swaps, s)
+	}
+	return swaps
+}
+
+// readFstab parses /etc/fstab, skipping comments and blank lines.
+func readFstab() []FstabEntry {
+	data, err := sysfs.ReadFile(sysfs.Fstab)
+	if err != nil {
+		return nil
+	}
+	var entries []FstabEntry
+	for i, raw := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(raw)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		entries = append(entries, FstabEntry{
+			Line:       i + 1,
+			Raw:        raw,
+			Spec:       fields[0],
+			MountPoint: unescapeMount(fields[1]),
+			FSType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		})
+	}
+	return entries
+}
+
+// blockDisks returns the physical disks under a /dev path: the disk
+// itself, the disk of a partition, or the disks under a dm/md device.
+func (info StorageInfo) blockDisks(dev string) []string {
+	name := strings.TrimPrefix(dev, "/dev/")
+	if dm, ok := strings.CutPrefix(name, "mapper/"); ok {
+		for _, s := range info.Stack {
+			if s.DMName == dm {
+				return s.Disks
+			}
+		}
+	}
+	for _, s := range info.Stack {
+		if s.Name == name {
+			return s.Disks
+		}
+	}
+	for _, d := range info.Disks {
+		if d.Name == name || slices.Contains(d.Partitions, name) {
+			return []string{d.Name}
+		}
+	}
+	// /dev/mapper/* and /dev/md/* are usually symlinks to dm-N and mdN.
+	if target, err := sysfs.Readlink(dev); err == nil && filepath.Base(target) != name {
+		return info.blockDisks("/dev/" + filepath.Base(target))
+	}
+	return nil
+}
+
+func (info StorageInfo) anyRotational(disks []string) bool {
+	for _, d := range info.Disks {
+		if d.Rotational && slices.Contains(disks, d.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mountOf returns the mount holding a file: the longest mount point
+// that is a prefix of its path.
+func (info StorageInfo) mountOf(path string) (MountInfo, bool) {
+	var best MountInfo
+	found := false
+	for _, m := range info.Mounts {
+		prefix := strings.TrimSuffix(m.MountPoint, "/") + "/"
+		if (m.MountPoint == "/" || strings.HasPrefix(path, prefix)) && len(m.MountPoint) >= len(best.MountPoint) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// unescapeMount decodes the octal escapes (\040 for space) the kernel
+// and fstab use in paths.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// auditedFS are the filesystems the mount audit knows options for.
+var auditedFS = map[string]bool{"ext2": true, "ext3": true, "ext4": true, "xfs": true, "btrfs": true, "f2fs": true}
+
+// mountFields audits one mount: atime updates, TRIM on flash and the
+// journal and compression options of ext4, xfs and btrfs.
+func mountFields(info StorageInfo, m MountInfo) []output.Field {
+	value := fmt.Sprintf("%s (%s)", m.Device, m.FSType)
+	switch {
+	case m.OnFlash():
+		value = fmt.Sprintf("%s (%s on flash)", m.Device, m.FSType)
+	case m.Rotational:
+		value = fmt.Sprintf("%s (%s on hdd)", m.Device, m.FSType)
+	}
+	fields := []output.Field{{Key: m.MountPoint, Value: value, Status: output.StatusInfo}}
+	if !m.Tunable() {
+		return fields
+	}
+
+	// The kernel lists relatime when no atime option is given.
+	atime, status := "relatime", output.StatusGood
+	switch {
+	case hasOption(m.Options, "noatime"):
+		atime = "noatime"
+	case hasOption(m.Options, "strictatime"), !hasOption(m.Options, "relatime"):
+		atime, status = "strictatime (a write on every read)", output.StatusWarn
+	}
+	fields = append(fields, output.Field{Key: "  Atime", Value: atime, Status: status})
+
+	if m.OnFlash() {
+		fields = append(fields, trimField(info, m))
+	}
+
+	switch m.FSType {
+	case "ext4":
+		commit := "5s (default)"
+		if v, ok := m.Option("commit"); ok {
+			commit = v + "s"
+		}
+		fields = append(fields, output.Field{Key: "  Journal Commit", Value: commit, Status: output.StatusInfo})
+	case "xfs":
+		logbsize := "32k (default)"
+		if v, ok := m.Option("logbsize"); ok {
+			logbsize = v
+		}
+		fields = append(fields, output.Field{Key: "  Log Buffer Size", Value: logbsize, Status: output.StatusInfo})
+	case "btrfs":
+		compress := "off"
+		if v, ok := m.Option("compress"); ok {
+			compress = v
+		} else if v, ok := m.Option("compress-force"); ok {
+			compress = v + " (forced)"
+		}
+		fields = append(fields, output.Field{Key: "  Compression", Value: compress, Status: output.StatusInfo})
+
+		_, ssd := m.Option("ssd")
+		switch {
+		case ssd && m.Rotational:
+			fields = append(fields, output.Field{Key: "  SSD Mode", Value: "on, but the disk is rotational", Status: output.StatusWarn})
+		case !ssd && m.OnFlash():
+			fields = append(fields, output.Field{Key: "  SSD Mode", Value: "off, but the disk is flash", Status: output.StatusWarn})
+		}
+	}
+	return fields
+}
+
+// trimField reports how freed blocks reach a flash disk: online discard
+// on every delete, or the weekly fstrim.timer.
+func trimField(info StorageInfo, m MountInfo) output.Field {
+	f := output.Field{Key: "  TRIM"}
+	discard, online := m.Option("discard")
+	switch {
+	case online && m.FSType == "btrfs" && discard == "async":
+		f.Value, f.Status = "discard=async", output.StatusGood
+	case online:
+		f.Value, f.Status = "discard (synchronous, on every delete)", output.StatusWarn
+	case info.Fstrim.Enabled || info.Fstrim.Active:
+		f.Value, f.Status = "fstrim.timer", output.StatusGood
+	default:
+		f.Value, f.Status = "none (no discard option, fstrim.timer disabled)", output.StatusWarn
+	}
+	return f
+}
+
+// swapField flags swap on rotational disks, where page-ins stall for
+// seek times.
+func swapField(s SwapInfo) output.Field {
+	f := output.Field{
+		Key:    "swap " + s.Device,
+		Value:  fmt.Sprintf("%s, %d MB, priority %d", s.Type, s.SizeKB/1024, s.Priority),
+		Status: output.StatusInfo,
+	}
+	if s.Rotational {
+		f.Value += ", on hdd"
+		f.Status = output.StatusWarn
+	}
+	return f
+}
+
+func hasOption(opts []string, name string) bool {
+	_, ok := findOption(opts, name)
+	return ok
+}
@@ -2,13 +2,12 @@ package detect
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
 
@@ -90,7 +89,7 @@ func DetectNetwork() NetworkInfo {
 	}
 
 	// Network interfaces
-	entries, err := os.ReadDir(sysfs.NetBase)
+	entries, err := sysfs.ReadDir(sysfs.NetBase)
 	if err != nil {
 		return info
 	}
@@ -129,7 +128,7 @@ func DetectNetwork() NetworkInfo {
 			sysfs.Exists(filepath.Join(base, "phy80211"))
 
 		// Virtual detection
-		link, err := os.Readlink(filepath.Join(base, "device"))
+		link, err := sysfs.Readlink(filepath.Join(base, "device"))
 		if err != nil {
 			iface.IsVirtual = true
 		} else {
@@ -254,7 +253,7 @@ func FormatBytes(b int) string {
 }
 
 func detectWifi(ifname string) *WifiInfo {
-	out, err := exec.Command("iw", "dev", ifname, "link").Output()
+	out, err := platform.Output("iw", "dev", ifname, "link")
 	if err != nil {
 		return nil
 	}
@@ -305,7 +304,7 @@ func detectWifi(ifname string) *WifiInfo {
 }
 
 func detectOffloads(ifname string) *NICOffloads {
-	out, err := exec.Command("ethtool", "-k", ifname).Output()
+	out, err := platform.Output("ethtool", "-k", ifname)
 	if err != nil {
 		return nil
 	}
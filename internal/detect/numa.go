@@ -0,0 +1,185 @@
+package detect
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// NUMAInfo holds the NUMA topology and the kernel's NUMA memory policy.
+type NUMAInfo struct {
+	Nodes       []NUMANode
+	Balancing   int // kernel.numa_balancing, -1 if not built in
+	ZoneReclaim int // vm.zone_reclaim_mode, -1 if not available
+}
+
+// NUMANode is one memory node.
+type NUMANode struct {
+	ID        int
+	Dir       string
+	CPUs      []int
+	TotalKB   int64
+	FreeKB    int64
+	Distances []int // to each node in Nodes order; 10 is local
+
+	// numastat counters since boot, in pages
+	Hit     int64 // allocated here as intended
+	Miss    int64 // allocated here, intended for another node
+	Foreign int64 // intended for here, allocated on another node
+}
+
+// ForeignPct returns the share of allocations meant for the node that
+// landed on another one.
+func (n NUMANode) ForeignPct() float64 {
+	if n.Hit+n.Foreign == 0 {
+		return 0
+	}
+	return float64(n.Foreign) * 100 / float64(n.Hit+n.Foreign)
+}
+
+// MultiNode returns true if the machine has more than one memory node,
+// the only case where NUMA settings do anything.
+func (info NUMAInfo) MultiNode() bool {
+	return len(info.Nodes) > 1
+}
+
+// DetectNUMA reads every node under /sys/devices/system/node.
+func DetectNUMA() NUMAInfo {
+	info := NUMAInfo{Balancing: -1, ZoneReclaim: -1}
+	if v, err := sysfs.ReadInt(sysfs.NUMABalancing); err == nil {
+		info.Balancing = v
+	}
+	if v, err := sysfs.ReadInt(sysfs.VMZoneReclaim); err == nil {
+		info.ZoneReclaim = v
+	}
+
+	entries, _ := sysfs.ReadDir(sysfs.NodeBase)
+	for _, e := range entries {
+		id, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "node"))
+		if err != nil || !strings.HasPrefix(e.Name(), "node") {
+			continue
+		}
+		n := NUMANode{ID: id, Dir: sysfs.NodeBase + "/" + e.Name()}
+		n.CPUs, _ = sysfs.ReadCPUList(n.Dir + "/cpulist")
+		if fields, err := sysfs.ReadFields(n.Dir + "/distance"); err == nil {
+			for _, f := range fields {
+				if d, err := strconv.Atoi(f); err == nil {
+					n.Distances = append(n.Distances, d)
+				}
+			}
+		}
+		// "Node 0 MemTotal:       32768000 kB"
+		if lines, err := sysfs.ReadLines(n.Dir + "/meminfo"); err == nil {
+			for _, line := range lines {
+				f := strings.Fields(line)
+				if len(f) < 4 {
+					continue
+				}
+				val, _ := strconv.ParseInt(f[3], 10, 64)
+				switch f[2] {
+				case "MemTotal:":
+					n.TotalKB = val
+				case "MemFree:":
+					n.FreeKB = val
+				}
+			}
+		}
+		if lines, err := sysfs.ReadLines(n.Dir + "/numastat"); err == nil {
+			for _, line := range lines {
+				key, val, _ := strings.Cut(line, " ")
+				count, _ := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+				switch key {
+				case "numa_hit":
+					n.Hit = count
+				case "numa_miss":
+					n.Miss = count
+				case "numa_foreign":
+					n.Foreign = count
+				}
+			}
+		}
+		info.Nodes = append(info.Nodes, n)
+	}
+	sort.Slice(info.Nodes, func(i, j int) bool { return info.Nodes[i].ID < info.Nodes[j].ID })
+	return info
+}
+
+// NUMASection formats NUMA info as an output section. A single node gets
+// one line: there is nothing to place or balance.
+func NUMASection(info NUMAInfo) output.Section {
+	sec := output.Section{Title: "NUMA"}
+	if !info.MultiNode() {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Nodes", Value: "1 (uniform memory access)", Status: output.StatusInfo})
+		return sec
+	}
+
+	sec.Fields = append(sec.Fields, output.Field{Key: "Nodes", Value: strconv.Itoa(len(info.Nodes)), Status: output.StatusInfo})
+	for _, n := range info.Nodes {
+		sec.Fields = append(sec.Fields, output.Field{
+			Key: fmt.Sprintf("node%d", n.ID),
+			Value: fmt.Sprintf("CPUs %s, %.1f GB, %.1f GB free", sysfs.FormatCPUList(n.CPUs),
+				float64(n.TotalKB)/1048576.0, float64(n.FreeKB)/1048576.0),
+			Status: output.StatusInfo,
+		})
+		if len(n.Distances) > 0 {
+			d := make([]string, len(n.Distances))
+			for i, v := range n.Distances {
+				d[i] = strconv.Itoa(v)
+			}
+			sec.Fields = append(sec.Fields, output.Field{Key: "  Distances", Value: strings.Join(d, " "), Status: output.StatusInfo})
+		}
+		// A few percent of misses is normal once a node fills up.
+		status := output.StatusGood
+		if n.ForeignPct() > 5 {
+			status = output.StatusWarn
+		}
+		sec.Fields = append(sec.Fields, output.Field{
+			Key:    "  Miss/Foreign",
+			Value:  fmt.Sprintf("%d / %d pages, %.1f%% of its allocations went to another node", n.Miss, n.Foreign, n.ForeignPct()),
+			Status: status,
+		})
+	}
+
+	if info.Balancing >= 0 {
+		sec.Fields = append(sec.Fields, output.Field{Key: "NUMA Balancing", Value: balancingStr(info.Balancing), Status: output.StatusInfo})
+	}
+	if info.ZoneReclaim >= 0 {
+		sec.Fields = append(sec.Fields, output.Field{Key: "Zone Reclaim", Value: ZoneReclaimStr(info.ZoneReclaim), Status: output.StatusInfo})
+	}
+	return sec
+}
+
+func balancingStr(v int) string {
+	switch v {
+	case 0:
+		return "off"
+	case 1:
+		return "on (pages and tasks migrate toward their node)"
+	case 2:
+		return "memory tiering only"
+	default:
+		return fmt.Sprintf("%d (on, with memory tiering)", v)
+	}
+}
+
+// ZoneReclaimStr describes a vm.zone_reclaim_mode value.
+func ZoneReclaimStr(v int) string {
+	if v == 0 {
+		return "0 (off: allocate on another node before reclaiming)"
+	}
+	var what []string
+	if v&2 != 0 {
+		what = append(what, "writes dirty pages")
+	}
+	if v&4 != 0 {
+		what = append(what, "swaps")
+	}
+	if len(what) == 0 {
+		return fmt.Sprintf("%d (reclaims local page cache first)", v)
+	}
+	return fmt.Sprintf("%d (reclaims local page cache first, %s)", v, strings.Join(what, " and "))
+}
@@ -0,0 +1,80 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestDetectNUMA(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for node, files := range map[string]map[string]string{
+		"node0": {
+			"cpulist":  "0-3\n",
+			"distance": "10 21\n",
+			"meminfo":  "Node 0 MemTotal:       16777216 kB\nNode 0 MemFree:         4194304 kB\n",
+			"numastat": "numa_hit 900\nnuma_miss 0\nnuma_foreign 100\nlocal_node 900\n",
+		},
+		"node1": {
+			"cpulist":  "4-7\n",
+			"distance": "21 10\n",
+			"meminfo":  "Node 1 MemTotal:       16777216 kB\nNode 1 MemFree:        12582912 kB\n",
+			"numastat": "numa_hit 1000\nnuma_miss 100\nnuma_foreign 0\n",
+		},
+	} {
+		for name, content := range files {
+			write(sysfs.NodeBase+"/"+node+"/"+name, content)
+		}
+	}
+	write(sysfs.NodeBase+"/possible", "0-1\n")
+	write(sysfs.NUMABalancing, "1\n")
+	write(sysfs.VMZoneReclaim, "0\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	info := DetectNUMA()
+	if !info.MultiNode() || info.Nodes[0].ID != 0 || info.Nodes[1].ID != 1 {
+		t.Fatalf("nodes = %+v", info.Nodes)
+	}
+	n := info.Nodes[0]
+	if !slices.Equal(n.CPUs, []int{0, 1, 2, 3}) || !slices.Equal(n.Distances, []int{10, 21}) ||
+		n.TotalKB != 16777216 || n.FreeKB != 4194304 || n.Foreign != 100 {
+		t.Errorf("node0 = %+v", n)
+	}
+	if got := n.ForeignPct(); got != 10 {
+		t.Errorf("ForeignPct = %v, want 10", got)
+	}
+	if info.Balancing != 1 || info.ZoneReclaim != 0 {
+		t.Errorf("balancing %d, zone reclaim %d", info.Balancing, info.ZoneReclaim)
+	}
+
+	var warned []string
+	for _, f := range NUMASection(info).Fields {
+		if f.Status == output.StatusWarn {
+			warned = append(warned, f.Key+": "+f.Value)
+		}
+	}
+	if len(warned) != 1 {
+		t.Errorf("warnings = %q, want node0's foreign allocations only", warned)
+	}
+
+	// One node: nothing to place.
+	sysfs.SetRoot(t.TempDir())
+	if sec := NUMASection(DetectNUMA()); len(sec.Fields) != 1 {
+		t.Errorf("single node section = %+v", sec.Fields)
+	}
+}
@@ -0,0 +1,187 @@
+package detect
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// nvmeIoctlAdminCmd is NVME_IOCTL_ADMIN_CMD, _IOWR('N', 0x41, struct
+// nvme_admin_cmd) from linux/nvme_ioctl.h.
+const nvmeIoctlAdminCmd = 0xC0484E41
+
+const (
+	nvmeAdminGetLogPage = 0x02
+	nvmeLogSMART        = 0x02
+	nvmeSMARTLogSize    = 512
+	nvmeNSIDAll         = 0xFFFFFFFF
+)
+
+// nvmeAdminCmd mirrors struct nvme_admin_cmd (72 bytes).
+type nvmeAdminCmd struct {
+	Opcode      uint8
+	Flags       uint8
+	Rsvd1       uint16
+	NSID        uint32
+	CDW2        uint32
+	CDW3        uint32
+	Metadata    uint64
+	Addr        uint64
+	MetadataLen uint32
+	DataLen     uint32
+	CDW10       uint32
+	CDW11       uint32
+	CDW12       uint32
+	CDW13       uint32
+	CDW14       uint32
+	CDW15       uint32
+	TimeoutMs   uint32
+	Result      uint32
+}
+
+// nvmeController matches the controller part of a namespace name:
+// nvme0 in nvme0n1 or nvme0c1n1.
+var nvmeController = regexp.MustCompile(`^nvme\d+`)
+
+// detectNVMeSMART reads the SMART/Health log of the disk's controller
+// with an admin Get Log Page ioctl, falling back to nvme-cli when the
+// ioctl fails (no root, old kernel) or when reading a snapshot, which
+// holds nvme-cli output only. While a snapshot is captured nvme-cli runs
+// as well so that its output is recorded.
+func detectNVMeSMART(diskName string) *SMARTInfo {
+	if !sysfs.IsLive() {
+		return nvmeCLISMART(diskName)
+	}
+	log, err := readNVMeSMARTLog("/dev/" + nvmeController.FindString(diskName))
+	if err != nil {
+		return nvmeCLISMART(diskName)
+	}
+	if sysfs.Recording() {
+		nvmeCLISMART(diskName)
+	}
+	return parseNVMeSMARTLog(log)
+}
+
+// readNVMeSMARTLog issues Get Log Page for log 0x02, controller-wide.
+func readNVMeSMARTLog(dev string) ([]byte, error) {
+	f, err := os.Open(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, nvmeSMARTLogSize)
+	cmd := nvmeAdminCmd{
+		Opcode:  nvmeAdminGetLogPage,
+		NSID:    nvmeNSIDAll,
+		Addr:    uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		DataLen: nvmeSMARTLogSize,
+		// Number of dwords minus one in the upper half, log ID below.
+		CDW10: (nvmeSMARTLogSize/4-1)<<16 | nvmeLogSMART,
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(buf)
+	if errno != 0 {
+		return nil, errno
+	}
+	return buf, nil
+}
+
+// parseNVMeSMARTLog decodes the SMART/Health Information log page
+// (NVMe base specification, Get Log Page, log identifier 02h).
+func parseNVMeSMARTLog(b []byte) *SMARTInfo {
+	if len(b) < nvmeSMARTLogSize {
+		return nil
+	}
+	s := &SMARTInfo{
+		CriticalWarning: int(b[0]),
+		AvailableSpare:  int(b[3]),
+		SpareThreshold:  int(b[4]),
+		PercentUsed:     int(b[5]),
+		Wear:            true,
+		DataReadTB:      nvmeDataUnitsTB(b[32:48]),
+		DataWrittenTB:   nvmeDataUnitsTB(b[48:64]),
+		PowerOnHours:    le128Int(b[128:144]),
+		UnsafeShutdowns: le128Int(b[144:160]),
+		MediaErrors:     le128Int(b[160:176]),
+		ErrorLogEntries: le128Int(b[176:192]),
+	}
+	// Composite temperature is in kelvins, 0 if not reported.
+	if k := int(binary.LittleEndian.Uint16(b[1:3])); k > 0 {
+		s.Temperature = k - 273
+	}
+	// Bits 0-3: spare below threshold, temperature, reliability, read-only.
+	s.Failed = s.CriticalWarning&0x0F != 0
+	return s
+}
+
+// nvmeDataUnitsTB converts a 128-bit little-endian count of data units,
+// each 1000 512-byte blocks, to terabytes.
+func nvmeDataUnitsTB(b []byte) float64 {
+	f, _ := new(big.Float).SetInt(le128(b)).Float64()
+	return f * 512000 / 1e12
+}
+
+// le128Int reads a 128-bit little-endian counter, saturating at MaxInt.
+func le128Int(b []byte) int {
+	n := le128(b)
+	if !n.IsInt64() {
+		return math.MaxInt
+	}
+	return int(n.Int64())
+}
+
+func le128(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// nvmeCLISMART parses 'nvme smart-log', whose layout changes between
+// nvme-cli versions. It is the fallback when the ioctl is unavailable.
+func nvmeCLISMART(diskName string) *SMARTInfo {
+	// Try nvme smart-log (needs root, but best data)
+	out, err := platform.Output("nvme", "smart-log", "/dev/"+diskName)
+	if err != nil {
+		return nil
+	}
+
+	s := &SMARTInfo{}
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(strings.ToLower(parts[0]))
+		val := strings.TrimSpace(parts[1])
+		// Strip trailing % or commas
+		val = strings.ReplaceAll(val, ",", "")
+		val = strings.TrimSuffix(val, "%")
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "percentage used":
+			s.PercentUsed, _ = strconv.Atoi(val)
+			s.Wear = true
+		case "power on hours":
+			s.PowerOnHours, _ = strconv.Atoi(val)
+		case "unsafe shutdowns":
+			s.UnsafeShutdowns, _ = strconv.Atoi(val)
+		case "media and data integrity errors":
+			s.MediaErrors, _ = strconv.Atoi(val)
+		}
+	}
+	return s
+}
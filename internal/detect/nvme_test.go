@@ -0,0 +1,45 @@
+package detect
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestNVMeAdminCmdLayout(t *testing.T) {
+	// The ioctl number encodes the struct size.
+	if size, encoded := unsafe.Sizeof(nvmeAdminCmd{}), uintptr(nvmeIoctlAdminCmd>>16&0x3FFF); size != encoded {
+		t.Errorf("nvme_admin_cmd is %d bytes, ioctl encodes %d", size, encoded)
+	}
+}
+
+func TestParseNVMeSMARTLog(t *testing.T) {
+	b := make([]byte, nvmeSMARTLogSize)
+	b[0] = 0x01                                   // spare below threshold
+	binary.LittleEndian.PutUint16(b[1:3], 273+47) // 47 °C
+	b[3], b[4], b[5] = 8, 10, 42
+	binary.LittleEndian.PutUint64(b[32:], 20_000_000) // 10.24 TB read
+	binary.LittleEndian.PutUint64(b[48:], 40_000_000) // 20.48 TB written
+	binary.LittleEndian.PutUint64(b[128:], 12345)
+	binary.LittleEndian.PutUint64(b[144:], 17)
+	binary.LittleEndian.PutUint64(b[168:], 1) // high half: saturates
+	binary.LittleEndian.PutUint64(b[176:], 3)
+
+	s := parseNVMeSMARTLog(b)
+	if s.Temperature != 47 || s.AvailableSpare != 8 || s.SpareThreshold != 10 || s.PercentUsed != 42 {
+		t.Errorf("got %+v", s)
+	}
+	if math.Abs(s.DataReadTB-10.24) > 0.001 || math.Abs(s.DataWrittenTB-20.48) > 0.001 {
+		t.Errorf("data read/written = %.3f/%.3f TB, want 10.240/20.480", s.DataReadTB, s.DataWrittenTB)
+	}
+	if s.PowerOnHours != 12345 || s.UnsafeShutdowns != 17 || s.ErrorLogEntries != 3 {
+		t.Errorf("counters = %d %d %d", s.PowerOnHours, s.UnsafeShutdowns, s.ErrorLogEntries)
+	}
+	if s.MediaErrors != math.MaxInt {
+		t.Errorf("media errors = %d, want saturated", s.MediaErrors)
+	}
+	if !s.Bad() || nvmeWarnings(s.CriticalWarning) != "spare below threshold" {
+		t.Errorf("critical warning %#x not reported", s.CriticalWarning)
+	}
+}
@@ -2,12 +2,11 @@ package detect
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
 
@@ -50,7 +49,7 @@ type BatteryInfo struct {
 func DetectPower() PowerInfo {
 	info := PowerInfo{}
 
-	entries, err := os.ReadDir(sysfs.PowerSupplyBase)
+	entries, err := sysfs.ReadDir(sysfs.PowerSupplyBase)
 	if err != nil {
 		return info
 	}
@@ -122,7 +121,7 @@ func DetectPower() PowerInfo {
 	// Check for tuned - only read profile if actually running
 	info.Tuned = checkService("tuned")
 	if info.Tuned.Active {
-		if profileOut, err := exec.Command("tuned-adm", "active").Output(); err == nil {
+		if profileOut, err := platform.Output("tuned-adm", "active"); err == nil {
 			line := strings.TrimSpace(string(profileOut))
 			if idx := strings.LastIndex(line, ": "); idx != -1 {
 				info.TunedProfile = line[idx+2:]
@@ -133,7 +132,7 @@ func DetectPower() PowerInfo {
 	// Check for power-profiles-daemon
 	info.PPD = checkService("power-profiles-daemon")
 	if info.PPD.Active {
-		if profileOut, err := exec.Command("powerprofilesctl", "get").Output(); err == nil {
+		if profileOut, err := platform.Output("powerprofilesctl", "get"); err == nil {
 			info.PowerProfile = strings.TrimSpace(string(profileOut))
 		}
 	}
@@ -228,7 +227,7 @@ func checkService(name string) ServiceState {
 	s := ServiceState{}
 
 	// is-active: "active", "inactive", "failed", etc. Exit code != 0 if not active.
-	activeOut, _ := exec.Command("systemctl", "is-active", name).Output()
+	activeOut, _ := platform.Output("systemctl", "is-active", name)
 	activeStr := strings.TrimSpace(string(activeOut))
 
 	// If systemctl returns nothing or "unknown", service isn't installed
@@ -240,7 +239,7 @@ func checkService(name string) ServiceState {
 	s.Active = activeStr == "active"
 
 	// is-enabled: "enabled", "disabled", "masked", "static", etc.
-	enabledOut, _ := exec.Command("systemctl", "is-enabled", name).Output()
+	enabledOut, _ := platform.Output("systemctl", "is-enabled", name)
 	enabledStr := strings.TrimSpace(string(enabledOut))
 	s.Enabled = enabledStr == "enabled"
 
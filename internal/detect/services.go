@@ -2,11 +2,11 @@ package detect
 
 import (
 	"fmt"
-	"os/exec"
 	"sort"
 	"strings"
 
 	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/platform"
 )
 
 // ServiceInfo holds systemd service diagnostic data.
@@ -31,7 +31,7 @@ func DetectServices() ServiceInfo {
 	info := ServiceInfo{}
 
 	// Boot timing
-	out, err := exec.Command("systemd-analyze").Output()
+	out, err := platform.Output("systemd-analyze")
 	if err == nil {
 		line := strings.TrimSpace(string(out))
 		info.BootTime = line
@@ -53,7 +53,7 @@ func DetectServices() ServiceInfo {
 	}
 
 	// Slow units (systemd-analyze blame)
-	out, err = exec.Command("systemd-analyze", "blame").Output()
+	out, err = platform.Output("systemd-analyze", "blame")
 	if err == nil {
 		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 		for i, line := range lines {
@@ -81,7 +81,7 @@ func DetectServices() ServiceInfo {
 	}
 
 	// Failed units
-	out, err = exec.Command("systemctl", "--failed", "--no-pager", "--plain", "--no-legend").Output()
+	out, err = platform.Output("systemctl", "--failed", "--no-pager", "--plain", "--no-legend")
 	if err == nil {
 		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 		for _, line := range lines {
@@ -93,7 +93,7 @@ func DetectServices() ServiceInfo {
 	}
 
 	// Count running services
-	out, err = exec.Command("systemctl", "list-units", "--type=service", "--state=running", "--no-pager", "--plain", "--no-legend").Output()
+	out, err = platform.Output("systemctl", "list-units", "--type=service", "--state=running", "--no-pager", "--plain", "--no-legend")
 	if err == nil {
 		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 		if len(lines) > 0 && lines[0] != "" {
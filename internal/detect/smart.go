@@ -0,0 +1,213 @@
+package detect
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/platform"
+)
+
+// smartctlReport is the part of 'smartctl --json -a' output tuner reads.
+type smartctlReport struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int `json:"hours"`
+	} `json:"power_on_time"`
+	ATAAttributes struct {
+		Table []smartctlAttr `json:"table"`
+	} `json:"ata_smart_attributes"`
+	SCSIGrownDefects *int `json:"scsi_grown_defect_list"`
+	SCSIEndurance    *int `json:"scsi_percentage_used_endurance_indicator"`
+	SCSIErrorLog     struct {
+		Read struct {
+			Uncorrected int `json:"total_uncorrected_errors"`
+		} `json:"read"`
+		Write struct {
+			Uncorrected int `json:"total_uncorrected_errors"`
+		} `json:"write"`
+	} `json:"scsi_error_counter_log"`
+}
+
+type smartctlAttr struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Value      int    `json:"value"` // normalized, higher is better
+	WhenFailed string `json:"when_failed"`
+	Raw        struct {
+		Value int64 `json:"value"`
+	} `json:"raw"`
+}
+
+// ATA attribute IDs. Vendors report SSD wear under different IDs; the
+// normalized value of each counts down from 100 as the flash wears.
+const (
+	ataReallocated   = 5
+	ataPending       = 197
+	ataUncorrectable = 198
+)
+
+var ataWearAttrs = []int{
+	177, // Wear_Leveling_Count (Samsung)
+	202, // Percent_Lifetime_Remain (Crucial, Micron)
+	231, // SSD_Life_Left (Kingston, SandForce)
+	233, // Media_Wearout_Indicator (Intel)
+}
+
+// WithSMART adds the health data of each disk to info: the NVMe log
+// page, or smartctl for ATA and SCSI. DetectStorage leaves it out
+// because tuning calls that every daemon interval, and only diagnose
+// needs drive health.
+func WithSMART(info StorageInfo) StorageInfo {
+	for i := range info.Disks {
+		d := &info.Disks[i]
+		switch {
+		case d.Type == "nvme":
+			d.SMART = detectNVMeSMART(d.Name)
+		case d.Transport != "virtio" && d.Transport != "mmc":
+			d.SMART = detectSmartctl(d.Name)
+		}
+	}
+	return info
+}
+
+// detectSmartctl reads SATA and SAS health through smartctl's JSON
+// output. smartctl sets exit status bits for failing disks, so the
+// output is parsed regardless of the error. It returns nil when smartctl
+// is missing, could not open the disk, usually for lack of root, or
+// found it spun down: -n standby keeps it from waking idle disks.
+func detectSmartctl(diskName string) *SMARTInfo {
+	out, _ := platform.Output("smartctl", "--json", "-n", "standby", "-a", "/dev/"+diskName)
+	if len(out) == 0 {
+		return nil
+	}
+	var r smartctlReport
+	if err := json.Unmarshal(out, &r); err != nil || r.SmartStatus == nil {
+		return nil
+	}
+	return r.info()
+}
+
+func (r smartctlReport) info() *SMARTInfo {
+	s := &SMARTInfo{
+		Failed:       !r.SmartStatus.Passed,
+		Temperature:  r.Temperature.Current,
+		PowerOnHours: r.PowerOnTime.Hours,
+	}
+
+	for _, a := range r.ATAAttributes.Table {
+		switch a.ID {
+		case ataReallocated:
+			s.Reallocated = int(a.Raw.Value)
+		case ataPending:
+			s.Pending = int(a.Raw.Value)
+		case ataUncorrectable:
+			s.Uncorrectable = int(a.Raw.Value)
+		}
+		for _, id := range ataWearAttrs {
+			if a.ID == id && !s.Wear {
+				s.PercentUsed = max(0, 100-a.Value)
+				s.Wear = true
+			}
+		}
+		// "past" means the attribute recovered; only "now" is failing.
+		if a.WhenFailed == "now" {
+			s.FailingAttrs = append(s.FailingAttrs, a.Name)
+		}
+	}
+
+	if r.SCSIGrownDefects != nil {
+		s.Reallocated = *r.SCSIGrownDefects
+	}
+	if r.SCSIEndurance != nil {
+		s.PercentUsed = *r.SCSIEndurance
+		s.Wear = true
+	}
+	s.Uncorrectable += r.SCSIErrorLog.Read.Uncorrected + r.SCSIErrorLog.Write.Uncorrected
+	return s
+}
+
+// smartFields shows the health data a drive reported.
+func smartFields(s *SMARTInfo) []output.Field {
+	var fields []output.Field
+	switch {
+	case s.CriticalWarning != 0:
+		fields = append(fields, output.Field{Key: "  SMART", Value: "critical warning: " + nvmeWarnings(s.CriticalWarning), Status: output.StatusBad})
+	case s.Failed:
+		fields = append(fields, output.Field{Key: "  SMART", Value: "FAILED", Status: output.StatusBad})
+	case len(s.FailingAttrs) > 0:
+		fields = append(fields, output.Field{Key: "  SMART", Value: "failing: " + strings.Join(s.FailingAttrs, ", "), Status: output.StatusBad})
+	}
+
+	if s.Wear {
+		wearStatus := output.StatusGood
+		if s.PercentUsed > 90 {
+			wearStatus = output.StatusBad
+		} else if s.PercentUsed > 70 {
+			wearStatus = output.StatusWarn
+		}
+		fields = append(fields, output.Field{Key: "  Wear Level", Value: fmt.Sprintf("%d%% used", s.PercentUsed), Status: wearStatus})
+	}
+	if s.Temperature > 0 {
+		tempStatus := output.StatusGood
+		if s.Temperature >= 70 {
+			tempStatus = output.StatusBad
+		} else if s.Temperature >= 60 {
+			tempStatus = output.StatusWarn
+		}
+		fields = append(fields, output.Field{Key: "  Temperature", Value: fmt.Sprintf("%d°C", s.Temperature), Status: tempStatus})
+	}
+	if s.SpareThreshold > 0 {
+		spareStatus := output.StatusGood
+		if s.AvailableSpare < s.SpareThreshold {
+			spareStatus = output.StatusBad
+		} else if s.AvailableSpare < 2*s.SpareThreshold {
+			spareStatus = output.StatusWarn
+		}
+		fields = append(fields, output.Field{Key: "  Available Spare", Value: fmt.Sprintf("%d%% (threshold %d%%)", s.AvailableSpare, s.SpareThreshold), Status: spareStatus})
+	}
+	if s.DataReadTB > 0 || s.DataWrittenTB > 0 {
+		fields = append(fields, output.Field{Key: "  Data Read/Written", Value: fmt.Sprintf("%.1f / %.1f TB", s.DataReadTB, s.DataWrittenTB), Status: output.StatusInfo})
+	}
+	if s.PowerOnHours > 0 {
+		fields = append(fields, output.Field{Key: "  Power On", Value: fmt.Sprintf("%d hours", s.PowerOnHours), Status: output.StatusInfo})
+	}
+
+	for _, c := range []struct {
+		key    string
+		n      int
+		status output.Status
+	}{
+		{"  Reallocated Sectors", s.Reallocated, output.StatusWarn},
+		{"  Pending Sectors", s.Pending, output.StatusBad},
+		{"  Uncorrectable Errors", s.Uncorrectable, output.StatusBad},
+		{"  Unsafe Shutdowns", s.UnsafeShutdowns, output.StatusWarn},
+		{"  Media Errors", s.MediaErrors, output.StatusBad},
+		{"  Error Log Entries", s.ErrorLogEntries, output.StatusInfo},
+	} {
+		if c.n > 0 {
+			fields = append(fields, output.Field{Key: c.key, Value: fmt.Sprintf("%d", c.n), Status: c.status})
+		}
+	}
+	return fields
+}
+
+// nvmeWarnings names the set bits of the NVMe critical warning byte.
+func nvmeWarnings(bits int) string {
+	var names []string
+	for i, name := range []string{
+		"spare below threshold", "temperature", "reliability degraded",
+		"read-only", "volatile backup failed", "persistent memory read-only",
+	} {
+		if bits&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
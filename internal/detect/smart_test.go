@@ -0,0 +1,54 @@
+package detect
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/platform"
+)
+
+const smartctlSATA = `{
+  "smartctl": {"exit_status": 8},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 41},
+  "power_on_time": {"hours": 31337},
+  "ata_smart_attributes": {"table": [
+    {"id": 5, "name": "Reallocated_Sector_Ct", "value": 95, "thresh": 10, "when_failed": "", "raw": {"value": 24}},
+    {"id": 177, "name": "Wear_Leveling_Count", "value": 88, "thresh": 0, "when_failed": "", "raw": {"value": 412}},
+    {"id": 187, "name": "Reported_Uncorrect", "value": 1, "thresh": 5, "when_failed": "now", "raw": {"value": 99}},
+    {"id": 197, "name": "Current_Pending_Sector", "value": 100, "thresh": 0, "when_failed": "", "raw": {"value": 2}}
+  ]}
+}`
+
+func TestDetectSmartctl(t *testing.T) {
+	platform.SetRunner(func(name string, args ...string) ([]byte, error) {
+		if !strings.Contains(strings.Join(args, " "), "-n standby") {
+			t.Errorf("smartctl %v would wake a spun-down disk", args)
+		}
+		if name == "smartctl" && args[len(args)-1] == "/dev/sda" {
+			// smartctl exits non-zero when attributes are failing.
+			return []byte(smartctlSATA), fmt.Errorf("exit status 8")
+		}
+		return []byte(`{"smartctl": {"exit_status": 2, "messages": [{"string": "Permission denied"}]}}`), fmt.Errorf("exit status 2")
+	})
+	defer platform.SetRunner(nil)
+
+	if s := detectSmartctl("sdb"); s != nil {
+		t.Errorf("unreadable disk: got %+v, want nil", s)
+	}
+
+	s := detectSmartctl("sda")
+	if s == nil {
+		t.Fatal("got nil SMART info")
+	}
+	if s.Temperature != 41 || s.PowerOnHours != 31337 || s.Reallocated != 24 || s.Pending != 2 {
+		t.Errorf("got %+v", s)
+	}
+	if !s.Wear || s.PercentUsed != 12 {
+		t.Errorf("wear = %v %d%%, want 12%% used", s.Wear, s.PercentUsed)
+	}
+	if got := strings.Join(s.FailingAttrs, ","); got != "Reported_Uncorrect" || !s.Bad() {
+		t.Errorf("failing = %q, bad = %v", got, s.Bad())
+	}
+}
@@ -0,0 +1,170 @@
+package detect
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// StackDevice is a device-mapper or md RAID device built on other block
+// devices. Filesystems on a stack read through its top-level device, so
+// that is where read-ahead matters; I/O scheduling happens on the
+// physical disks at the bottom.
+type StackDevice struct {
+	Name        string   // dm-0, md0
+	Kind        string   // lvm, crypt, multipath, dm, or the md level (raid1, raid5, ...)
+	DMName      string   // device-mapper name, e.g. vg0-root; empty for md
+	Slaves      []string // direct members: partitions, disks or other stack devices
+	Holders     []string // stack devices built on this one
+	Disks       []string // physical disks at the bottom, sorted
+	ReadAhead   int
+	StripeCache int // md/stripe_cache_size for raid4/5/6, 0 otherwise
+}
+
+// TopLevel returns true if nothing else is stacked on the device.
+func (d StackDevice) TopLevel() bool {
+	return len(d.Holders) == 0
+}
+
+// Label names the device the way users know it.
+func (d StackDevice) Label() string {
+	if d.DMName != "" {
+		return d.DMName + " (" + d.Name + ")"
+	}
+	return d.Name
+}
+
+// DiskIDs returns the storage rule identities of the physical disks
+// under dev.
+func (info StorageInfo) DiskIDs(dev StackDevice) []profile.DiskID {
+	var ids []profile.DiskID
+	for _, name := range dev.Disks {
+		for _, d := range info.Disks {
+			if d.Name == name {
+				ids = append(ids, d.ID())
+			}
+		}
+	}
+	return ids
+}
+
+func isStacked(name string) bool {
+	return strings.HasPrefix(name, "dm-") || strings.HasPrefix(name, "md")
+}
+
+func detectStackDevice(name string) StackDevice {
+	base := filepath.Join(sysfs.BlockBase, name)
+	dev := StackDevice{
+		Name:    name,
+		Slaves:  dirNames(filepath.Join(base, "slaves")),
+		Holders: dirNames(filepath.Join(base, "holders")),
+	}
+
+	if strings.HasPrefix(name, "dm-") {
+		dev.DMName, _ = sysfs.ReadString(filepath.Join(base, "dm/name"))
+		uuid, _ := sysfs.ReadString(filepath.Join(base, "dm/uuid"))
+		switch {
+		case strings.HasPrefix(uuid, "LVM-"):
+			dev.Kind = "lvm"
+		case strings.HasPrefix(uuid, "CRYPT-"):
+			dev.Kind = "crypt"
+		case strings.HasPrefix(uuid, "mpath-"):
+			dev.Kind = "multipath"
+		default:
+			dev.Kind = "dm"
+		}
+	} else {
+		dev.Kind, _ = sysfs.ReadString(filepath.Join(base, "md/level"))
+		if dev.Kind == "" {
+			dev.Kind = "md"
+		}
+		if v, err := sysfs.ReadInt(filepath.Join(base, "md/stripe_cache_size")); err == nil {
+			dev.StripeCache = v
+		}
+	}
+
+	if v, err := sysfs.ReadInt(filepath.Join(base, "queue/read_ahead_kb")); err == nil {
+		dev.ReadAhead = v
+	}
+	return dev
+}
+
+// listPartitions returns the partitions of a disk: the subdirectories of
+// /sys/block/<disk> that have a "partition" file.
+func listPartitions(disk string) []string {
+	var parts []string
+	for _, name := range dirNames(filepath.Join(sysfs.BlockBase, disk)) {
+		if strings.HasPrefix(name, disk) &&
+			sysfs.Exists(filepath.Join(sysfs.BlockBase, disk, name, "partition")) {
+			parts = append(parts, name)
+		}
+	}
+	return parts
+}
+
+// listHolders returns the stack devices using a disk directly or through
+// one of its partitions.
+func listHolders(disk string, partitions []string) []string {
+	holders := dirNames(filepath.Join(sysfs.BlockBase, disk, "holders"))
+	for _, p := range partitions {
+		holders = append(holders, dirNames(filepath.Join(sysfs.BlockBase, disk, p, "holders"))...)
+	}
+	return holders
+}
+
+// resolveStack fills in the physical disks under each stack device by
+// walking slaves down through partitions and other stack devices.
+func resolveStack(info *StorageInfo) {
+	partOf := make(map[string]string) // partition -> disk
+	for _, d := range info.Disks {
+		for _, p := range d.Partitions {
+			partOf[p] = d.Name
+		}
+	}
+	byName := make(map[string]*StackDevice)
+	for i := range info.Stack {
+		byName[info.Stack[i].Name] = &info.Stack[i]
+	}
+
+	var walk func(name string, seen map[string]bool, disks map[string]bool)
+	walk = func(name string, seen map[string]bool, disks map[string]bool) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if dev, ok := byName[name]; ok {
+			for _, s := range dev.Slaves {
+				walk(s, seen, disks)
+			}
+			return
+		}
+		if disk, ok := partOf[name]; ok {
+			name = disk
+		}
+		disks[name] = true
+	}
+
+	for i := range info.Stack {
+		disks := make(map[string]bool)
+		walk(info.Stack[i].Name, make(map[string]bool), disks)
+		for d := range disks {
+			info.Stack[i].Disks = append(info.Stack[i].Disks, d)
+		}
+		sort.Strings(info.Stack[i].Disks)
+	}
+}
+
+func dirNames(path string) []string {
+	entries, err := sysfs.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
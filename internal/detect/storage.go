@@ -2,52 +2,71 @@ package detect
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/krisk248/tuner/internal/output"
+	"github.com/krisk248/tuner/internal/profile"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
 
 // SMARTInfo holds disk health data.
 type SMARTInfo struct {
-	PercentUsed    int // wear level 0-100
-	PowerOnHours   int
+	PercentUsed     int  // wear level 0-100
+	Wear            bool // PercentUsed is reported
+	PowerOnHours    int
 	UnsafeShutdowns int
-	MediaErrors    int
+	MediaErrors     int
+	Temperature     int // Celsius, 0 if unknown
+	AvailableSpare  int // NVMe spare capacity left, percent
+	SpareThreshold  int // NVMe warns below this AvailableSpare
+	CriticalWarning int // NVMe critical warning bits
+	DataReadTB      float64
+	DataWrittenTB   float64
+	ErrorLogEntries int
+	Reallocated     int // reallocated sectors, or grown defects on SCSI
+	Pending         int // unreadable sectors waiting for reallocation
+	Uncorrectable   int
+	Failed          bool     // the drive's overall self-assessment failed
+	FailingAttrs    []string // ATA attributes at or below their threshold
+}
+
+// Bad returns true if the drive reports failure or lost data.
+func (s *SMARTInfo) Bad() bool {
+	return s.Failed || s.CriticalWarning != 0 || len(s.FailingAttrs) > 0 || s.Pending > 0 || s.Uncorrectable > 0 || s.MediaErrors > 0
 }
 
 // DiskInfo holds per-device storage info.
 type DiskInfo struct {
-	Name       string
-	Type       string // nvme, ssd, hdd
-	Scheduler  string
-	AvailScheds []string
-	Rotational bool
-	SizeGB     float64
-	Model      string
-	NrRequests int
-	ReadAhead  int
-	SMART      *SMARTInfo
+	Name           string
+	Type           string // nvme, ssd, hdd
+	Scheduler      string
+	AvailScheds    []string
+	Rotational     bool
+	SizeGB         float64
+	Model          string
+	Serial         string
+	Transport      string // nvme, sata, sas, scsi, usb, virtio, mmc
+	NrRequests     int
+	ReadAhead      int
+	Queue          map[string]int // readable profile.QueueAttrNames attributes
+	MaxHWSectorsKB int
+	SMART          *SMARTInfo
+	Partitions     []string // e.g. sda1, sda2
+	Holders        []string // dm/md devices built on the disk or its partitions
 }
 
 // StorageInfo holds overall storage diagnostic data.
 type StorageInfo struct {
-	Disks      []DiskInfo
-	Mounts     []MountInfo
+	Disks       []DiskInfo
+	Stack       []StackDevice // dm and md devices, see stack.go
+	Mounts      []MountInfo   // see mounts.go
+	Swaps       []SwapInfo
+	Fstab       []FstabEntry
+	Fstrim      ServiceState      // fstrim.timer
 	FileSystems map[string]string // device -> fstype
 }
 
-// MountInfo holds mount point info.
-type MountInfo struct {
-	Device     string
-	MountPoint string
-	FSType     string
-}
-
 // DetectStorage gathers storage device information.
 func DetectStorage() StorageInfo {
 	info := StorageInfo{
@@ -55,16 +74,20 @@ func DetectStorage() StorageInfo {
 	}
 
 	// Read block devices
-	entries, err := os.ReadDir(sysfs.BlockBase)
+	entries, err := sysfs.ReadDir(sysfs.BlockBase)
 	if err != nil {
 		return info
 	}
 
 	for _, e := range entries {
 		name := e.Name()
-		// Skip loop, ram, dm, zram devices
+		// Skip loop, ram and zram devices
 		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") ||
-			strings.HasPrefix(name, "zram") || strings.HasPrefix(name, "dm-") {
+			strings.HasPrefix(name, "zram") {
+			continue
+		}
+		if isStacked(name) {
+			info.Stack = append(info.Stack, detectStackDevice(name))
 			continue
 		}
 
@@ -106,6 +129,9 @@ func DetectStorage() StorageInfo {
 			disk.Model = model
 		}
 
+		disk.Serial = readSerial(base)
+		disk.Transport = detectTransport(name)
+
 		// Nr requests
 		if v, err := sysfs.ReadInt(filepath.Join(base, "queue/nr_requests")); err == nil {
 			disk.NrRequests = v
@@ -116,53 +142,130 @@ func DetectStorage() StorageInfo {
 			disk.ReadAhead = v
 		}
 
-		// SMART data for NVMe
-		if disk.Type == "nvme" {
-			disk.SMART = detectNVMeSMART(name)
+		// Other queue attributes tuner can set
+		disk.Queue = make(map[string]int)
+		for _, attr := range profile.QueueAttrNames {
+			if v, err := sysfs.ReadInt(filepath.Join(base, "queue", attr)); err == nil {
+				disk.Queue[attr] = v
+			}
 		}
+		if v, err := sysfs.ReadInt(filepath.Join(base, "queue/max_hw_sectors_kb")); err == nil {
+			disk.MaxHWSectorsKB = v
+		}
+
+		disk.Partitions = listPartitions(name)
+		disk.Holders = listHolders(name, disk.Partitions)
 
 		info.Disks = append(info.Disks, disk)
 	}
 
-	// Parse /proc/mounts
-	if lines, err := sysfs.ReadLines("/proc/mounts"); err == nil {
-		for _, line := range lines {
-			fields := strings.Fields(line)
-			if len(fields) < 3 {
-				continue
-			}
-			// Only show real filesystems
-			if !strings.HasPrefix(fields[0], "/") {
-				continue
-			}
-			mount := MountInfo{
-				Device:     fields[0],
-				MountPoint: fields[1],
-				FSType:     fields[2],
-			}
-			info.Mounts = append(info.Mounts, mount)
-			info.FileSystems[fields[0]] = fields[2]
-		}
+	resolveStack(&info)
+
+	info.Mounts = detectMounts(info)
+	info.Swaps = detectSwaps(info)
+	info.Fstab = readFstab()
+	info.Fstrim = checkService("fstrim.timer")
+	for _, m := range info.Mounts {
+		info.FileSystems[m.Device] = m.FSType
 	}
 
 	return info
 }
 
+// ID returns what profile storage rules match the disk on.
+func (d DiskInfo) ID() profile.DiskID {
+	return profile.DiskID{
+		Type:      d.Type,
+		Model:     d.Model,
+		Serial:    d.Serial,
+		Transport: d.Transport,
+		SizeGB:    d.SizeGB,
+	}
+}
+
+// readSerial returns the serial number udev reports as ID_SERIAL_SHORT.
+// NVMe and virtio expose it directly; SCSI and SATA disks carry it in
+// the unit serial number VPD page (0x80): a 4-byte header, then the
+// serial, padded with spaces.
+func readSerial(base string) string {
+	for _, rel := range []string{"device/serial", "serial"} {
+		if s, err := sysfs.ReadString(filepath.Join(base, rel)); err == nil && s != "" {
+			return s
+		}
+	}
+	page, err := sysfs.ReadString(filepath.Join(base, "device/vpd_pg80"))
+	if err != nil || len(page) < 4 {
+		return ""
+	}
+	n := int(page[3])
+	if 4+n > len(page) {
+		n = len(page) - 4
+	}
+	return strings.TrimSpace(page[4 : 4+n])
+}
+
+// detectTransport classifies a disk by the bus in its sysfs device path,
+// e.g. /sys/block/sda -> ../devices/pci0000:00/0000:00:17.0/ata1/...
+func detectTransport(name string) string {
+	switch {
+	case strings.HasPrefix(name, "nvme"):
+		return "nvme"
+	case strings.HasPrefix(name, "vd"):
+		return "virtio"
+	case strings.HasPrefix(name, "mmcblk"):
+		return "mmc"
+	}
+	target, err := sysfs.Readlink(filepath.Join(sysfs.BlockBase, name))
+	if err != nil {
+		return ""
+	}
+	switch {
+	case strings.Contains(target, "/usb"):
+		return "usb"
+	case strings.Contains(target, "/ata"):
+		return "sata"
+	case strings.Contains(target, "/end_device-"):
+		return "sas"
+	case strings.Contains(target, "/virtio"):
+		return "virtio"
+	}
+	return "scsi"
+}
+
 // StorageSection formats storage info as an output section.
 func StorageSection(info StorageInfo) output.Section {
 	sec := output.Section{Title: "Storage"}
 
 	for _, disk := range info.Disks {
 		prefix := fmt.Sprintf("%s (%s)", disk.Name, disk.Type)
+		if disk.Transport != "" && disk.Transport != disk.Type {
+			prefix = fmt.Sprintf("%s (%s, %s)", disk.Name, disk.Type, disk.Transport)
+		}
 		if disk.Model != "" {
 			prefix = fmt.Sprintf("%s [%s]", prefix, disk.Model)
 		}
 
+		status := output.StatusInfo
+		if disk.SMART != nil && disk.SMART.Bad() {
+			status = output.StatusBad
+		}
 		sec.Fields = append(sec.Fields,
-			output.Field{Key: prefix, Value: fmt.Sprintf("%.0f GB", disk.SizeGB), Status: output.StatusInfo},
+			output.Field{Key: prefix, Value: fmt.Sprintf("%.0f GB", disk.SizeGB), Status: status},
 			output.Field{Key: "  Scheduler", Value: disk.Scheduler, Status: schedulerStatus(disk.Type, disk.Scheduler)},
 		)
 
+		if disk.Serial != "" {
+			sec.Fields = append(sec.Fields,
+				output.Field{Key: "  Serial", Value: disk.Serial, Status: output.StatusInfo},
+			)
+		}
+
+		if len(disk.Holders) > 0 {
+			sec.Fields = append(sec.Fields,
+				output.Field{Key: "  Used By", Value: strings.Join(disk.Holders, ", "), Status: output.StatusInfo},
+			)
+		}
+
 		if disk.ReadAhead > 0 {
 			sec.Fields = append(sec.Fields,
 				output.Field{Key: "  Read Ahead", Value: fmt.Sprintf("%d KB", disk.ReadAhead), Status: output.StatusInfo},
@@ -170,74 +273,53 @@ func StorageSection(info StorageInfo) output.Section {
 		}
 
 		if disk.SMART != nil {
-			s := disk.SMART
-			wearStatus := output.StatusGood
-			if s.PercentUsed > 90 {
-				wearStatus = output.StatusBad
-			} else if s.PercentUsed > 70 {
-				wearStatus = output.StatusWarn
-			}
-			sec.Fields = append(sec.Fields,
-				output.Field{Key: "  Wear Level", Value: fmt.Sprintf("%d%% used", s.PercentUsed), Status: wearStatus},
-				output.Field{Key: "  Power On", Value: fmt.Sprintf("%d hours", s.PowerOnHours), Status: output.StatusInfo},
-			)
-			if s.UnsafeShutdowns > 0 {
-				sec.Fields = append(sec.Fields,
-					output.Field{Key: "  Unsafe Shutdowns", Value: fmt.Sprintf("%d", s.UnsafeShutdowns), Status: output.StatusWarn},
-				)
-			}
-			if s.MediaErrors > 0 {
-				sec.Fields = append(sec.Fields,
-					output.Field{Key: "  Media Errors", Value: fmt.Sprintf("%d", s.MediaErrors), Status: output.StatusBad},
-				)
-			}
+			sec.Fields = append(sec.Fields, smartFields(disk.SMART)...)
 		}
 	}
 
-	// Show filesystems
+	// Stacked devices, top level first
+	for _, dev := range info.Stack {
+		if !dev.TopLevel() {
+			continue
+		}
+		sec.Fields = append(sec.Fields, stackFields(info, dev, "")...)
+	}
+
+	// Filesystems and swap
 	for _, m := range info.Mounts {
-		if m.MountPoint == "/" || m.MountPoint == "/home" || m.MountPoint == "/boot" {
-			sec.Fields = append(sec.Fields,
-				output.Field{Key: m.MountPoint, Value: fmt.Sprintf("%s (%s)", m.Device, m.FSType), Status: output.StatusInfo},
-			)
+		if len(m.Disks) > 0 || m.MountPoint == "/" {
+			sec.Fields = append(sec.Fields, mountFields(info, m)...)
 		}
 	}
+	for _, s := range info.Swaps {
+		sec.Fields = append(sec.Fields, swapField(s))
+	}
 
 	return sec
 }
 
-func detectNVMeSMART(diskName string) *SMARTInfo {
-	// Try nvme smart-log (needs root, but best data)
-	out, err := exec.Command("nvme", "smart-log", "/dev/"+diskName).Output()
-	if err != nil {
-		return nil
+// stackFields shows a stack device and, indented, the devices under it.
+func stackFields(info StorageInfo, dev StackDevice, indent string) []output.Field {
+	fields := []output.Field{
+		{Key: indent + dev.Label(), Value: fmt.Sprintf("%s on %s", dev.Kind, strings.Join(dev.Slaves, ", ")), Status: output.StatusInfo},
 	}
-
-	s := &SMARTInfo{}
-	for _, line := range strings.Split(string(out), "\n") {
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
+	if dev.TopLevel() {
+		fields = append(fields, output.Field{Key: indent + "  Disks", Value: strings.Join(dev.Disks, ", "), Status: output.StatusInfo})
+		if dev.ReadAhead > 0 {
+			fields = append(fields, output.Field{Key: indent + "  Read Ahead", Value: fmt.Sprintf("%d KB", dev.ReadAhead), Status: output.StatusInfo})
 		}
-		key := strings.TrimSpace(strings.ToLower(parts[0]))
-		val := strings.TrimSpace(parts[1])
-		// Strip trailing % or commas
-		val = strings.ReplaceAll(val, ",", "")
-		val = strings.TrimSuffix(val, "%")
-		val = strings.TrimSpace(val)
-
-		switch key {
-		case "percentage used":
-			s.PercentUsed, _ = strconv.Atoi(val)
-		case "power on hours":
-			s.PowerOnHours, _ = strconv.Atoi(val)
-		case "unsafe shutdowns":
-			s.UnsafeShutdowns, _ = strconv.Atoi(val)
-		case "media and data integrity errors":
-			s.MediaErrors, _ = strconv.Atoi(val)
+	}
+	if dev.StripeCache > 0 {
+		fields = append(fields, output.Field{Key: indent + "  Stripe Cache", Value: fmt.Sprintf("%d pages", dev.StripeCache), Status: output.StatusInfo})
+	}
+	for _, s := range dev.Slaves {
+		for _, sub := range info.Stack {
+			if sub.Name == s {
+				fields = append(fields, stackFields(info, sub, indent+"  ")...)
+			}
 		}
 	}
-	return s
+	return fields
 }
 
 func schedulerStatus(diskType, sched string) output.Status {
@@ -2,67 +2,329 @@ package persist
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
-const (
-	BackupDir  = "/etc/tuner"
+const BackupDir = "/etc/tuner"
+
+var (
+	// BackupsDir holds one JSON file per backup generation plus the
+	// pristine record.
+	BackupsDir = "/etc/tuner/backups"
+
+	// BackupFile is the single backup written by older versions. Reads
+	// show it as generation 1; the first command that changes the store
+	// imports it.
 	BackupFile = "/etc/tuner/backup.json"
 )
 
-// BackupData holds the original values before tuning.
+const pristineName = "pristine.json"
+
+// BackupData is one restore point: the values of every path an apply or
+// save was about to change, read just before it changed them.
 type BackupData struct {
+	ID        int               `json:"id"` // 0 for the pristine record
 	Timestamp string            `json:"timestamp"`
+	Command   string            `json:"command,omitempty"` // apply, save or import
 	Profile   string            `json:"profile"`
+	Workload  string            `json:"workload,omitempty"`
 	Values    map[string]string `json:"values"` // path -> original value
 }
 
-// SaveBackup writes the backup data to disk.
-func SaveBackup(profile string, values map[string]string) error {
-	if err := os.MkdirAll(BackupDir, 0755); err != nil {
-		return err
+// Time parses the backup timestamp.
+func (b *BackupData) Time() time.Time {
+	t, _ := time.Parse(time.RFC3339, b.Timestamp)
+	return t
+}
+
+// SaveBackup stores data as a new generation and returns it with its ID
+// and timestamp filled in. Paths seen for the first time are also added
+// to the pristine record, which is never overwritten or pruned.
+func SaveBackup(data BackupData) (*BackupData, error) {
+	if err := importLegacy(); err != nil {
+		return nil, err
+	}
+	gens, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(BackupsDir, 0755); err != nil {
+		return nil, err
+	}
+
+	data.ID = 1
+	if len(gens) > 0 {
+		data.ID = gens[len(gens)-1].ID + 1
+	}
+	if data.Timestamp == "" {
+		data.Timestamp = time.Now().Format(time.RFC3339)
+	}
+	if data.Values == nil {
+		data.Values = map[string]string{}
 	}
 
-	data := BackupData{
-		Timestamp: time.Now().Format(time.RFC3339),
-		Profile:   profile,
-		Values:    values,
+	if err := writeBackup(generationPath(data.ID), &data); err != nil {
+		return nil, err
 	}
+	if err := mergePristine(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
 
-	b, err := json.MarshalIndent(data, "", "  ")
+// ListBackups returns every generation, oldest first.
+func ListBackups() ([]*BackupData, error) {
+	legacy, err := legacyBackup()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var gens []*BackupData
+	if legacy != nil {
+		gens = append(gens, legacy)
 	}
 
-	return os.WriteFile(BackupFile, b, 0644)
+	entries, err := os.ReadDir(BackupsDir)
+	if os.IsNotExist(err) {
+		return gens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if _, err := strconv.Atoi(name); err != nil || name == e.Name() {
+			continue
+		}
+		b, err := readBackup(filepath.Join(BackupsDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, b)
+	}
+	sort.Slice(gens, func(i, j int) bool { return gens[i].ID < gens[j].ID })
+	return gens, nil
 }
 
-// LoadBackup reads the backup data from disk.
-func LoadBackup() (*BackupData, error) {
-	b, err := os.ReadFile(BackupFile)
+// LoadBackup reads one generation.
+func LoadBackup(id int) (*BackupData, error) {
+	legacy, err := legacyBackup()
 	if err != nil {
 		return nil, err
 	}
+	if legacy != nil && id == legacy.ID {
+		return legacy, nil
+	}
+	b, err := readBackup(generationPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no backup generation %d (see 'tuner backup list')", id)
+	}
+	return b, err
+}
 
-	var data BackupData
-	if err := json.Unmarshal(b, &data); err != nil {
+// LoadPristine reads the values the system had before tuner first
+// changed each path.
+func LoadPristine() (*BackupData, error) {
+	legacy, err := legacyBackup()
+	if err != nil {
 		return nil, err
 	}
-
-	return &data, nil
+	if legacy != nil {
+		return &BackupData{Timestamp: legacy.Timestamp, Profile: legacy.Profile, Values: legacy.Values}, nil
+	}
+	return readBackup(filepath.Join(BackupsDir, pristineName))
 }
 
-// BackupExists returns true if a backup file exists.
+// BackupExists returns true if any restore point exists.
 func BackupExists() bool {
-	_, err := os.Stat(BackupFile)
+	_, err := LoadPristine()
 	return err == nil
 }
 
-// RemoveBackup deletes the backup file.
-func RemoveBackup() error {
-	return os.Remove(BackupFile)
+// RestorePlan returns the path -> value writes that take the system back
+// to the state just before generation id. Each path takes its value from
+// the oldest generation at or after id that recorded it; paths only
+// touched before id are already in that state.
+func RestorePlan(id int) (map[string]string, error) {
+	if _, err := LoadBackup(id); err != nil {
+		return nil, err
+	}
+	gens, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	plan := make(map[string]string)
+	for _, g := range gens {
+		if g.ID < id {
+			continue
+		}
+		for path, v := range g.Values {
+			if _, ok := plan[path]; !ok {
+				plan[path] = v
+			}
+		}
+	}
+	return plan, nil
+}
+
+// RemoveBackupsFrom deletes generation id and every later one, after
+// they have been restored.
+func RemoveBackupsFrom(id int) error {
+	if err := importLegacy(); err != nil {
+		return err
+	}
+	gens, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, g := range gens {
+		if g.ID >= id {
+			if err := os.Remove(generationPath(g.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RemoveAllBackups deletes every generation and the pristine record,
+// once the system has been restored to its pristine state.
+func RemoveAllBackups() error {
+	// Import first so the legacy file is moved out of the way too.
+	if err := importLegacy(); err != nil {
+		return err
+	}
+	err := os.RemoveAll(BackupsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// PruneBackups deletes generations beyond the newest keep (0 = no count
+// limit) and generations older than maxAge (0 = no age limit). The
+// newest generation and the pristine record are always kept. It returns
+// the IDs removed.
+func PruneBackups(keep int, maxAge time.Duration) ([]int, error) {
+	if err := importLegacy(); err != nil {
+		return nil, err
+	}
+	gens, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []int
+	now := time.Now()
+	for i, g := range gens {
+		newerCount := len(gens) - 1 - i
+		if newerCount == 0 {
+			break
+		}
+		tooMany := keep > 0 && newerCount >= keep
+		tooOld := maxAge > 0 && now.Sub(g.Time()) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(generationPath(g.ID)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, g.ID)
+	}
+	return removed, nil
+}
+
+func generationPath(id int) string {
+	return filepath.Join(BackupsDir, fmt.Sprintf("%04d.json", id))
+}
+
+// mergePristine adds the values of paths not yet in the pristine record.
+func mergePristine(gen *BackupData) error {
+	path := filepath.Join(BackupsDir, pristineName)
+	pristine, err := readBackup(path)
+	if errors.Is(err, os.ErrNotExist) {
+		pristine = &BackupData{Timestamp: gen.Timestamp, Profile: gen.Profile, Values: map[string]string{}}
+	} else if err != nil {
+		return err
+	}
+
+	for p, v := range gen.Values {
+		if _, ok := pristine.Values[p]; !ok {
+			pristine.Values[p] = v
+		}
+	}
+	return writeBackup(path, pristine)
+}
+
+// legacyBackup returns the backup.json of older versions as generation
+// 1, or nil if there is none or the store has already imported it. It
+// only reads, so commands that just look at backups work without root.
+func legacyBackup() (*BackupData, error) {
+	b, err := readBackup(BackupFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import %s: %w", BackupFile, err)
+	}
+	if _, err := os.Stat(filepath.Join(BackupsDir, pristineName)); err == nil {
+		return nil, nil // already imported
+	}
+	b.ID = 1
+	b.Command = "import"
+	return b, nil
+}
+
+// importLegacy moves a backup.json from older versions into the store
+// as generation 1 and renames the original out of the way. Only
+// commands that change the store call it.
+func importLegacy() error {
+	b, err := legacyBackup()
+	if err != nil || b == nil {
+		return err
+	}
+
+	if err := os.MkdirAll(BackupsDir, 0755); err != nil {
+		return err
+	}
+	if err := writeBackup(generationPath(1), b); err != nil {
+		return err
+	}
+	if err := mergePristine(b); err != nil {
+		return err
+	}
+	return os.Rename(BackupFile, BackupFile+".imported")
+}
+
+func readBackup(path string) (*BackupData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data BackupData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if data.Values == nil {
+		data.Values = map[string]string{}
+	}
+	return &data, nil
+}
+
+func writeBackup(path string, data *BackupData) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
 }
 
 // SysctlPath returns the path for the tuner sysctl drop-in.
@@ -0,0 +1,139 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func useBackupDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	oldDir, oldFile := BackupsDir, BackupFile
+	BackupsDir = filepath.Join(dir, "backups")
+	BackupFile = filepath.Join(dir, "backup.json")
+	t.Cleanup(func() { BackupsDir, BackupFile = oldDir, oldFile })
+	return dir
+}
+
+func TestBackupGenerations(t *testing.T) {
+	useBackupDir(t)
+
+	save := func(values map[string]string) *BackupData {
+		t.Helper()
+		b, err := SaveBackup(BackupData{Command: "apply", Profile: "server", Values: values})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	// Two applies of the same path: the second sees already-tuned values.
+	first := save(map[string]string{"/proc/sys/vm/swappiness": "60"})
+	second := save(map[string]string{"/proc/sys/vm/swappiness": "10", "/proc/sys/vm/dirty_ratio": "20"})
+	third := save(map[string]string{"/proc/sys/vm/swappiness": "1"})
+	if first.ID != 1 || second.ID != 2 || third.ID != 3 {
+		t.Fatalf("IDs = %d, %d, %d, want 1, 2, 3", first.ID, second.ID, third.ID)
+	}
+
+	pristine, err := LoadPristine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pristine.Values["/proc/sys/vm/swappiness"] != "60" || pristine.Values["/proc/sys/vm/dirty_ratio"] != "20" {
+		t.Errorf("pristine = %v, want first-seen values", pristine.Values)
+	}
+
+	plan, err := RestorePlan(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan["/proc/sys/vm/swappiness"] != "10" || plan["/proc/sys/vm/dirty_ratio"] != "20" {
+		t.Errorf("RestorePlan(2) = %v", plan)
+	}
+	if plan, _ := RestorePlan(3); len(plan) != 1 || plan["/proc/sys/vm/swappiness"] != "1" {
+		t.Errorf("RestorePlan(3) = %v", plan)
+	}
+
+	// Pruning never touches the newest generation or the pristine record.
+	removed, err := PruneBackups(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("pruned %v, want generations 1 and 2", removed)
+	}
+	if _, err := LoadPristine(); err != nil {
+		t.Errorf("pristine pruned: %v", err)
+	}
+	if next := save(nil); next.ID != 4 {
+		t.Errorf("next ID after prune = %d, want 4", next.ID)
+	}
+
+	if err := RemoveBackupsFrom(4); err != nil {
+		t.Fatal(err)
+	}
+	gens, _ := ListBackups()
+	if len(gens) != 1 || gens[0].ID != 3 {
+		t.Errorf("after RemoveBackupsFrom(4): %d generations", len(gens))
+	}
+}
+
+func TestPruneByAge(t *testing.T) {
+	useBackupDir(t)
+
+	old := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	SaveBackup(BackupData{Timestamp: old, Values: map[string]string{"a": "1"}})
+	SaveBackup(BackupData{Timestamp: old, Values: map[string]string{"a": "2"}})
+	SaveBackup(BackupData{Values: map[string]string{"a": "3"}})
+
+	removed, err := PruneBackups(0, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0] != 1 || removed[1] != 2 {
+		t.Errorf("pruned %v, want [1 2]", removed)
+	}
+}
+
+func TestImportLegacyBackup(t *testing.T) {
+	useBackupDir(t)
+
+	legacy := `{"timestamp": "2025-01-02T03:04:05Z", "profile": "laptop", "values": {"/proc/sys/vm/swappiness": "60"}}`
+	if err := os.WriteFile(BackupFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading shows the legacy backup but writes nothing.
+	gens, err := ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gens) != 1 || gens[0].ID != 1 || gens[0].Command != "import" || gens[0].Profile != "laptop" {
+		t.Fatalf("listed = %+v", gens)
+	}
+	if b, err := LoadBackup(1); err != nil || b.Profile != "laptop" {
+		t.Errorf("LoadBackup(1) = %v, %v", b, err)
+	}
+	if p, err := LoadPristine(); err != nil || p.Values["/proc/sys/vm/swappiness"] != "60" {
+		t.Errorf("pristine before import = %v, %v", p, err)
+	}
+	if _, err := os.Stat(BackupsDir); !os.IsNotExist(err) {
+		t.Errorf("reading created %s", BackupsDir)
+	}
+
+	// The first new restore point imports it.
+	if _, err := SaveBackup(BackupData{Command: "apply", Values: map[string]string{"/proc/sys/vm/swappiness": "10"}}); err != nil {
+		t.Fatal(err)
+	}
+	gens, _ = ListBackups()
+	if len(gens) != 2 || gens[0].Command != "import" || gens[1].ID != 2 {
+		t.Fatalf("after import = %+v", gens)
+	}
+	if _, err := os.Stat(BackupFile); !os.IsNotExist(err) {
+		t.Error("legacy backup.json left in place")
+	}
+	if p, err := LoadPristine(); err != nil || p.Values["/proc/sys/vm/swappiness"] != "60" {
+		t.Errorf("pristine after import = %v, %v", p, err)
+	}
+}
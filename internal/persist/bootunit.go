@@ -0,0 +1,246 @@
+package persist
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// BootUnitName is the systemd oneshot unit that re-applies the CPU
+// values and NIC IRQ affinity which neither sysctl.d, udev nor
+// tmpfiles.d can carry: they need a glob over CPUs or IRQs and an order
+// (governor before EPP).
+const BootUnitName = "tuner-sysfs.service"
+
+// BootUnitPath returns the path for the tuner boot unit.
+func BootUnitPath() string {
+	return "/etc/systemd/system/" + BootUnitName
+}
+
+// WriteBootUnit writes the boot unit for the profile. It returns false,
+// and removes any previous unit, when the machine has nothing the unit
+// would set (e.g. no cpufreq support).
+func WriteBootUnit(p profile.Profile) (bool, error) {
+	content := RenderBootUnit(p)
+	if content == "" {
+		return false, RemoveBootUnit()
+	}
+	return true, os.WriteFile(BootUnitPath(), []byte(content), 0644)
+}
+
+// RenderBootUnit returns the contents WriteBootUnit would write, or ""
+// if there is nothing to persist. CPU values are those of the profile's
+// current power state; laptops that switch between AC and battery need
+// the daemon to follow the change.
+func RenderBootUnit(p profile.Profile) string {
+	v := p.Values
+
+	// TLP owns governor, EPP and turbo at boot when it is enabled.
+	tlpOwnsCPU := v.SkipIfTLP && detect.DetectPower().TLP.Enabled
+
+	var execs []string
+	if !tlpOwnsCPU {
+		// The P-state mode goes first: switching it resets the policies.
+		// Active mode only offers performance and powersave, and only
+		// active mode has EPP.
+		pstate := detect.DetectPState()
+		mode := pstate.Status
+		if slices.Contains(pstate.Modes(), v.PStateMode) {
+			mode = v.PStateMode
+			execs = append(execs, writeExec(pstate.StatusPath, mode))
+		}
+
+		policies := detect.DetectCPUPolicies()
+		classes := detect.CoreClasses(policies)
+		// EPP after the governor: intel_pstate rejects most EPP values
+		// under the performance governor.
+		for _, attr := range []string{"scaling_governor", "energy_performance_preference"} {
+			for _, class := range classes {
+				group := detect.PoliciesOf(policies, class)
+				c := v.Cores(class)
+				value := c.Governor
+				if attr == "scaling_governor" {
+					if avail := pstate.Governors(mode); avail != nil && !slices.Contains(avail, value) {
+						continue
+					}
+				} else {
+					if pstate.Driver != "" && mode != "active" {
+						continue
+					}
+					value = c.EPP
+				}
+				if value == "" || !sysfs.Exists(group[0].Dir+"/"+attr) {
+					continue
+				}
+				if class == "" {
+					execs = append(execs, eachCPUExec("cpufreq/"+attr, value))
+				} else {
+					execs = append(execs, policyExecs(group, attr, func(detect.CPUPolicy) string { return value })...)
+				}
+			}
+		}
+		for _, class := range classes {
+			group := detect.PoliciesOf(policies, class)
+			c := v.Cores(class)
+			if c.MaxFreqMHz.Set {
+				execs = append(execs, policyExecs(group, "scaling_max_freq", func(p detect.CPUPolicy) string {
+					return strconv.Itoa(p.ClampFreq(c.MaxFreqMHz.Value))
+				})...)
+			}
+			if c.MinFreqMHz.Set {
+				execs = append(execs, policyExecs(group, "scaling_min_freq", func(p detect.CPUPolicy) string {
+					return strconv.Itoa(p.ClampFreq(c.MinFreqMHz.Value))
+				})...)
+			}
+		}
+		if pstate.PerfPctKnown {
+			if v.MaxPerfPct.Set {
+				execs = append(execs, writeExec(sysfs.IntelMaxPerfPct, strconv.Itoa(v.MaxPerfPct.Value)))
+			}
+			if v.MinPerfPct.Set {
+				execs = append(execs, writeExec(sysfs.IntelMinPerfPct, strconv.Itoa(v.MinPerfPct.Value)))
+			}
+		}
+		if v.IdleMaxLatency.Set && sysfs.Exists(sysfs.CPUIdleBase) {
+			execs = append(execs, idleExec(v.IdleMaxLatency.Value))
+		}
+		switch {
+		case sysfs.Exists(sysfs.IntelNoTurbo):
+			execs = append(execs, writeExec(sysfs.IntelNoTurbo, boolString(!v.TurboOn)))
+		case sysfs.Exists(sysfs.CPUBoost):
+			execs = append(execs, writeExec(sysfs.CPUBoost, boolString(v.TurboOn)))
+		}
+	}
+
+	// irqbalance would move pinned IRQs again once it starts.
+	var pinsIRQs bool
+	if v.IRQAffinity != "" {
+		if irq := detect.DetectIRQ(); !irq.IRQBalance.Enabled {
+			for _, n := range irq.NICs {
+				if line := irqExec(n); line != "" {
+					execs = append(execs, line)
+					pinsIRQs = true
+				}
+			}
+		}
+	}
+
+	if len(execs) == 0 {
+		return ""
+	}
+
+	var lines []string
+	lines = append(lines, "# Generated by tuner - do not edit manually")
+	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Name))
+	lines = append(lines, "")
+	lines = append(lines, "[Unit]")
+	lines = append(lines, "Description=Apply tuner sysfs settings")
+	if pinsIRQs {
+		// Many NIC drivers request their queue IRQs when the link is
+		// brought up.
+		lines = append(lines, "Wants=network-online.target")
+		lines = append(lines, "After=systemd-modules-load.service network-online.target")
+	} else {
+		lines = append(lines, "After=systemd-modules-load.service")
+	}
+	lines = append(lines, "")
+	lines = append(lines, "[Service]")
+	lines = append(lines, "Type=oneshot")
+	lines = append(lines, "RemainAfterExit=yes")
+	lines = append(lines, execs...)
+	lines = append(lines, "")
+	lines = append(lines, "[Install]")
+	lines = append(lines, "WantedBy=multi-user.target")
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// RemoveBootUnit removes the tuner boot unit.
+func RemoveBootUnit() error {
+	err := os.Remove(BootUnitPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// BootUnitExists returns true if the boot unit is installed.
+func BootUnitExists() bool {
+	_, err := os.Stat(BootUnitPath())
+	return err == nil
+}
+
+// writeExec is an ExecStart line writing value to one file. The leading
+// "-" keeps one unsupported knob from failing the whole unit.
+func writeExec(path, value string) string {
+	return fmt.Sprintf(`ExecStart=-/bin/sh -c 'echo %s > %s'`, value, path)
+}
+
+// eachCPUExec writes value to rel under every CPU present at boot.
+func eachCPUExec(rel, value string) string {
+	return fmt.Sprintf(`ExecStart=-/bin/sh -c 'for f in %s/cpu[0-9]*/%s; do echo %s > "$f"; done'`,
+		sysfs.CPUBase, rel, value)
+}
+
+// idleExec disables, on every CPU, the cpuidle states whose exit latency
+// exceeds maxLatency microseconds, testing each state at boot as apply
+// does.
+func idleExec(maxLatency int) string {
+	return fmt.Sprintf(`ExecStart=-/bin/sh -c 'for d in %s/cpu[0-9]*/cpuidle/state[0-9]*; do [ "$(cat "$d/latency")" -gt %d ] && echo 1 > "$d/disable"; done'`,
+		sysfs.CPUBase, maxLatency)
+}
+
+// irqExec pins a NIC's queue IRQs as apply does. IRQ numbers can change
+// between boots, so each vector is found by its action directory under
+// /proc/irq; tee takes the glob where a redirect cannot. Vectors whose
+// names the shell or systemd would interpret are skipped.
+func irqExec(n detect.NICQueues) string {
+	targets := n.SpreadTargets()
+	if targets == nil {
+		return ""
+	}
+	var cmds []string
+	for i, q := range n.IRQs {
+		if strings.ContainsAny(q.Name, " \t'\"\\$%*?[;&|<>()`") {
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf("echo %d | tee %s/*/%s/../smp_affinity_list >/dev/null", targets[i], sysfs.ProcIRQBase, q.Name))
+	}
+	if len(cmds) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`ExecStart=-/bin/sh -c '%s'`, strings.Join(cmds, "; "))
+}
+
+// policyExecs writes attr in each policy of a core class. CPU numbering
+// is stable across boots, so the policies are listed rather than globbed;
+// one line per distinct value.
+func policyExecs(group []detect.CPUPolicy, attr string, value func(detect.CPUPolicy) string) []string {
+	var execs []string
+	for _, val := range detect.PolicyValues(group, value) {
+		var paths []string
+		for _, p := range group {
+			if value(p) == val && sysfs.Exists(p.Dir+"/"+attr) {
+				paths = append(paths, p.Dir+"/"+attr)
+			}
+		}
+		if len(paths) > 0 {
+			execs = append(execs, fmt.Sprintf(`ExecStart=-/bin/sh -c 'for f in %s; do echo %s > "$f"; done'`,
+				strings.Join(paths, " "), val))
+		}
+	}
+	return execs
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
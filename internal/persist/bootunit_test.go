@@ -0,0 +1,75 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestRenderBootUnit(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		sysfs.CPUGovernor:  "powersave",
+		sysfs.CPUEPP:       "balance_power",
+		sysfs.IntelNoTurbo: "1",
+	} {
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	unit := RenderBootUnit(profile.Profile{Name: "server", Values: profile.ServerValues()})
+
+	for _, want := range []string{
+		"Type=oneshot",
+		`for f in /sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor; do echo performance > "$f"; done`,
+		`echo 0 > /sys/devices/system/cpu/intel_pstate/no_turbo`,
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+	// EPP is written after the governor.
+	if strings.Index(unit, "energy_performance_preference") < strings.Index(unit, "scaling_governor") {
+		t.Error("EPP written before governor")
+	}
+	if strings.Contains(unit, "transparent_hugepage") {
+		t.Error("THP belongs in tmpfiles.d, not the boot unit")
+	}
+
+	// Nothing to set means no unit at all.
+	sysfs.SetRoot(t.TempDir())
+	if unit := RenderBootUnit(profile.Profile{Values: profile.ServerValues()}); unit != "" {
+		t.Errorf("expected empty unit, got:\n%s", unit)
+	}
+}
+
+func TestIRQExec(t *testing.T) {
+	sysfs.SetRoot(t.TempDir())
+	defer sysfs.SetRoot("")
+
+	n := detect.NICQueues{
+		Name:      "eth0",
+		LocalCPUs: []int{2, 3},
+		IRQs: []detect.QueueIRQ{
+			{IRQ: 25, Name: "eth0-TxRx-0"},
+			{IRQ: 26, Name: "odd $name"},
+			{IRQ: 27, Name: "mlx5_comp2@pci:0000:03:00.0"},
+		},
+	}
+	want := `ExecStart=-/bin/sh -c 'echo 2 | tee /proc/irq/*/eth0-TxRx-0/../smp_affinity_list >/dev/null; ` +
+		`echo 2 | tee /proc/irq/*/mlx5_comp2@pci:0000:03:00.0/../smp_affinity_list >/dev/null'`
+	if got := irqExec(n); got != want {
+		t.Errorf("irqExec =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package persist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// CmdlineProposal is a suggested kernel command line change for settings
+// that only fully take effect at boot: THP mode governs allocations made
+// before tmpfiles.d runs, and zswap's pool is created with the boot-time
+// compressor. tuner never edits the bootloader itself.
+type CmdlineProposal struct {
+	Current  string
+	Proposed string
+	Add      []string // key=value parameters to add or replace
+	Remove   []string // existing parameters being replaced
+}
+
+// Empty returns true if the running command line already matches.
+func (c CmdlineProposal) Empty() bool {
+	return len(c.Add) == 0
+}
+
+// ProposeCmdline compares the running kernel command line with the
+// boot-time parameters the profile implies.
+func ProposeCmdline(p profile.Profile) CmdlineProposal {
+	v := p.Values
+	current, _ := sysfs.ReadString(sysfs.ProcCmdline)
+	prop := CmdlineProposal{Current: current}
+
+	var want []string
+	if v.THPEnabled != "" {
+		want = append(want, "transparent_hugepage="+v.THPEnabled)
+	}
+	switch v.Zswap {
+	case "on":
+		want = append(want, "zswap.enabled=1")
+	case "off":
+		want = append(want, "zswap.enabled=0")
+	}
+	if v.ZswapCompressor != "" {
+		want = append(want, "zswap.compressor="+v.ZswapCompressor)
+	}
+	if v.ZswapMaxPool.Set {
+		want = append(want, fmt.Sprintf("zswap.max_pool_percent=%d", v.ZswapMaxPool.Value))
+	}
+
+	params := strings.Fields(current)
+	for _, w := range want {
+		key := w[:strings.Index(w, "=")+1]
+		found := false
+		for i, param := range params {
+			if !strings.HasPrefix(param, key) {
+				continue
+			}
+			found = true
+			if param != w {
+				prop.Remove = append(prop.Remove, param)
+				prop.Add = append(prop.Add, w)
+				params[i] = w
+			}
+		}
+		if !found {
+			prop.Add = append(prop.Add, w)
+			params = append(params, w)
+		}
+	}
+	prop.Proposed = strings.Join(params, " ")
+	return prop
+}
+
+// CmdlineInstructions returns the commands to make a proposal permanent
+// with the bootloader tooling of the distribution family.
+func CmdlineInstructions(d platform.Distro, c CmdlineProposal) []string {
+	add := strings.Join(c.Add, " ")
+	var removeKeys []string
+	for _, r := range c.Remove {
+		removeKeys = append(removeKeys, r[:strings.Index(r, "=")])
+	}
+
+	switch d.Family {
+	case platform.FamilyRHEL:
+		cmd := fmt.Sprintf(`sudo grubby --update-kernel=ALL --args="%s"`, add)
+		if len(removeKeys) > 0 {
+			cmd = fmt.Sprintf(`sudo grubby --update-kernel=ALL --remove-args="%s" --args="%s"`,
+				strings.Join(removeKeys, " "), add)
+		}
+		return []string{cmd, "Reboot to apply."}
+	case platform.FamilyDebian:
+		return []string{
+			fmt.Sprintf(`Edit /etc/default/grub and add "%s" to GRUB_CMDLINE_LINUX_DEFAULT`, add),
+			"sudo update-grub",
+			"Reboot to apply.",
+		}
+	case platform.FamilyArch:
+		return []string{
+			fmt.Sprintf(`Edit the "options" line of your entry in /boot/loader/entries/*.conf (systemd-boot) and add "%s"`, add),
+			"Check the default entry with: bootctl list",
+			"Reboot to apply.",
+		}
+	default:
+		return []string{
+			fmt.Sprintf(`Add "%s" to the kernel command line in your bootloader configuration.`, add),
+			"Reboot to apply.",
+		}
+	}
+}
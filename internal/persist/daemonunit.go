@@ -0,0 +1,77 @@
+package persist
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DaemonUnitName is the systemd service that runs 'tuner daemon run' to
+// keep a profile enforced.
+const DaemonUnitName = "tuner-daemon.service"
+
+// DaemonUnitPath returns the path for the tuner daemon unit.
+func DaemonUnitPath() string {
+	return "/etc/systemd/system/" + DaemonUnitName
+}
+
+// DaemonArgs are the 'tuner daemon run' arguments baked into the unit.
+type DaemonArgs struct {
+	Exe      string // absolute path of the tuner binary
+	Profile  string
+	Workload string
+	Interval time.Duration
+}
+
+// RenderDaemonUnit returns the contents of the daemon unit.
+func RenderDaemonUnit(a DaemonArgs) string {
+	cmd := []string{a.Exe, "daemon", "run"}
+	if a.Profile != "" {
+		cmd = append(cmd, "--profile", a.Profile)
+	}
+	if a.Workload != "" {
+		cmd = append(cmd, "--workload", a.Workload)
+	}
+	if a.Interval > 0 {
+		cmd = append(cmd, "--interval", a.Interval.String())
+	}
+
+	var lines []string
+	lines = append(lines, "# Generated by tuner - do not edit manually")
+	lines = append(lines, "")
+	lines = append(lines, "[Unit]")
+	lines = append(lines, "Description=Keep the tuner profile enforced")
+	lines = append(lines, "After=systemd-modules-load.service "+BootUnitName)
+	lines = append(lines, "")
+	lines = append(lines, "[Service]")
+	lines = append(lines, "Type=simple")
+	lines = append(lines, "ExecStart="+strings.Join(cmd, " "))
+	lines = append(lines, "ExecReload=/bin/kill -HUP $MAINPID")
+	lines = append(lines, "Restart=on-failure")
+	lines = append(lines, "RestartSec=10")
+	lines = append(lines, "")
+	lines = append(lines, "[Install]")
+	lines = append(lines, "WantedBy=multi-user.target")
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// WriteDaemonUnit writes the daemon unit.
+func WriteDaemonUnit(a DaemonArgs) error {
+	return os.WriteFile(DaemonUnitPath(), []byte(RenderDaemonUnit(a)), 0644)
+}
+
+// RemoveDaemonUnit removes the daemon unit.
+func RemoveDaemonUnit() error {
+	err := os.Remove(DaemonUnitPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// DaemonUnitExists returns true if the daemon unit is installed.
+func DaemonUnitExists() bool {
+	_, err := os.Stat(DaemonUnitPath())
+	return err == nil
+}
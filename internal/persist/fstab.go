@@ -0,0 +1,252 @@
+package persist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// FstabChange is one mount option the profile wants changed. tuner
+// never edits /etc/fstab; FstabDiff shows the edit for the user to make.
+type FstabChange struct {
+	MountPoint string
+	Key        string   // what is tuned, e.g. "atime"
+	Current    string   // as mounted now
+	Add        string   // option to add, empty to only remove
+	Remove     []string // option names dropped before adding
+	Reason     string
+	Benefit    string
+}
+
+// atimeOptions are the options that select atime behaviour.
+var atimeOptions = []string{"atime", "noatime", "relatime", "norelatime", "strictatime"}
+
+// ProposeFstab compares the mount options in effect with the profile's.
+func ProposeFstab(v profile.Values, info detect.StorageInfo) []FstabChange {
+	var changes []FstabChange
+	for _, m := range info.Mounts {
+		if !m.Tunable() {
+			continue
+		}
+		add := func(c FstabChange) {
+			c.MountPoint = m.MountPoint
+			changes = append(changes, c)
+		}
+
+		// noatime also satisfies relatime: it writes even less.
+		_, set := m.Option(v.MountAtime)
+		_, noatime := m.Option("noatime")
+		if v.MountAtime != "" && !set && !noatime {
+			want := v.MountAtime
+			c := FstabChange{Key: "atime", Current: "strictatime", Add: want, Remove: atimeOptions}
+			if _, ok := m.Option("relatime"); ok {
+				c.Current = "relatime"
+			}
+			if want == "noatime" {
+				c.Reason = "Reading a file no longer writes its access time"
+				c.Benefit = "Fewer metadata writes; only tools relying on atime (mutt, tmpwatch) notice"
+			} else {
+				c.Reason = "Access times are written at most once a day"
+				c.Benefit = "Far fewer metadata writes than strictatime"
+			}
+			add(c)
+		}
+
+		if m.OnFlash() {
+			discard, online := m.Option("discard")
+			async := m.FSType == "btrfs" && discard == "async"
+			switch {
+			case v.Trim == "fstrim" && online && !async:
+				add(FstabChange{
+					Key: "discard", Current: "discard", Remove: []string{"discard"},
+					Reason:  "Synchronous discard sends a TRIM with every delete; fstrim.timer trims weekly in one batch",
+					Benefit: "Faster deletes, no TRIM stalls on drives that queue it poorly",
+				})
+			case v.Trim == "discard" && !online:
+				c := FstabChange{Key: "discard", Current: "off", Add: "discard", Remove: []string{"discard", "nodiscard"},
+					Reason: "Frees blocks on the drive as files are deleted"}
+				if m.FSType == "btrfs" {
+					c.Add = "discard=async"
+				}
+				add(c)
+			}
+		}
+
+		switch m.FSType {
+		case "ext4":
+			if !v.Ext4Commit.Set {
+				break
+			}
+			cur, ok := m.Option("commit")
+			if !ok {
+				cur = "5"
+			}
+			if want := fmt.Sprint(v.Ext4Commit.Value); cur != want {
+				add(FstabChange{
+					Key: "commit", Current: cur + "s", Add: "commit=" + want, Remove: []string{"commit"},
+					Reason:  fmt.Sprintf("Journal commits every %ss instead of every %ss", want, cur),
+					Benefit: fmt.Sprintf("Disk stays idle longer; up to %ss of writes can be lost on a crash", want),
+				})
+			}
+		case "xfs":
+			if !v.XFSLogBSize.Set {
+				break
+			}
+			cur, ok := m.Option("logbsize")
+			if !ok {
+				cur = "32k"
+			}
+			if want := fmt.Sprintf("%dk", v.XFSLogBSize.Value); cur != want {
+				add(FstabChange{
+					Key: "logbsize", Current: cur, Add: "logbsize=" + want, Remove: []string{"logbsize"},
+					Reason:  "Larger in-memory log buffers batch more metadata updates per log write",
+					Benefit: "Faster fsync-heavy and metadata-heavy workloads",
+				})
+			}
+		case "btrfs":
+			changes = append(changes, btrfsChanges(v, m)...)
+		}
+	}
+	return changes
+}
+
+func btrfsChanges(v profile.Values, m detect.MountInfo) []FstabChange {
+	var changes []FstabChange
+	compress := []string{"compress", "compress-force"}
+	cur := "no"
+	for _, name := range compress {
+		if val, ok := m.Option(name); ok {
+			cur, _, _ = strings.Cut(val, ":") // drop the zstd level
+		}
+	}
+	if want := v.BtrfsCompress; want != "" && cur != want {
+		c := FstabChange{MountPoint: m.MountPoint, Key: "compress", Current: cur, Remove: compress}
+		if want == "no" {
+			c.Reason = "Compression costs CPU on every write"
+		} else {
+			c.Add = "compress=" + want
+			c.Reason = "Transparent compression trades a little CPU for less I/O and space; applies to new writes"
+			c.Benefit = "Smaller, often faster, reads and writes on compressible data"
+		}
+		changes = append(changes, c)
+	}
+
+	_, ssd := m.Option("ssd")
+	switch {
+	case m.OnFlash() && !ssd:
+		changes = append(changes, FstabChange{
+			MountPoint: m.MountPoint, Key: "ssd", Current: "off", Add: "ssd", Remove: []string{"ssd", "nossd"},
+			Reason: "btrfs did not detect the flash disk (common behind dm or USB) and allocates as for a hard disk",
+		})
+	case m.Rotational && ssd:
+		changes = append(changes, FstabChange{
+			MountPoint: m.MountPoint, Key: "ssd", Current: "on", Remove: []string{"ssd"},
+			Reason: "SSD allocation spreads writes, which costs seeks on a rotational disk",
+		})
+	}
+	return changes
+}
+
+// FstabDiff renders the changes as a unified diff against /etc/fstab
+// that patch(1) accepts: hunks follow fstab line order, whatever order
+// the mounts happened in. Mounts that are not in fstab are listed in
+// comments before it, which patch skips.
+func FstabDiff(info detect.StorageInfo, changes []FstabChange) string {
+	type hunk struct {
+		entry detect.FstabEntry
+		opts  []string
+	}
+	var out strings.Builder // comments, then the diff
+	var hunks []hunk
+	for _, mp := range mountPoints(changes) {
+		e, ok := info.FstabFor(mp)
+		if !ok {
+			var edits []string
+			for _, c := range changes {
+				switch {
+				case c.MountPoint != mp:
+				case c.Add != "":
+					edits = append(edits, c.Add)
+				default:
+					edits = append(edits, "without "+c.Key)
+				}
+			}
+			fmt.Fprintf(&out, "# %s is not in %s; set its mount options: %s\n", mp, sysfs.Fstab, strings.Join(edits, ", "))
+			continue
+		}
+		opts := e.Options
+		for _, c := range changes {
+			if c.MountPoint == mp {
+				opts = editOptions(opts, c)
+			}
+		}
+		if !slices.Equal(opts, e.Options) {
+			hunks = append(hunks, hunk{entry: e, opts: opts})
+		}
+	}
+	if len(hunks) == 0 {
+		return out.String()
+	}
+
+	slices.SortFunc(hunks, func(a, b hunk) int { return a.entry.Line - b.entry.Line })
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", sysfs.Fstab, sysfs.Fstab)
+	for _, h := range hunks {
+		e := h.entry
+		fmt.Fprintf(&out, "@@ -%d +%d @@\n", e.Line, e.Line)
+		fmt.Fprintf(&out, "-%s\n", e.Raw)
+		fmt.Fprintf(&out, "+%s\n", replaceField(e.Raw, 3, strings.Join(h.opts, ",")))
+	}
+	return out.String()
+}
+
+// mountPoints returns the mount points changes touch, in order.
+func mountPoints(changes []FstabChange) []string {
+	var out []string
+	for _, c := range changes {
+		if !slices.Contains(out, c.MountPoint) {
+			out = append(out, c.MountPoint)
+		}
+	}
+	return out
+}
+
+// editOptions drops the options c replaces and adds the new one.
+func editOptions(opts []string, c FstabChange) []string {
+	var out []string
+	for _, o := range opts {
+		name, _, _ := strings.Cut(o, "=")
+		if !slices.Contains(c.Remove, name) {
+			out = append(out, o)
+		}
+	}
+	if c.Add != "" {
+		out = append(out, c.Add)
+	}
+	if len(out) == 0 {
+		out = []string{"defaults"}
+	}
+	return out
+}
+
+// replaceField replaces the n-th (0-based) whitespace-separated field of
+// line, keeping the original spacing.
+func replaceField(line string, n int, value string) string {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' }
+	for i, field := 0, 0; i < len(line); field++ {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		start := i
+		for i < len(line) && !isSpace(line[i]) {
+			i++
+		}
+		if field == n {
+			return line[:start] + value + line[i:]
+		}
+	}
+	return line
+}
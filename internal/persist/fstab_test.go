@@ -0,0 +1,101 @@
+package persist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestFstabDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"/sys/block/nvme0n1/queue/rotational":    "0",
+		"/sys/block/nvme0n1/queue/scheduler":     "[none] mq-deadline",
+		"/sys/block/nvme0n1/nvme0n1p2/partition": "2",
+		"/sys/block/nvme0n1/nvme0n1p3/partition": "3",
+		"/sys/block/sda/queue/rotational":        "1",
+		"/sys/block/sda/queue/scheduler":         "[bfq] none",
+		"/sys/block/sda/sda1/partition":          "1",
+		"/sys/block/sda/sda2/partition":          "2",
+		"/proc/mounts": "/dev/nvme0n1p2 / ext4 rw,relatime,discard 0 0\n" +
+			"/dev/nvme0n1p3 /home btrfs rw,noatime,compress=zstd:3,ssd,space_cache=v2 0 0\n" +
+			"/dev/sda1 /srv/backup xfs rw,relatime,logbufs=8,logbsize=32k 0 0\n" +
+			"tmpfs /tmp tmpfs rw 0 0\n",
+		"/proc/swaps": "Filename\tType\tSize\tUsed\tPriority\n/dev/sda2 partition 8388604 0 -2\n",
+		"/etc/fstab": "# /etc/fstab\n" +
+			"UUID=1111  /      ext4   defaults,discard  0 1\n" +
+			"UUID=2222  /home  btrfs  noatime,compress=zstd:3  0 0\n",
+	})
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.Ext4Commit = profile.Int(30)
+	v.XFSLogBSize = profile.Int(256)
+
+	info := detect.DetectStorage()
+	var got []string
+	for _, c := range ProposeFstab(v, info) {
+		got = append(got, c.MountPoint+" "+c.Key+"="+c.Add)
+	}
+	// /home already has noatime and btrfs_compress is unset for servers.
+	want := []string{
+		"/ atime=noatime", "/ discard=", "/ commit=commit=30",
+		"/srv/backup atime=noatime", "/srv/backup logbsize=logbsize=256k",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	diff := FstabDiff(info, ProposeFstab(v, info))
+	wantDiff := "# /srv/backup is not in /etc/fstab; set its mount options: noatime, logbsize=256k\n" +
+		"--- /etc/fstab\n" +
+		"+++ /etc/fstab\n" +
+		"@@ -2 +2 @@\n" +
+		"-UUID=1111  /      ext4   defaults,discard  0 1\n" +
+		"+UUID=1111  /      ext4   defaults,noatime,commit=30  0 1\n"
+	if diff != wantDiff {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, wantDiff)
+	}
+
+	if len(info.Swaps) != 1 || !info.Swaps[0].Rotational {
+		t.Errorf("swaps = %+v, want /dev/sda2 on a rotational disk", info.Swaps)
+	}
+}
+
+func TestFstabDiffFollowsFstabOrder(t *testing.T) {
+	dir := t.TempDir()
+	// /data was mounted before / but comes after it in fstab.
+	writeFixture(t, dir, map[string]string{
+		"/sys/block/sda/queue/rotational": "1",
+		"/sys/block/sda/queue/scheduler":  "[mq-deadline] none",
+		"/sys/block/sda/sda1/partition":   "1",
+		"/sys/block/sda/sda2/partition":   "2",
+		"/proc/mounts": "/dev/sda2 /data ext4 rw,noatime 0 0\n" +
+			"/dev/sda1 / ext4 rw,noatime 0 0\n",
+		"/etc/fstab": "UUID=1111  /      ext4  noatime  0 1\n" +
+			"UUID=2222  /data  ext4  noatime  0 2\n",
+	})
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.Ext4Commit = profile.Int(30)
+	info := detect.DetectStorage()
+
+	diff := FstabDiff(info, ProposeFstab(v, info))
+	want := "--- /etc/fstab\n" +
+		"+++ /etc/fstab\n" +
+		"@@ -1 +1 @@\n" +
+		"-UUID=1111  /      ext4  noatime  0 1\n" +
+		"+UUID=1111  /      ext4  noatime,commit=30  0 1\n" +
+		"@@ -2 +2 @@\n" +
+		"-UUID=2222  /data  ext4  noatime  0 2\n" +
+		"+UUID=2222  /data  ext4  noatime,commit=30  0 2\n"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
@@ -5,28 +5,63 @@ import (
 	"os"
 	"strings"
 
+	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/profile"
 )
 
 // WriteSysctl generates and writes /etc/sysctl.d/99-tuner.conf for the given profile.
-func WriteSysctl(p profile.Profile) error {
+func WriteSysctl(p profile.Profile, mem detect.MemoryInfo, numa detect.NUMAInfo) error {
+	return os.WriteFile(SysctlPath(), []byte(RenderSysctl(p, mem, numa)), 0644)
+}
+
+// RenderSysctl returns the contents WriteSysctl would write. mem sizes
+// hugepages_percent; NUMA policy is written only if numa has several
+// nodes.
+func RenderSysctl(p profile.Profile, mem detect.MemoryInfo, numa detect.NUMAInfo) string {
 	v := p.Values
 
 	var lines []string
 	lines = append(lines, "# Generated by tuner - do not edit manually")
-	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Type))
+	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Name))
+	if p.Workload != "" {
+		lines = append(lines, fmt.Sprintf("# Workload: %s", p.Workload))
+	}
 	lines = append(lines, "")
 
 	// Memory
 	lines = append(lines, "# Memory")
 	lines = append(lines, fmt.Sprintf("vm.swappiness = %d", v.Swappiness))
-	lines = append(lines, fmt.Sprintf("vm.dirty_background_ratio = %d", v.DirtyBgRatio))
-	lines = append(lines, fmt.Sprintf("vm.dirty_ratio = %d", v.DirtyRatio))
+	// Only one of each bytes/ratio pair may be set; the kernel zeroes the other.
+	if v.DirtyBgBytes.Set {
+		lines = append(lines, fmt.Sprintf("vm.dirty_background_bytes = %d", v.DirtyBgBytes.Value))
+	} else {
+		lines = append(lines, fmt.Sprintf("vm.dirty_background_ratio = %d", v.DirtyBgRatio))
+	}
+	if v.DirtyBytes.Set {
+		lines = append(lines, fmt.Sprintf("vm.dirty_bytes = %d", v.DirtyBytes.Value))
+	} else {
+		lines = append(lines, fmt.Sprintf("vm.dirty_ratio = %d", v.DirtyRatio))
+	}
 	lines = append(lines, fmt.Sprintf("vm.dirty_expire_centisecs = %d", v.DirtyExpire))
 	lines = append(lines, fmt.Sprintf("vm.dirty_writeback_centisecs = %d", v.DirtyWriteback))
 	lines = append(lines, fmt.Sprintf("vm.vfs_cache_pressure = %d", v.VFSCachePressure))
+	if v.WantsHugepages() && !v.HasHugepagePool(mem.HugePageSizeKB) {
+		lines = append(lines, fmt.Sprintf("vm.nr_hugepages = %d", v.NrHugepages(mem.TotalKB, mem.HugePageSizeKB)))
+	}
 	lines = append(lines, "")
 
+	// NUMA policy only matters with several nodes.
+	if numa.MultiNode() {
+		var numa []string
+		numa = appendOptSysctl(numa, "kernel.numa_balancing", v.NUMABalancing)
+		numa = appendOptSysctl(numa, "vm.zone_reclaim_mode", v.ZoneReclaim)
+		if len(numa) > 0 {
+			lines = append(lines, "# NUMA")
+			lines = append(lines, numa...)
+			lines = append(lines, "")
+		}
+	}
+
 	// Network
 	lines = append(lines, "# Network")
 	lines = append(lines, fmt.Sprintf("net.ipv4.tcp_congestion_control = %s", v.TCPCongestion))
@@ -36,10 +71,31 @@ func WriteSysctl(p profile.Profile) error {
 	lines = append(lines, fmt.Sprintf("net.core.wmem_max = %d", v.WmemMax))
 	lines = append(lines, fmt.Sprintf("net.ipv4.tcp_rmem = %s", v.TCPRmem))
 	lines = append(lines, fmt.Sprintf("net.ipv4.tcp_wmem = %s", v.TCPWmem))
+	lines = appendOptSysctl(lines, "net.core.somaxconn", v.Somaxconn)
+	lines = appendOptSysctl(lines, "net.netfilter.nf_conntrack_max", v.ConntrackMax)
+	lines = appendOptSysctl(lines, "net.core.busy_poll", v.BusyPoll)
+	lines = appendOptSysctl(lines, "net.core.busy_read", v.BusyRead)
 	lines = append(lines, "")
 
-	content := strings.Join(lines, "\n")
-	return os.WriteFile(SysctlPath(), []byte(content), 0644)
+	// Kernel limits
+	var limits []string
+	limits = appendOptSysctl(limits, "fs.inotify.max_user_watches", v.InotifyWatches)
+	limits = appendOptSysctl(limits, "fs.inotify.max_user_instances", v.InotifyInstances)
+	limits = appendOptSysctl(limits, "kernel.pid_max", v.PidMax)
+	if len(limits) > 0 {
+		lines = append(lines, "# Kernel limits")
+		lines = append(lines, limits...)
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func appendOptSysctl(lines []string, key string, v profile.OptInt) []string {
+	if !v.Set {
+		return lines
+	}
+	return append(lines, fmt.Sprintf("%s = %d", key, v.Value))
 }
 
 // RemoveSysctl removes the tuner sysctl config.
@@ -0,0 +1,39 @@
+package persist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+)
+
+func TestRenderSysctl(t *testing.T) {
+	v := profile.ServerValues()
+	profile.KVMHost.Apply(&v) // NUMA balancing on
+	v.HugepagesPercent = profile.Int(50)
+	p := profile.Profile{Name: "server", Workload: profile.KVMHost, Values: v}
+
+	// 64 GB of RAM in 2 MB pages.
+	mem := detect.MemoryInfo{TotalKB: 64 << 20, HugePageSizeKB: 2048}
+	oneNode := detect.NUMAInfo{Nodes: []detect.NUMANode{{ID: 0}}}
+	got := RenderSysctl(p, mem, oneNode)
+	if !strings.Contains(got, "vm.nr_hugepages = 16384\n") {
+		t.Errorf("missing 16384 huge pages:\n%s", got)
+	}
+	if strings.Contains(got, "# NUMA") {
+		t.Errorf("NUMA policy written for a single node:\n%s", got)
+	}
+
+	twoNodes := detect.NUMAInfo{Nodes: []detect.NUMANode{{ID: 0}, {ID: 1}}}
+	got = RenderSysctl(p, mem, twoNodes)
+	if !strings.Contains(got, "# NUMA\nkernel.numa_balancing = 1\nvm.zone_reclaim_mode = 0\n") {
+		t.Errorf("missing NUMA policy:\n%s", got)
+	}
+
+	// A pool of the default size replaces hugepages_percent.
+	p.Values.HugepagePools = []profile.HugepagePool{{SizeKB: 2048, Count: 512}}
+	if got = RenderSysctl(p, mem, oneNode); strings.Contains(got, "vm.nr_hugepages") {
+		t.Errorf("vm.nr_hugepages written next to a 2048 kB pool:\n%s", got)
+	}
+}
@@ -1,6 +1,10 @@
 package persist
 
-import "os/exec"
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
 
 // ReloadSysctl applies sysctl settings from the drop-in file.
 func ReloadSysctl() error {
@@ -14,3 +18,40 @@ func ReloadUdev() error {
 	}
 	return exec.Command("udevadm", "trigger").Run()
 }
+
+// EnableBootUnit reloads systemd and enables the boot unit so it runs
+// on every boot. Values are already live after apply, so it is not
+// started now.
+func EnableBootUnit() error {
+	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+		return err
+	}
+	return exec.Command("systemctl", "enable", BootUnitName).Run()
+}
+
+// DisableBootUnit disables the boot unit. Call before RemoveBootUnit.
+func DisableBootUnit() error {
+	return exec.Command("systemctl", "disable", BootUnitName).Run()
+}
+
+// ReloadSystemd makes systemd forget removed unit files.
+func ReloadSystemd() error {
+	return exec.Command("systemctl", "daemon-reload").Run()
+}
+
+// StartDaemonUnit reloads systemd, then enables and starts the daemon.
+func StartDaemonUnit() error {
+	if err := ReloadSystemd(); err != nil {
+		return err
+	}
+	if out, err := exec.Command("systemctl", "enable", "--now", DaemonUnitName).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl enable --now %s: %v: %s", DaemonUnitName, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// StopDaemonUnit stops and disables the daemon. Call before
+// RemoveDaemonUnit.
+func StopDaemonUnit() error {
+	return exec.Command("systemctl", "disable", "--now", DaemonUnitName).Run()
+}
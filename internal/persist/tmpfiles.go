@@ -0,0 +1,80 @@
+package persist
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// TmpfilesPath returns the path for the tuner tmpfiles.d entries.
+func TmpfilesPath() string {
+	return "/etc/tmpfiles.d/99-tuner.conf"
+}
+
+// WriteTmpfiles writes tmpfiles.d "w" entries for runtime-writable
+// memory knobs that are not sysctls. It returns false, and removes any
+// previous file, when there is nothing to write.
+func WriteTmpfiles(p profile.Profile) (bool, error) {
+	content := RenderTmpfiles(p)
+	if content == "" {
+		return false, RemoveTmpfiles()
+	}
+	return true, os.WriteFile(TmpfilesPath(), []byte(content), 0644)
+}
+
+// RenderTmpfiles returns the contents WriteTmpfiles would write, or ""
+// if there is nothing to persist.
+func RenderTmpfiles(p profile.Profile) string {
+	v := p.Values
+
+	var entries []string
+	add := func(path, value string) {
+		if value != "" && sysfs.Exists(path) {
+			entries = append(entries, fmt.Sprintf("w %s - - - - %s", path, value))
+		}
+	}
+
+	add(sysfs.THPEnabled, v.THPEnabled)
+	add(sysfs.THPDefrag, v.THPDefrag)
+	switch v.Zswap {
+	case "on":
+		add(sysfs.ZswapEnabled, "Y")
+	case "off":
+		add(sysfs.ZswapEnabled, "N")
+	}
+	add(sysfs.ZswapCompressor, v.ZswapCompressor)
+	if v.ZswapMaxPool.Set {
+		add(sysfs.ZswapMaxPool, fmt.Sprintf("%d", v.ZswapMaxPool.Value))
+	}
+	for _, a := range v.Khugepaged.Attrs() {
+		add(sysfs.KhugepagedBase+"/"+a.Name, fmt.Sprintf("%d", a.Value))
+	}
+	for _, h := range v.HugepagePools {
+		add(detect.HugePagePoolDir(int64(h.SizeKB), h.NodeID())+"/nr_hugepages", fmt.Sprintf("%d", h.Count))
+	}
+
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var lines []string
+	lines = append(lines, "# Generated by tuner - do not edit manually")
+	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Name))
+	lines = append(lines, "# Type Path Mode User Group Age Argument")
+	lines = append(lines, entries...)
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// RemoveTmpfiles removes the tuner tmpfiles.d entries.
+func RemoveTmpfiles() error {
+	err := os.Remove(TmpfilesPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
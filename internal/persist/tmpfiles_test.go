@@ -0,0 +1,109 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func writeFixture(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRenderTmpfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		sysfs.THPEnabled:                 "always [madvise] never",
+		sysfs.THPDefrag:                  "always defer defer+madvise [madvise] never",
+		sysfs.ZswapEnabled:               "N",
+		sysfs.ZswapCompressor:            "lzo",
+		sysfs.KhugepagedBase + "/defrag": "1",
+		sysfs.HugepagesBase + "/hugepages-2048kB/nr_hugepages":               "0",
+		sysfs.NodeBase + "/node1/hugepages/hugepages-1048576kB/nr_hugepages": "0",
+	})
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	profile.Database.Apply(&v)
+	v.Zswap = "on"
+	v.ZswapCompressor = "zstd"
+	v.ZswapMaxPool = profile.Int(25) // no max_pool_percent in the fixture
+	v.Khugepaged.Defrag = profile.Int(0)
+	v.HugepagePools = []profile.HugepagePool{
+		{SizeKB: 1048576, Count: 4, Node: profile.Int(1)},
+		{SizeKB: 2048, Count: 1024},
+		{SizeKB: 32768, Count: 8}, // no 32 MB pool in the fixture
+	}
+
+	got := RenderTmpfiles(profile.Profile{Name: "server", Values: v})
+	for _, want := range []string{
+		"w /sys/kernel/mm/transparent_hugepage/enabled - - - - never",
+		"w /sys/kernel/mm/transparent_hugepage/defrag - - - - never",
+		"w /sys/module/zswap/parameters/enabled - - - - Y",
+		"w /sys/module/zswap/parameters/compressor - - - - zstd",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "max_pool_percent") || strings.Contains(got, "32768kB") {
+		t.Errorf("wrote an entry for a missing path:\n%s", got)
+	}
+	for _, want := range []string{
+		"w /sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages - - - - 1024",
+		"w /sys/devices/system/node/node1/hugepages/hugepages-1048576kB/nr_hugepages - - - - 4",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing pool entry %q:\n%s", want, got)
+		}
+	}
+	if !strings.Contains(got, "w /sys/kernel/mm/transparent_hugepage/khugepaged/defrag - - - - 0") {
+		t.Errorf("missing khugepaged defrag:\n%s", got)
+	}
+}
+
+func TestProposeCmdline(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		sysfs.ProcCmdline: "root=/dev/sda1 ro transparent_hugepage=always quiet\n",
+	})
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	profile.Database.Apply(&v)
+	v.Zswap = "off"
+
+	prop := ProposeCmdline(profile.Profile{Values: v})
+	want := "root=/dev/sda1 ro transparent_hugepage=never quiet zswap.enabled=0"
+	if prop.Proposed != want {
+		t.Errorf("Proposed = %q, want %q", prop.Proposed, want)
+	}
+	if len(prop.Remove) != 1 || prop.Remove[0] != "transparent_hugepage=always" {
+		t.Errorf("Remove = %v", prop.Remove)
+	}
+
+	rhel := CmdlineInstructions(platform.Distro{Family: platform.FamilyRHEL}, prop)
+	if !strings.Contains(rhel[0], `--remove-args="transparent_hugepage" --args="transparent_hugepage=never zswap.enabled=0"`) {
+		t.Errorf("grubby command = %q", rhel[0])
+	}
+
+	// A matching command line proposes nothing.
+	writeFixture(t, dir, map[string]string{sysfs.ProcCmdline: want})
+	if prop := ProposeCmdline(profile.Profile{Values: v}); !prop.Empty() {
+		t.Errorf("expected no proposal, got %v", prop.Add)
+	}
+}
@@ -9,34 +9,201 @@ import (
 	"github.com/krisk248/tuner/internal/profile"
 )
 
+// udevEnd is the label every tuner disk rule jumps to once it applies.
+const udevEnd = `GOTO="tuner_disk_end"`
+
+// udevTypes matches each device type the way detect classifies disks.
+var udevTypes = []struct{ match, typ string }{
+	{`KERNEL=="nvme*"`, "nvme"},
+	{`KERNEL!="nvme*", ATTR{queue/rotational}=="0"`, "ssd"},
+	{`KERNEL!="nvme*", ATTR{queue/rotational}=="1"`, "hdd"},
+}
+
 // WriteUdev generates and writes /etc/udev/rules.d/99-tuner-disk.rules.
 func WriteUdev(p profile.Profile) error {
+	return os.WriteFile(UdevPath(), []byte(RenderUdev(p)), 0644)
+}
+
+// RenderUdev returns the contents WriteUdev would write. Disks are
+// matched on stable attributes, never on kernel names like sda, which
+// can change order between boots. The per-type defaults are written
+// first; then the first matching storage rule overrides what it sets and
+// skips the rest, the same precedence apply uses. dm and md devices only
+// get read-ahead and the md stripe cache, never a scheduler.
+func RenderUdev(p profile.Profile) string {
 	v := p.Values
-	storage := detect.DetectStorage()
 
 	var lines []string
 	lines = append(lines, "# Generated by tuner - do not edit manually")
-	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Type))
+	lines = append(lines, fmt.Sprintf("# Profile: %s", p.Name))
 	lines = append(lines, "")
+	lines = append(lines, `ACTION!="add|change", `+udevEnd)
+	lines = append(lines, `SUBSYSTEM!="block", `+udevEnd)
+	lines = append(lines, `ENV{DEVTYPE}!="disk", `+udevEnd)
 
-	for _, disk := range storage.Disks {
-		sched := v.IOScheduler(disk.Type)
-		// udev rule to set scheduler
-		lines = append(lines, fmt.Sprintf(
-			`ACTION=="add|change", KERNEL=="%s", ATTR{queue/scheduler}="%s"`,
-			disk.Name, sched,
-		))
-		if v.ReadAhead > 0 {
-			lines = append(lines, fmt.Sprintf(
-				`ACTION=="add|change", KERNEL=="%s", ATTR{queue/read_ahead_kb}="%d"`,
-				disk.Name, v.ReadAhead,
-			))
+	storage := detect.DetectStorage()
+	if stack := udevStack(v, storage); len(stack) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "# Read-ahead on top of dm/md stacks; schedulers stay on the disks")
+		lines = append(lines, stack...)
+	}
+	lines = append(lines, `KERNEL=="loop*|ram*|zram*|dm-*|md*", `+udevEnd)
+	lines = append(lines, "")
+
+	lines = append(lines, "# Defaults by device type")
+	for _, t := range udevTypes {
+		attrs := append([]string{fmt.Sprintf(`ATTR{queue/scheduler}="%s"`, v.IOScheduler(t.typ))}, udevQueue(v.Queue(t.typ))...)
+		lines = append(lines, t.match+", "+strings.Join(attrs, ", "))
+	}
+	if v.ReadAhead > 0 {
+		lines = append(lines, fmt.Sprintf(`ATTR{queue/read_ahead_kb}="%d"`, v.ReadAhead))
+	}
+
+	if len(v.StorageRules) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "# Storage rules, first match wins")
+		disks := storage.Disks
+		for i, r := range v.StorageRules {
+			lines = append(lines, fmt.Sprintf("# %d: %s", i+1, r))
+			if !r.NeedsResolve() {
+				lines = append(lines, udevRuleLines(v, r)...)
+				continue
+			}
+
+			// udev cannot compare sizes or tell SAS from SCSI: pin the
+			// rule to the disks it matches now.
+			matched := 0
+			for _, d := range disks {
+				if !r.Matches(d.ID()) {
+					continue
+				}
+				matched++
+				assign := udevAssignments(r, ruleQueue(v, r, d.Type))
+				if d.Serial != "" {
+					lines = append(lines, fmt.Sprintf(`ENV{ID_SERIAL_SHORT}=="%s", %s`, d.Serial, assign))
+				} else {
+					lines = append(lines, fmt.Sprintf("# %s has no serial number; matched by kernel name", d.Name))
+					lines = append(lines, fmt.Sprintf(`KERNEL=="%s", %s`, d.Name, assign))
+				}
+			}
+			if matched == 0 {
+				lines = append(lines, "# (matched no disk when saved)")
+			}
 		}
 	}
 
 	lines = append(lines, "")
-	content := strings.Join(lines, "\n")
-	return os.WriteFile(UdevPath(), []byte(content), 0644)
+	lines = append(lines, `LABEL="tuner_disk_end"`)
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// udevStack returns the rules for stack devices. dm devices are matched
+// by their device-mapper name, which LVM and cryptsetup keep stable; md
+// arrays by kernel name, which mdadm.conf pins. Values are written on
+// "change" too, since that is when dm tables and md arrays go live.
+func udevStack(v profile.Values, storage detect.StorageInfo) []string {
+	var lines []string
+	for _, dev := range storage.Stack {
+		var attrs []string
+		if ra := v.StackReadAhead(storage.DiskIDs(dev)); dev.TopLevel() && ra > 0 {
+			attrs = append(attrs, fmt.Sprintf(`ATTR{queue/read_ahead_kb}="%d"`, ra))
+		}
+		if v.MDStripeCache.Set && dev.StripeCache > 0 {
+			attrs = append(attrs, fmt.Sprintf(`ATTR{md/stripe_cache_size}="%d"`, v.MDStripeCache.Value))
+		}
+		if len(attrs) == 0 {
+			continue
+		}
+		match := fmt.Sprintf(`KERNEL=="%s"`, dev.Name)
+		if dev.DMName != "" {
+			match = fmt.Sprintf(`ENV{DM_NAME}=="%s"`, dev.DMName)
+		}
+		attrs = append(attrs, udevEnd)
+		lines = append(lines, match+", "+strings.Join(attrs, ", "))
+	}
+	return lines
+}
+
+// udevMatch translates a rule's match keys into udev match keys.
+func udevMatch(r profile.StorageRule) string {
+	var keys []string
+	if r.Model != "" {
+		keys = append(keys, fmt.Sprintf(`ATTRS{model}=="%s"`, r.Model))
+	}
+	if r.Serial != "" {
+		keys = append(keys, fmt.Sprintf(`ENV{ID_SERIAL_SHORT}=="%s"`, r.Serial))
+	}
+	switch r.Transport {
+	case "nvme":
+		keys = append(keys, `KERNEL=="nvme*"`)
+	case "sata":
+		keys = append(keys, `ENV{ID_BUS}=="ata"`)
+	case "usb":
+		keys = append(keys, `ENV{ID_BUS}=="usb"`)
+	case "virtio":
+		keys = append(keys, `KERNEL=="vd*"`)
+	case "mmc":
+		keys = append(keys, `KERNEL=="mmcblk*"`)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// udevQueue writes queue attributes. udev cannot cap max_sectors_kb at
+// the hardware limit the way apply does; a larger value fails for that
+// disk and is logged by udev.
+func udevQueue(q profile.QueueValues) []string {
+	var attrs []string
+	for _, a := range q.Attrs() {
+		attrs = append(attrs, fmt.Sprintf(`ATTR{queue/%s}="%d"`, a.Name, a.Value))
+	}
+	return attrs
+}
+
+// udevRuleLines writes a rule udev can match by itself. A rule that
+// switches the scheduler gets one line per device type: the switch
+// resets nr_requests, so the line writes the type's queue attributes
+// again under the rule's own, as apply does.
+func udevRuleLines(v profile.Values, r profile.StorageRule) []string {
+	match := udevMatch(r)
+	if r.Scheduler == "" {
+		return []string{match + ", " + udevAssignments(r, r.QueueValues)}
+	}
+	if r.Transport == "nvme" {
+		return []string{match + ", " + udevAssignments(r, ruleQueue(v, r, "nvme"))}
+	}
+	var lines []string
+	for _, t := range udevTypes {
+		if r.Transport != "" && t.typ == "nvme" {
+			continue // other transports are never NVMe
+		}
+		lines = append(lines, match+", "+t.match+", "+udevAssignments(r, ruleQueue(v, r, t.typ)))
+	}
+	return lines
+}
+
+// ruleQueue returns the queue attributes a rule's line writes on a disk
+// of diskType: only its own, unless it switches the scheduler.
+func ruleQueue(v profile.Values, r profile.StorageRule, diskType string) profile.QueueValues {
+	if r.Scheduler == "" {
+		return r.QueueValues
+	}
+	return v.Queue(diskType).Override(r.QueueValues)
+}
+
+// udevAssignments writes the values a rule sets, scheduler first since
+// switching schedulers resets nr_requests, then the queue attributes q.
+func udevAssignments(r profile.StorageRule, q profile.QueueValues) string {
+	var attrs []string
+	if r.Scheduler != "" {
+		attrs = append(attrs, fmt.Sprintf(`ATTR{queue/scheduler}="%s"`, r.Scheduler))
+	}
+	if r.ReadAhead.Set {
+		attrs = append(attrs, fmt.Sprintf(`ATTR{queue/read_ahead_kb}="%d"`, r.ReadAhead.Value))
+	}
+	attrs = append(attrs, udevQueue(q)...)
+	attrs = append(attrs, udevEnd)
+	return strings.Join(attrs, ", ")
 }
 
 // RemoveUdev removes the tuner udev rules.
@@ -0,0 +1,84 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestRenderUdevStorageRules(t *testing.T) {
+	dir := t.TempDir()
+	dev := "/sys/devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda"
+	writeFixture(t, dir, map[string]string{
+		dev + "/queue/rotational": "1",
+		dev + "/queue/scheduler":  "[mq-deadline] bfq none",
+		dev + "/size":             "31251759104", // 14.5 TiB
+		dev + "/device/model":     "ST16000NM001G-2K",
+		dev + "/device/vpd_pg80":  "\x00\x80\x00\x0cZL2ABCDE    ",
+	})
+	os.MkdirAll(filepath.Join(dir, sysfs.BlockBase), 0755)
+	if err := os.Symlink("../devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		filepath.Join(dir, sysfs.BlockBase, "sda")); err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.QueueHDD.AddRandom = profile.Int(0)
+	v.StorageRules = []profile.StorageRule{
+		{Model: "ST16000*", Scheduler: "mq-deadline", ReadAhead: profile.Int(4096), QueueValues: profile.QueueValues{NrRequests: profile.Int(256)}},
+		{Transport: "sata", MinSizeGB: 8000, Scheduler: "bfq"},
+	}
+	got := RenderUdev(profile.Profile{Name: "server", Values: v})
+
+	for _, want := range []string{
+		`KERNEL!="nvme*", ATTR{queue/rotational}=="1", ATTR{queue/scheduler}="bfq", ATTR{queue/add_random}="0"`,
+		`ATTRS{model}=="ST16000*", KERNEL!="nvme*", ATTR{queue/rotational}=="1", ATTR{queue/scheduler}="mq-deadline", ATTR{queue/read_ahead_kb}="4096", ATTR{queue/nr_requests}="256", ATTR{queue/add_random}="0", GOTO="tuner_disk_end"`,
+		// Sizes are resolved to the serials of the disks matched now.
+		`ENV{ID_SERIAL_SHORT}=="ZL2ABCDE", ATTR{queue/scheduler}="bfq", ATTR{queue/add_random}="0", GOTO="tuner_disk_end"`,
+		`LABEL="tuner_disk_end"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `KERNEL=="sda"`) {
+		t.Errorf("rules match on the kernel name:\n%s", got)
+	}
+}
+
+func TestRenderUdevRuleSchedulerSwitch(t *testing.T) {
+	sysfs.SetRoot(t.TempDir())
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.QueueNVMe.NrRequests = profile.Int(1023)
+	v.QueueHDD.NrRequests = profile.Int(64)
+	v.StorageRules = []profile.StorageRule{
+		{Transport: "nvme", Scheduler: "mq-deadline"},
+		{Model: "ST16000*", Scheduler: "mq-deadline", QueueValues: profile.QueueValues{NoMerges: profile.Int(1)}},
+		{Model: "WDC*", ReadAhead: profile.Int(1024)},
+	}
+	got := RenderUdev(profile.Profile{Name: "server", Values: v})
+
+	// The switch resets nr_requests, so each type's value is written
+	// again after it.
+	for _, want := range []string{
+		`KERNEL=="nvme*", ATTR{queue/scheduler}="mq-deadline", ATTR{queue/nr_requests}="1023", GOTO="tuner_disk_end"`,
+		`ATTRS{model}=="ST16000*", KERNEL!="nvme*", ATTR{queue/rotational}=="1", ATTR{queue/scheduler}="mq-deadline", ATTR{queue/nr_requests}="64", ATTR{queue/nomerges}="1", GOTO="tuner_disk_end"`,
+		// Without a switch the defaults line already wrote the rest.
+		`ATTRS{model}=="WDC*", ATTR{queue/read_ahead_kb}="1024", GOTO="tuner_disk_end"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `KERNEL=="nvme*", KERNEL=="nvme*"`) {
+		t.Errorf("the nvme transport rule matches the kernel name twice:\n%s", got)
+	}
+}
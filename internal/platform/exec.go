@@ -0,0 +1,27 @@
+package platform
+
+import "os/exec"
+
+// Runner executes an external command and returns its standard output.
+// Output is returned even when the command exits non-zero.
+type Runner func(name string, args ...string) ([]byte, error)
+
+var runner Runner = execOutput
+
+// SetRunner replaces the command runner used by detection. Passing nil
+// restores the default, which executes the command on the live system.
+func SetRunner(r Runner) {
+	if r == nil {
+		r = execOutput
+	}
+	runner = r
+}
+
+// Output runs a read-only diagnostic command through the current runner.
+func Output(name string, args ...string) ([]byte, error) {
+	return runner(name, args...)
+}
+
+func execOutput(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
@@ -0,0 +1,62 @@
+package profile
+
+import "fmt"
+
+// CoreValues are the cpufreq settings for one class of cores on hybrid
+// CPUs. Values a class leaves unset fall back to the profile-wide ones.
+type CoreValues struct {
+	Governor   string `toml:"governor" oneof:"performance powersave schedutil ondemand conservative userspace"`
+	EPP        string `toml:"epp" oneof:"default performance balance_performance balance_power power"`
+	MinFreqMHz OptInt `toml:"min_freq_mhz" min:"0"` // clamped to the cores' cpuinfo range
+	MaxFreqMHz OptInt `toml:"max_freq_mhz" min:"0"`
+}
+
+// Cores returns the cpufreq settings for a core class: "performance",
+// "efficiency", or "" on CPUs whose cores are all alike.
+func (v Values) Cores(class string) CoreValues {
+	var o CoreValues
+	switch class {
+	case "performance":
+		o = v.CPUPerformance
+	case "efficiency":
+		o = v.CPUEfficiency
+	}
+	c := CoreValues{Governor: v.Governor, EPP: v.EPP, MinFreqMHz: v.MinFreqMHz, MaxFreqMHz: v.MaxFreqMHz}
+	if o.Governor != "" {
+		c.Governor = o.Governor
+	}
+	if o.EPP != "" {
+		c.EPP = o.EPP
+	}
+	if o.MinFreqMHz.Set {
+		c.MinFreqMHz = o.MinFreqMHz
+	}
+	if o.MaxFreqMHz.Set {
+		c.MaxFreqMHz = o.MaxFreqMHz
+	}
+	return c
+}
+
+// validate rejects a frequency range that is empty.
+func (c CoreValues) validate() error {
+	return checkRange("min_freq_mhz", c.MinFreqMHz, "max_freq_mhz", c.MaxFreqMHz)
+}
+
+// validateCPU rejects empty profile-wide frequency and performance
+// ranges. It returns the key to report the error at.
+func (v Values) validateCPU() (string, error) {
+	if err := checkRange("min_freq_mhz", v.MinFreqMHz, "max_freq_mhz", v.MaxFreqMHz); err != nil {
+		return "min_freq_mhz", err
+	}
+	if err := checkRange("min_perf_pct", v.MinPerfPct, "max_perf_pct", v.MaxPerfPct); err != nil {
+		return "min_perf_pct", err
+	}
+	return "", nil
+}
+
+func checkRange(loKey string, lo OptInt, hiKey string, hi OptInt) error {
+	if lo.Set && hi.Set && lo.Value > hi.Value {
+		return fmt.Errorf("%s %d is above %s %d", loKey, lo.Value, hiKey, hi.Value)
+	}
+	return nil
+}
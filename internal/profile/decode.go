@@ -0,0 +1,209 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodeTOML assigns the keys of t onto the struct pointed to by out.
+// Fields are matched by their `toml` tag; keys without a matching field
+// are errors. Fields may also carry validation tags:
+//
+//	oneof:"a b c"  string must be one of the listed values
+//	min:"0"        integer lower bound (inclusive)
+//	max:"200"      integer upper bound (inclusive)
+//
+// OptInt fields take an integer and are marked Set.
+//
+// Only keys present in t are touched, so decoding onto a populated
+// struct overrides just the values the file sets.
+func decodeTOML(t *tomlTable, out any) error {
+	return decodeTable(t, reflect.ValueOf(out).Elem())
+}
+
+// tomlFields maps tag names to struct fields, flattening fields tagged
+// `toml:",inline"` into their parent.
+func tomlFields(rv reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := f.Tag.Get("toml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if tag == ",inline" {
+			for k, v := range tomlFields(rv.Field(i)) {
+				fields[k] = v
+			}
+			continue
+		}
+		fields[tag] = rv.Field(i)
+	}
+	return fields
+}
+
+// tomlField returns the struct field description for a tag name.
+func tomlField(rt reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := f.Tag.Get("toml")
+		if tag == name {
+			return f, true
+		}
+		if tag == ",inline" {
+			if sf, ok := tomlField(f.Type, name); ok {
+				return sf, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func decodeTable(t *tomlTable, rv reflect.Value) error {
+	fields := tomlFields(rv)
+
+	for _, key := range t.order {
+		v := t.keys[key]
+		fv, ok := fields[key]
+		if !ok {
+			return errAt(v.line, "unknown key %q", key)
+		}
+		sf, _ := tomlField(rv.Type(), key)
+		if err := assignValue(fv, v, key); err != nil {
+			return err
+		}
+		if err := validateField(sf, fv, v.line, key); err != nil {
+			return err
+		}
+	}
+
+	for name, sub := range t.tables {
+		fv, ok := fields[name]
+		if !ok || fv.Kind() != reflect.Struct || fv.Type() == optIntType {
+			return errAt(sub.line, "unknown table [%s]", name)
+		}
+		if err := decodeTable(sub, fv); err != nil {
+			return err
+		}
+	}
+
+	for name, arr := range t.arrays {
+		fv, ok := fields[name]
+		if !ok || fv.Kind() != reflect.Slice || fv.Type().Elem().Kind() != reflect.Struct {
+			return errAt(arr[0].line, "unknown table [[%s]]", name)
+		}
+		// Arrays of tables replace, never append to, inherited entries.
+		slice := reflect.MakeSlice(fv.Type(), len(arr), len(arr))
+		for i, sub := range arr {
+			if err := decodeTable(sub, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	}
+
+	return nil
+}
+
+var optIntType = reflect.TypeOf(OptInt{})
+
+func assignValue(fv reflect.Value, v *tomlValue, key string) error {
+	if fv.Type() == optIntType {
+		n, ok := v.val.(int64)
+		if !ok {
+			return errAt(v.line, "%s: expected an integer, got %s", key, tomlTypeName(v.val))
+		}
+		fv.Set(reflect.ValueOf(Int(int(n))))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		s, ok := v.val.(string)
+		if !ok {
+			return errAt(v.line, "%s: expected a string, got %s", key, tomlTypeName(v.val))
+		}
+		fv.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, ok := v.val.(int64)
+		if !ok {
+			return errAt(v.line, "%s: expected an integer, got %s", key, tomlTypeName(v.val))
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, ok := v.val.(bool)
+		if !ok {
+			return errAt(v.line, "%s: expected true or false, got %s", key, tomlTypeName(v.val))
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		items, ok := v.val.([]any)
+		if !ok {
+			return errAt(v.line, "%s: expected an array, got %s", key, tomlTypeName(v.val))
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			elem := &tomlValue{line: v.line, val: item}
+			if err := assignValue(slice.Index(i), elem, key); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	default:
+		return errAt(v.line, "%s: cannot be set from a profile file", key)
+	}
+	return nil
+}
+
+func validateField(sf reflect.StructField, fv reflect.Value, line int, key string) error {
+	if oneof := sf.Tag.Get("oneof"); oneof != "" && fv.Kind() == reflect.String {
+		allowed := strings.Fields(oneof)
+		ok := false
+		for _, a := range allowed {
+			if fv.String() == a {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errAt(line, "%s: %q is not one of %s", key, fv.String(), strings.Join(allowed, ", "))
+		}
+	}
+
+	if fv.Type() == optIntType {
+		fv = fv.Field(0)
+	}
+	if fv.Kind() != reflect.Int && fv.Kind() != reflect.Int64 {
+		return nil
+	}
+	n := fv.Int()
+	if s := sf.Tag.Get("min"); s != "" {
+		if lo, _ := strconv.ParseInt(s, 10, 64); n < lo {
+			return errAt(line, "%s: %d is below the minimum of %d", key, n, lo)
+		}
+	}
+	if s := sf.Tag.Get("max"); s != "" {
+		if hi, _ := strconv.ParseInt(s, 10, 64); n > hi {
+			return errAt(line, "%s: %d is above the maximum of %d", key, n, hi)
+		}
+	}
+	return nil
+}
+
+func tomlTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "an array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
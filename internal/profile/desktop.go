@@ -24,6 +24,9 @@ func DesktopValues() Values {
 		SchedSSD:         "kyber",
 		SchedHDD:         "bfq",
 		ReadAhead:        256,
+		MountAtime:       "noatime",
+		Trim:             "fstrim",
+		BtrfsCompress:    "zstd",
 		SkipIfTLP:        false,
 	}
 }
@@ -0,0 +1,175 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SystemProfileDir holds site-wide profile files.
+const SystemProfileDir = "/etc/tuner/profiles"
+
+// ProfileDirs lists the directories searched for *.toml profile files.
+// Earlier directories take precedence when two files share a name.
+var ProfileDirs = defaultProfileDirs()
+
+func defaultProfileDirs() []string {
+	var dirs []string
+	if cfg, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(cfg, "tuner", "profiles"))
+	}
+	return append(dirs, SystemProfileDir)
+}
+
+// File is a named profile loaded from a TOML file.
+type File struct {
+	Name        string
+	Path        string
+	Description string
+	Type        Type     // hardware base; empty means auto-detect
+	Extends     string   // parent profile, built-in or file
+	Workload    Workload // workload preset applied before the file's keys
+	extendsLine int
+	doc         *tomlTable
+}
+
+// fileHeader holds the keys of a profile file that describe the profile
+// itself rather than a tuning value.
+type fileHeader struct {
+	Description string   `toml:"description"`
+	Type        Type     `toml:"type" oneof:"server desktop laptop"`
+	Extends     string   `toml:"extends"`
+	Workload    Workload `toml:"workload" oneof:"database latency build-host kvm-host k8s-node"`
+}
+
+// fileDoc is the full schema of a profile file.
+type fileDoc struct {
+	fileHeader `toml:",inline"`
+	Values     `toml:",inline"`
+}
+
+// IsBuiltin returns true for the compiled-in profile names.
+func IsBuiltin(name string) bool {
+	switch Type(name) {
+	case Server, Desktop, Laptop:
+		return true
+	}
+	return false
+}
+
+// LoadFile parses and validates a profile file. Errors point at the
+// offending file and line.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if IsBuiltin(name) {
+		return nil, fmt.Errorf("%s: profile name %q is reserved for the built-in profile", path, name)
+	}
+
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fileError(path, err)
+	}
+
+	// Decode onto a scratch document so every key is type- and
+	// range-checked up front, not when the profile is first used.
+	var check fileDoc
+	if err := decodeTOML(doc, &check); err != nil {
+		return nil, fileError(path, err)
+	}
+
+	for i, r := range check.StorageRules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, doc.arrays["storage_rules"][i].line, err)
+		}
+	}
+
+	if i, err := validateHugepages(check.HugepagePools); err != nil {
+		return nil, fmt.Errorf("%s:%d: %v", path, doc.arrays["hugepages"][i].line, err)
+	}
+
+	if key, err := check.validateCPU(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %v", path, doc.keys[key].line, err)
+	}
+	for _, c := range []struct {
+		table  string
+		values CoreValues
+	}{
+		{"cpu_performance", check.CPUPerformance},
+		{"cpu_efficiency", check.CPUEfficiency},
+	} {
+		if err := c.values.validate(); err != nil {
+			return nil, fmt.Errorf("%s:%d: [%s] %v", path, doc.tables[c.table].line, c.table, err)
+		}
+	}
+
+	f := &File{
+		Name:        name,
+		Path:        path,
+		Description: check.Description,
+		Type:        check.Type,
+		Extends:     check.Extends,
+		Workload:    check.Workload,
+		doc:         doc,
+	}
+	if v, ok := doc.keys["extends"]; ok {
+		f.extendsLine = v.line
+		if f.Type != "" {
+			return nil, fmt.Errorf("%s:%d: set either type or extends, not both", path, v.line)
+		}
+		if f.Extends == name {
+			return nil, fmt.Errorf("%s:%d: profile cannot extend itself", path, v.line)
+		}
+	}
+	return f, nil
+}
+
+func fileError(path string, err error) error {
+	var te *tomlError
+	if errors.As(err, &te) {
+		return fmt.Errorf("%s:%d: %s", path, te.line, te.msg)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// ListFiles loads every profile file in ProfileDirs. Files that fail to
+// parse are reported in errs and skipped.
+func ListFiles() (files []*File, errs []error) {
+	seen := make(map[string]bool)
+	for _, dir := range ProfileDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.toml"))
+		sort.Strings(matches)
+		for _, path := range matches {
+			f, err := LoadFile(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			files = append(files, f)
+		}
+	}
+	return files, errs
+}
+
+// FindFile locates and loads the profile file with the given name.
+func FindFile(name string) (*File, error) {
+	for _, dir := range ProfileDirs {
+		path := filepath.Join(dir, name+".toml")
+		if _, err := os.Stat(path); err == nil {
+			return LoadFile(path)
+		}
+	}
+	return nil, fmt.Errorf("unknown profile %q (built-in: server, desktop, laptop; files are read from %s)",
+		name, strings.Join(ProfileDirs, ", "))
+}
@@ -0,0 +1,209 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProfile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "db-server", `
+# Tweaks for the database fleet
+description = "PostgreSQL hosts"
+type = "server"
+
+swappiness = 1
+thp_enabled = "never"
+sched_ssd = "mq-deadline"   # deadline for shared SATA SSDs
+turbo = false
+`)
+
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, err := Load("db-server", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "db-server" || p.Type != Server {
+		t.Errorf("Load = %s (%s), want db-server (server)", p.Name, p.Type)
+	}
+	if p.Values.Swappiness != 1 || p.Values.THPEnabled != "never" || p.Values.SchedSSD != "mq-deadline" {
+		t.Errorf("file values not applied: %+v", p.Values)
+	}
+	if p.Values.TurboOn {
+		t.Error("turbo = false not applied")
+	}
+	// Keys the file does not set keep the server values.
+	if p.Values.DirtyBgRatio != ServerValues().DirtyBgRatio {
+		t.Errorf("dirty_background_ratio = %d, want server default", p.Values.DirtyBgRatio)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown-key", "type = \"server\"\nswapiness = 10\n", ":2: unknown key \"swapiness\""},
+		{"bad-range", "\n\nswappiness = 500\n", ":3: swappiness: 500 is above the maximum of 200"},
+		{"bad-type", "dirty_ratio = \"20\"\n", ":1: dirty_ratio: expected an integer, got a string"},
+		{"bad-enum", "thp_enabled = \"sometimes\"\n", ":1: thp_enabled: \"sometimes\" is not one of always, madvise, never"},
+		{"bad-syntax", "governor performance\n", ":1: expected key = value"},
+		{"duplicate", "swappiness = 1\nswappiness = 2\n", ":2: duplicate key \"swappiness\""},
+		{"bad-perf-range", "max_perf_pct = 50\nmin_perf_pct = 80\n", ":2: min_perf_pct 80 is above max_perf_pct 50"},
+		{"bad-mode", "pstate_mode = \"off\"\n", ":1: pstate_mode: \"off\" is not one of active, passive, guided"},
+		{"bad-freq-range", "\n[cpu_efficiency]\nmin_freq_mhz = 3000\nmax_freq_mhz = 2000\n", ":2: [cpu_efficiency] min_freq_mhz 3000 is above max_freq_mhz 2000"},
+		{"hugepages-size", "[[hugepages]]\ncount = 8\n", ":1: hugepages entry needs size_kb"},
+		{"hugepages-twice", "[[hugepages]]\nsize_kb = 2048\ncount = 8\n\n[[hugepages]]\nsize_kb = 2048\ncount = 16\n", ":5: hugepages entry for 2048 kB pages is listed twice"},
+		{"hugepages-mixed", "[[hugepages]]\nsize_kb = 2048\ncount = 8\nnode = 0\n\n[[hugepages]]\nsize_kb = 2048\ncount = 16\n", ":6: hugepages entry for 2048 kB pages mixes a system-wide pool with node pools"},
+		{"khugepaged-range", "[khugepaged]\nmax_ptes_none = 512\n", ":2: max_ptes_none: 512 is above the maximum of 511"},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := writeProfile(t, dir, tt.name, tt.content)
+		_, err := LoadFile(path)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), path+tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.name, err, path+tt.want)
+		}
+	}
+}
+
+func TestLoadReservedName(t *testing.T) {
+	dir := t.TempDir()
+	path := writeProfile(t, dir, "server", "swappiness = 1\n")
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected error for a file named after a built-in profile")
+	}
+}
+
+func TestLoadUnknownProfile(t *testing.T) {
+	old := ProfileDirs
+	ProfileDirs = []string{t.TempDir()}
+	defer func() { ProfileDirs = old }()
+
+	if _, err := Load("nope", ""); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestParseTOMLTables(t *testing.T) {
+	doc, err := parseTOML(`
+name = 'literal # not a comment'
+list = [
+  "a",
+  "b", # trailing comment
+]
+
+[[rule]]
+match = "ST*"
+
+[[rule]]
+match = "WD*"
+
+[cpu.efficiency]
+governor = "powersave"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.keys["name"].val; got != "literal # not a comment" {
+		t.Errorf("name = %q", got)
+	}
+	if got := doc.keys["list"].val.([]any); len(got) != 2 {
+		t.Errorf("list = %v, want 2 items", got)
+	}
+	if got := len(doc.arrays["rule"]); got != 2 {
+		t.Errorf("rule count = %d, want 2", got)
+	}
+	if doc.tables["cpu"].tables["efficiency"].keys["governor"].line != 15 {
+		t.Error("nested table key has wrong line number")
+	}
+}
+
+func TestResolveExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "db-server", `
+extends = "server"
+swappiness = 10
+thp_enabled = "never"
+`)
+	base := writeProfile(t, dir, "pg-primary", `
+extends = "db-server"
+swappiness = 0
+`)
+
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, settings, err := Resolve("pg-primary", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != Server || p.Name != "pg-primary" {
+		t.Errorf("Resolve = %s (%s), want pg-primary (server)", p.Name, p.Type)
+	}
+	// An explicit zero overrides the parent's value.
+	if p.Values.Swappiness != 0 {
+		t.Errorf("swappiness = %d, want 0", p.Values.Swappiness)
+	}
+	if p.Values.THPEnabled != "never" {
+		t.Errorf("thp_enabled = %q, want inherited never", p.Values.THPEnabled)
+	}
+
+	sources := make(map[string]string)
+	for _, s := range settings {
+		sources[s.Key] = s.Source
+	}
+	if want := base + ":3"; sources["swappiness"] != want {
+		t.Errorf("swappiness source = %q, want %q", sources["swappiness"], want)
+	}
+	if !strings.HasSuffix(sources["thp_enabled"], "db-server.toml:4") {
+		t.Errorf("thp_enabled source = %q, want db-server.toml:4", sources["thp_enabled"])
+	}
+	if sources["dirty_ratio"] != "built-in server" {
+		t.Errorf("dirty_ratio source = %q, want built-in server", sources["dirty_ratio"])
+	}
+}
+
+func TestResolveExtendsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "a", `extends = "b"`)
+	writeProfile(t, dir, "b", `extends = "a"`)
+	writeProfile(t, dir, "orphan", `extends = "missing"`)
+	writeProfile(t, dir, "both", "type = \"server\"\nextends = \"a\"")
+
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	for name, want := range map[string]string{
+		"a":      "extends cycle",
+		"orphan": `orphan.toml:1: extends: unknown profile "missing"`,
+		"both":   "both.toml:2: set either type or extends",
+	} {
+		_, _, err := Resolve(name, "")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(%s) error = %v, want %q", name, err, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package profile
+
+import "fmt"
+
+// HugepagePool reserves huge pages of one size, [[hugepages]] in profile
+// files. Without node the count is system-wide and the kernel spreads it
+// over the nodes; with node it is reserved on that node only.
+type HugepagePool struct {
+	SizeKB int    `toml:"size_kb" min:"1"` // e.g. 2048, 1048576
+	Count  int    `toml:"count" min:"0"`
+	Node   OptInt `toml:"node" min:"0"`
+}
+
+// NodeID returns the pool's node, or -1 for a system-wide pool.
+func (h HugepagePool) NodeID() int {
+	if !h.Node.Set {
+		return -1
+	}
+	return h.Node.Value
+}
+
+// String describes the pool, e.g. "2048 kB pages on node 1".
+func (h HugepagePool) String() string {
+	if h.Node.Set {
+		return fmt.Sprintf("%d kB pages on node %d", h.SizeKB, h.Node.Value)
+	}
+	return fmt.Sprintf("%d kB pages", h.SizeKB)
+}
+
+// KhugepagedValues are khugepaged settings under
+// /sys/kernel/mm/transparent_hugepage/khugepaged, [khugepaged] in
+// profile files. Unset attributes are left alone.
+type KhugepagedValues struct {
+	Defrag       OptInt `toml:"defrag" min:"0" max:"1"`
+	PagesToScan  OptInt `toml:"pages_to_scan" min:"1"`
+	ScanSleepMS  OptInt `toml:"scan_sleep_millisecs" min:"0"`
+	AllocSleepMS OptInt `toml:"alloc_sleep_millisecs" min:"0"`
+	MaxPtesNone  OptInt `toml:"max_ptes_none" min:"0" max:"511"`
+}
+
+// KhugepagedAttr is one khugepaged attribute and the value to write.
+type KhugepagedAttr struct {
+	Name  string // file name under khugepaged/
+	Value int
+}
+
+// KhugepagedAttrNames lists the attributes KhugepagedValues covers.
+var KhugepagedAttrNames = []string{
+	"defrag", "pages_to_scan", "scan_sleep_millisecs", "alloc_sleep_millisecs", "max_ptes_none",
+}
+
+// Attrs returns the attributes that are set, in KhugepagedAttrNames
+// order.
+func (k KhugepagedValues) Attrs() []KhugepagedAttr {
+	var out []KhugepagedAttr
+	for i, v := range []OptInt{k.Defrag, k.PagesToScan, k.ScanSleepMS, k.AllocSleepMS, k.MaxPtesNone} {
+		if v.Set {
+			out = append(out, KhugepagedAttr{Name: KhugepagedAttrNames[i], Value: v.Value})
+		}
+	}
+	return out
+}
+
+// validateHugepages rejects pools without a size, pools listed twice
+// and a system-wide pool mixed with node pools of the same size:
+// writing the system-wide count spreads it over the nodes again.
+func validateHugepages(pools []HugepagePool) (int, error) {
+	for i, h := range pools {
+		if h.SizeKB == 0 {
+			return i, fmt.Errorf("hugepages entry needs size_kb, e.g. 2048 or 1048576")
+		}
+		for _, prev := range pools[:i] {
+			if prev.SizeKB != h.SizeKB {
+				continue
+			}
+			if prev.NodeID() == h.NodeID() {
+				return i, fmt.Errorf("hugepages entry for %s is listed twice", h)
+			}
+			if !prev.Node.Set || !h.Node.Set {
+				return i, fmt.Errorf("hugepages entry for %s mixes a system-wide pool with node pools; use one or the other", h)
+			}
+		}
+	}
+	return 0, nil
+}
@@ -31,6 +31,10 @@ func laptopACValues() Values {
 		SchedSSD:         "bfq",
 		SchedHDD:         "bfq",
 		ReadAhead:        256,
+		MountAtime:       "noatime",
+		Trim:             "fstrim",
+		Ext4Commit:       Int(60),
+		BtrfsCompress:    "zstd",
 		SkipIfTLP:        true,
 	}
 }
@@ -58,6 +62,10 @@ func laptopBatteryValues() Values {
 		SchedSSD:         "bfq",
 		SchedHDD:         "bfq",
 		ReadAhead:        128,
+		MountAtime:       "noatime",
+		Trim:             "fstrim",
+		Ext4Commit:       Int(60),
+		BtrfsCompress:    "zstd",
 		SkipIfTLP:        true,
 	}
 }
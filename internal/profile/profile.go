@@ -2,9 +2,9 @@ package profile
 
 import (
 	"os"
-	"os/exec"
 	"strings"
 
+	"github.com/krisk248/tuner/internal/platform"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
 
@@ -27,19 +27,27 @@ const (
 
 // Profile holds the detected or selected profile with tuning values.
 type Profile struct {
+	Name       string // built-in type or profile file name
 	Type       Type
+	Workload   Workload // empty when no workload preset is applied
 	PowerState PowerState
 	Values     Values
 }
 
 // AutoDetect determines the machine profile.
 func AutoDetect() Profile {
+	p := detectType()
+	p.Name = string(p.Type)
+	return p
+}
+
+func detectType() Profile {
 	p := Profile{PowerState: OnAC}
 
 	// 1. Check for battery -> laptop
 	if hasBattery() {
 		p.Type = Laptop
-		p.PowerState = detectPowerState()
+		p.PowerState = DetectPowerState()
 		p.Values = LaptopValues(p.PowerState)
 		return p
 	}
@@ -53,7 +61,7 @@ func AutoDetect() Profile {
 			return p
 		case isLaptopChassis(chassisType):
 			p.Type = Laptop
-			p.PowerState = detectPowerState()
+			p.PowerState = DetectPowerState()
 			p.Values = LaptopValues(p.PowerState)
 			return p
 		}
@@ -74,12 +82,12 @@ func AutoDetect() Profile {
 
 // ForType returns a profile for an explicitly chosen type.
 func ForType(t Type) Profile {
-	p := Profile{Type: t, PowerState: OnAC}
+	p := Profile{Name: string(t), Type: t, PowerState: OnAC}
 	switch t {
 	case Server:
 		p.Values = ServerValues()
 	case Laptop:
-		p.PowerState = detectPowerState()
+		p.PowerState = DetectPowerState()
 		p.Values = LaptopValues(p.PowerState)
 	default:
 		p.Values = DesktopValues()
@@ -88,7 +96,7 @@ func ForType(t Type) Profile {
 }
 
 func hasBattery() bool {
-	entries, err := os.ReadDir(sysfs.PowerSupplyBase)
+	entries, err := sysfs.ReadDir(sysfs.PowerSupplyBase)
 	if err != nil {
 		return false
 	}
@@ -100,18 +108,28 @@ func hasBattery() bool {
 	return false
 }
 
-func detectPowerState() PowerState {
-	entries, err := os.ReadDir(sysfs.PowerSupplyBase)
+// DetectPowerState reports OnBattery when the machine has an external
+// power supply (mains adapter or USB-C charger) and none is online.
+func DetectPowerState() PowerState {
+	entries, err := sysfs.ReadDir(sysfs.PowerSupplyBase)
 	if err != nil {
 		return OnAC
 	}
+	external := false
 	for _, e := range entries {
-		if strings.HasPrefix(e.Name(), "AC") || strings.HasPrefix(e.Name(), "ADP") {
-			path := sysfs.PowerSupplyBase + "/" + e.Name() + "/online"
-			if v, err := sysfs.ReadInt(path); err == nil && v == 0 {
-				return OnBattery
-			}
+		base := sysfs.PowerSupplyBase + "/" + e.Name()
+		typ, _ := sysfs.ReadString(base + "/type")
+		if typ != "Mains" && typ != "USB" &&
+			!strings.HasPrefix(e.Name(), "AC") && !strings.HasPrefix(e.Name(), "ADP") {
+			continue
 		}
+		external = true
+		if v, err := sysfs.ReadInt(base + "/online"); err == nil && v == 1 {
+			return OnAC
+		}
+	}
+	if external {
+		return OnBattery
 	}
 	return OnAC
 }
@@ -141,7 +159,7 @@ func hasDisplay() bool {
 }
 
 func isMultiUserTarget() bool {
-	out, err := exec.Command("systemctl", "get-default").Output()
+	out, err := platform.Output("systemctl", "get-default")
 	if err != nil {
 		return false
 	}
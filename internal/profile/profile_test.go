@@ -1,6 +1,12 @@
 package profile
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/sysfs"
+)
 
 func TestServerValues(t *testing.T) {
 	v := ServerValues()
@@ -90,3 +96,69 @@ func TestForType(t *testing.T) {
 		t.Errorf("ForType(Desktop).Type = %q, want desktop", p.Type)
 	}
 }
+
+func TestDetectPowerState(t *testing.T) {
+	dir := t.TempDir()
+	supply := func(name, typ, online string) {
+		base := filepath.Join(dir, sysfs.PowerSupplyBase, name)
+		os.MkdirAll(base, 0755)
+		os.WriteFile(filepath.Join(base, "type"), []byte(typ+"\n"), 0644)
+		if online != "" {
+			os.WriteFile(filepath.Join(base, "online"), []byte(online+"\n"), 0644)
+		}
+	}
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	if got := DetectPowerState(); got != OnAC {
+		t.Errorf("no supplies: %s, want ac", got)
+	}
+
+	supply("BAT0", "Battery", "")
+	supply("ACAD", "Mains", "0")
+	supply("ucsi-source-psy-USBC000:001", "USB", "0")
+	if got := DetectPowerState(); got != OnBattery {
+		t.Errorf("all chargers offline: %s, want battery", got)
+	}
+
+	// A USB-C charger counts as AC even with the barrel adapter unplugged.
+	supply("ucsi-source-psy-USBC000:001", "USB", "1")
+	if got := DetectPowerState(); got != OnAC {
+		t.Errorf("USB-C charger online: %s, want ac", got)
+	}
+}
+
+func TestCoresFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "hybrid", `
+extends = "server"
+min_freq_mhz = 1200
+
+[cpu_efficiency]
+governor = "powersave"
+max_freq_mhz = 2000
+`)
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, err := Load("hybrid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := p.Values
+
+	tests := []struct {
+		class string
+		want  CoreValues
+	}{
+		{"", CoreValues{Governor: "performance", EPP: "performance", MinFreqMHz: Int(1200)}},
+		{"performance", CoreValues{Governor: "performance", EPP: "performance", MinFreqMHz: Int(1200)}},
+		{"efficiency", CoreValues{Governor: "powersave", EPP: "performance", MinFreqMHz: Int(1200), MaxFreqMHz: Int(2000)}},
+	}
+	for _, tt := range tests {
+		if got := v.Cores(tt.class); got != tt.want {
+			t.Errorf("Cores(%q) = %+v, want %+v", tt.class, got, tt.want)
+		}
+	}
+}
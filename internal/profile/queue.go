@@ -0,0 +1,73 @@
+package profile
+
+// QueueValues are block queue attributes under /sys/block/<dev>/queue.
+// Unset attributes are left alone.
+type QueueValues struct {
+	NrRequests   OptInt `toml:"nr_requests" min:"4"`
+	MaxSectorsKB OptInt `toml:"max_sectors_kb" min:"4"` // capped at max_hw_sectors_kb
+	RqAffinity   OptInt `toml:"rq_affinity" min:"0" max:"2"`
+	NoMerges     OptInt `toml:"nomerges" min:"0" max:"2"`
+	AddRandom    OptInt `toml:"add_random" min:"0" max:"1"`
+	IOStats      OptInt `toml:"iostats" min:"0" max:"1"`
+	WBTLatUsec   OptInt `toml:"wbt_lat_usec" min:"0"` // 0 disables writeback throttling
+}
+
+// QueueAttr is one queue attribute and the value to write.
+type QueueAttr struct {
+	Name  string // file name under queue/
+	Value int
+}
+
+// QueueAttrNames lists the attributes QueueValues covers, in the order
+// they are written. nr_requests goes first so it lands right after the
+// scheduler switch that resets it.
+var QueueAttrNames = []string{
+	"nr_requests", "max_sectors_kb", "rq_affinity", "nomerges",
+	"add_random", "iostats", "wbt_lat_usec",
+}
+
+func (q *QueueValues) fields() []*OptInt {
+	return []*OptInt{
+		&q.NrRequests, &q.MaxSectorsKB, &q.RqAffinity, &q.NoMerges,
+		&q.AddRandom, &q.IOStats, &q.WBTLatUsec,
+	}
+}
+
+// Attrs returns the attributes that are set, in QueueAttrNames order.
+func (q QueueValues) Attrs() []QueueAttr {
+	var out []QueueAttr
+	for i, v := range q.fields() {
+		if v.Set {
+			out = append(out, QueueAttr{Name: QueueAttrNames[i], Value: v.Value})
+		}
+	}
+	return out
+}
+
+// Empty returns true if no attribute is set.
+func (q QueueValues) Empty() bool {
+	return len(q.Attrs()) == 0
+}
+
+// Override returns q with every attribute o sets replaced.
+func (q QueueValues) Override(o QueueValues) QueueValues {
+	dst := q.fields()
+	for i, v := range o.fields() {
+		if v.Set {
+			*dst[i] = *v
+		}
+	}
+	return q
+}
+
+// Queue returns the queue attributes for a device type.
+func (v Values) Queue(diskType string) QueueValues {
+	switch diskType {
+	case "nvme":
+		return v.QueueNVMe
+	case "ssd":
+		return v.QueueSSD
+	default:
+		return v.QueueHDD
+	}
+}
@@ -0,0 +1,215 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Setting is one resolved profile value and the layer that set it.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string // "built-in server" or "path:line"
+}
+
+// Load returns the profile with the given name, which is either a
+// built-in type or the name of a profile file. An empty name
+// auto-detects the hardware type. A non-empty workload replaces the
+// one named in the profile file, if any.
+func Load(name string, workload Workload) (Profile, error) {
+	p, _, err := Resolve(name, workload)
+	return p, err
+}
+
+// Resolve builds a profile in layers: the built-in values for the
+// hardware type, then the workload preset, then each file of the
+// extends chain, root first. Keys a file does not mention are inherited
+// unchanged, so an explicit zero or false in a file is an override,
+// never "unset". The returned settings list every value in Values with
+// the layer it came from.
+func Resolve(name string, workload Workload) (Profile, []Setting, error) {
+	var base Type
+	var chain []*File
+	switch {
+	case name == "":
+	case IsBuiltin(name):
+		base = Type(name)
+	default:
+		f, err := FindFile(name)
+		if err != nil {
+			return Profile{}, nil, err
+		}
+		if base, chain, err = f.chain(); err != nil {
+			return Profile{}, nil, err
+		}
+	}
+
+	var p Profile
+	if base != "" {
+		p = ForType(base)
+	} else {
+		p = AutoDetect()
+	}
+	settings := builtinSettings(p)
+	sources := make(map[string]string)
+	for _, s := range settings {
+		sources[s.Key] = s.Source
+	}
+
+	if workload == "" {
+		for _, f := range chain {
+			if f.Workload != "" {
+				workload = f.Workload
+			}
+		}
+	}
+	if workload != "" {
+		workload.Apply(&p.Values)
+		p.Workload = workload
+		for i, s := range valueSettings(p.Values) {
+			if s.Value != settings[i].Value {
+				sources[s.Key] = "workload " + string(workload)
+			}
+		}
+	}
+
+	for _, layer := range chain {
+		doc := fileDoc{Values: p.Values}
+		if err := decodeTOML(layer.doc, &doc); err != nil {
+			return Profile{}, nil, fileError(layer.Path, err)
+		}
+		p.Values = doc.Values
+		for key, line := range layer.doc.lines() {
+			sources[key] = fmt.Sprintf("%s:%d", layer.Path, line)
+		}
+	}
+	if len(chain) > 0 {
+		p.Name = chain[len(chain)-1].Name
+	}
+
+	settings = valueSettings(p.Values)
+	for i := range settings {
+		settings[i].Source = sources[settings[i].Key]
+	}
+	return p, settings, nil
+}
+
+// chain follows extends links from f to a built-in profile. It returns
+// the hardware base and the files to apply, root first.
+func (f *File) chain() (Type, []*File, error) {
+	var files []*File
+	seen := make(map[string]bool)
+
+	for cur := f; ; {
+		if seen[cur.Name] {
+			return "", nil, fmt.Errorf("%s:%d: extends cycle through %q", cur.Path, cur.extendsLine, cur.Name)
+		}
+		seen[cur.Name] = true
+		files = append([]*File{cur}, files...)
+
+		switch {
+		case cur.Extends == "":
+			return cur.Type, files, nil
+		case IsBuiltin(cur.Extends):
+			return Type(cur.Extends), files, nil
+		}
+
+		parent, err := FindFile(cur.Extends)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s:%d: extends: %w", cur.Path, cur.extendsLine, err)
+		}
+		cur = parent
+	}
+}
+
+// lines returns the line each top-level key, table or array of tables
+// was first defined on.
+func (t *tomlTable) lines() map[string]int {
+	out := make(map[string]int)
+	for k, v := range t.keys {
+		out[k] = v.line
+	}
+	for k, sub := range t.tables {
+		out[k] = sub.line
+	}
+	for k, arr := range t.arrays {
+		out[k] = arr[0].line
+	}
+	return out
+}
+
+func builtinSettings(p Profile) []Setting {
+	source := "built-in " + string(p.Type)
+	if p.Type == Laptop {
+		source += fmt.Sprintf(" (%s)", p.PowerState)
+	}
+	settings := valueSettings(p.Values)
+	for i := range settings {
+		settings[i].Source = source
+	}
+	return settings
+}
+
+// valueSettings lists every tagged field of v in declaration order.
+func valueSettings(v Values) []Setting {
+	return fieldSettings(reflect.ValueOf(v))
+}
+
+func fieldSettings(rv reflect.Value) []Setting {
+	var out []Setting
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		switch tag := rt.Field(i).Tag.Get("toml"); tag {
+		case "", "-":
+		case ",inline":
+			out = append(out, fieldSettings(rv.Field(i))...)
+		default:
+			out = append(out, Setting{Key: tag, Value: formatTOMLValue(rv.Field(i))})
+		}
+	}
+	return out
+}
+
+// formatTOMLValue renders a field the way it would be written in a
+// profile file.
+func formatTOMLValue(rv reflect.Value) string {
+	if o, ok := rv.Interface().(OptInt); ok {
+		if !o.Set {
+			return "unset"
+		}
+		return strconv.Itoa(o.Value)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return strconv.Quote(rv.String())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Struct {
+			return fmt.Sprintf("%d entries", rv.Len())
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = formatTOMLValue(rv.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		var parts []string
+		for _, s := range fieldSettings(rv) {
+			if s.Value != "unset" {
+				parts = append(parts, s.Key+" = "+s.Value)
+			}
+		}
+		if len(parts) == 0 {
+			return "unset"
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	default:
+		return fmt.Sprint(rv.Interface())
+	}
+}
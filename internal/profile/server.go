@@ -23,7 +23,10 @@ func ServerValues() Values {
 		SchedNVMe:        "none",
 		SchedSSD:         "kyber",
 		SchedHDD:         "bfq",
+		ZoneReclaim:      Int(0), // page cache is worth more than node-local allocations
 		ReadAhead:        256,
+		MountAtime:       "noatime",
+		Trim:             "fstrim",
 		SkipIfTLP:        false,
 	}
 }
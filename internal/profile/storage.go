@@ -0,0 +1,150 @@
+package profile
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// StorageRule overrides the storage values for the disks it matches.
+// Every match key that is set must match. Rules are tried in order and
+// the first match wins; values a rule leaves unset keep the profile's
+// per-type scheduler and queue attributes and global read-ahead.
+type StorageRule struct {
+	// Match keys. Model and Serial are shell globs, as in udev.
+	Model     string `toml:"model"`
+	Serial    string `toml:"serial"`
+	Transport string `toml:"transport" oneof:"nvme sata sas scsi usb virtio mmc"`
+	MinSizeGB int    `toml:"min_size_gb" min:"0"`
+	MaxSizeGB int    `toml:"max_size_gb" min:"0"` // 0 = no upper bound
+
+	// Values
+	Scheduler   string `toml:"scheduler" oneof:"none mq-deadline kyber bfq"`
+	ReadAhead   OptInt `toml:"read_ahead_kb" min:"0"`
+	QueueValues `toml:",inline"`
+}
+
+// DiskID is what storage rules match against.
+type DiskID struct {
+	Type      string // nvme, ssd, hdd
+	Model     string
+	Serial    string
+	Transport string
+	SizeGB    float64
+}
+
+// DiskValues are the storage values in force for one disk.
+type DiskValues struct {
+	Scheduler string
+	ReadAhead int // 0 = leave alone
+	Queue     QueueValues
+	Rule      int // 1-based index of the matching rule, 0 if none
+}
+
+// Matches returns true if every match key the rule sets matches d.
+func (r StorageRule) Matches(d DiskID) bool {
+	if r.Model != "" && !globMatch(r.Model, d.Model) {
+		return false
+	}
+	if r.Serial != "" && !globMatch(r.Serial, d.Serial) {
+		return false
+	}
+	if r.Transport != "" && r.Transport != d.Transport {
+		return false
+	}
+	if r.MinSizeGB > 0 && d.SizeGB < float64(r.MinSizeGB) {
+		return false
+	}
+	if r.MaxSizeGB > 0 && d.SizeGB > float64(r.MaxSizeGB) {
+		return false
+	}
+	return true
+}
+
+// NeedsResolve returns true if udev cannot match the rule by itself:
+// sizes cannot be compared in udev rules, and SAS and SCSI disks share
+// the same ID_BUS. Such rules are written for the disks they match at
+// save time.
+func (r StorageRule) NeedsResolve() bool {
+	return r.MinSizeGB > 0 || r.MaxSizeGB > 0 || r.Transport == "sas" || r.Transport == "scsi"
+}
+
+// String describes the rule's match keys.
+func (r StorageRule) String() string {
+	var parts []string
+	if r.Model != "" {
+		parts = append(parts, fmt.Sprintf("model=%q", r.Model))
+	}
+	if r.Serial != "" {
+		parts = append(parts, fmt.Sprintf("serial=%q", r.Serial))
+	}
+	if r.Transport != "" {
+		parts = append(parts, "transport="+r.Transport)
+	}
+	if r.MinSizeGB > 0 {
+		parts = append(parts, fmt.Sprintf("size>=%dGB", r.MinSizeGB))
+	}
+	if r.MaxSizeGB > 0 {
+		parts = append(parts, fmt.Sprintf("size<=%dGB", r.MaxSizeGB))
+	}
+	return strings.Join(parts, " ")
+}
+
+// validate rejects rules that would match every disk or change nothing.
+func (r StorageRule) validate() error {
+	if r.Model == "" && r.Serial == "" && r.Transport == "" && r.MinSizeGB == 0 && r.MaxSizeGB == 0 {
+		return fmt.Errorf("storage rule needs at least one of model, serial, transport, min_size_gb, max_size_gb")
+	}
+	if r.Scheduler == "" && !r.ReadAhead.Set && r.QueueValues.Empty() {
+		return fmt.Errorf("storage rule sets nothing: add scheduler, read_ahead_kb or a queue attribute such as nr_requests")
+	}
+	if r.MaxSizeGB > 0 && r.MaxSizeGB < r.MinSizeGB {
+		return fmt.Errorf("storage rule max_size_gb %d is below min_size_gb %d", r.MaxSizeGB, r.MinSizeGB)
+	}
+	for _, pattern := range []string{r.Model, r.Serial} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("storage rule pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// ForDisk returns the storage values for one disk: the first matching
+// rule's values over the per-type scheduler and queue attributes and the
+// global read-ahead.
+func (v Values) ForDisk(d DiskID) DiskValues {
+	dv := DiskValues{Scheduler: v.IOScheduler(d.Type), ReadAhead: v.ReadAhead, Queue: v.Queue(d.Type)}
+	for i, r := range v.StorageRules {
+		if !r.Matches(d) {
+			continue
+		}
+		dv.Rule = i + 1
+		if r.Scheduler != "" {
+			dv.Scheduler = r.Scheduler
+		}
+		if r.ReadAhead.Set {
+			dv.ReadAhead = r.ReadAhead.Value
+		}
+		dv.Queue = dv.Queue.Override(r.QueueValues)
+		break
+	}
+	return dv
+}
+
+// StackReadAhead returns the read-ahead for a dm or md device built on
+// the given disks: the largest any of them would get, so striping over
+// several disks never reads ahead less than one of them alone.
+func (v Values) StackReadAhead(disks []DiskID) int {
+	ra := 0
+	for _, d := range disks {
+		ra = max(ra, v.ForDisk(d).ReadAhead)
+	}
+	return ra
+}
+
+// globMatch matches like udev: SCSI models are space padded, so trailing
+// whitespace is ignored.
+func globMatch(pattern, s string) bool {
+	ok, _ := path.Match(pattern, strings.TrimRight(s, " "))
+	return ok
+}
@@ -0,0 +1,132 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStorageRulesFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "storage-host", `
+type = "server"
+read_ahead_kb = 256
+
+[[storage_rules]]
+model = "ST16000*"
+scheduler = "mq-deadline"
+read_ahead_kb = 4096
+nr_requests = 256
+
+[[storage_rules]]
+transport = "nvme"
+min_size_gb = 1000
+read_ahead_kb = 512
+`)
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, err := Load("storage-host", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := p.Values
+
+	tests := []struct {
+		disk DiskID
+		want DiskValues
+	}{
+		// SCSI models are space padded.
+		{DiskID{Type: "hdd", Model: "ST16000NM001G-2K  ", Transport: "sata", SizeGB: 14902},
+			DiskValues{Scheduler: "mq-deadline", ReadAhead: 4096, Queue: QueueValues{NrRequests: Int(256)}, Rule: 1}},
+		{DiskID{Type: "nvme", Model: "Samsung SSD 990", Transport: "nvme", SizeGB: 1863},
+			DiskValues{Scheduler: v.SchedNVMe, ReadAhead: 512, Rule: 2}},
+		{DiskID{Type: "nvme", Model: "Samsung SSD 990", Transport: "nvme", SizeGB: 476},
+			DiskValues{Scheduler: v.SchedNVMe, ReadAhead: 256}},
+		{DiskID{Type: "ssd", Model: "WDC WDS100T2B0A", Transport: "sata", SizeGB: 931},
+			DiskValues{Scheduler: v.SchedSSD, ReadAhead: 256}},
+	}
+	for _, tt := range tests {
+		if got := v.ForDisk(tt.disk); got != tt.want {
+			t.Errorf("ForDisk(%s) = %+v, want %+v", tt.disk.Model, got, tt.want)
+		}
+	}
+}
+
+func TestStorageRuleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no-match", "[[storage_rules]]\nscheduler = \"none\"\n", ":1: storage rule needs at least one of"},
+		{"no-values", "\n[[storage_rules]]\nmodel = \"ST*\"\n", ":2: storage rule sets nothing"},
+		{"bad-transport", "[[storage_rules]]\ntransport = \"fibre\"\n", ":2: transport: \"fibre\" is not one of"},
+		{"bad-glob", "[[storage_rules]]\nmodel = \"ST[\"\nscheduler = \"none\"\n", ":1: storage rule pattern \"ST[\""},
+		{"bad-queue", "[queue_ssd]\nrq_affinity = 3\n", ":2: rq_affinity: 3 is above the maximum of 2"},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := writeProfile(t, dir, tt.name, tt.content)
+		_, err := LoadFile(path)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), path+tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.name, err, path+tt.want)
+		}
+	}
+}
+
+func TestQueueValuesFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "queue-host", `
+type = "server"
+
+[queue_nvme]
+rq_affinity = 2
+nomerges = 2
+
+[queue_hdd]
+nr_requests = 256
+
+[[storage_rules]]
+model = "INTEL SSDPE*"
+nomerges = 0
+`)
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, err := Load("queue-host", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := p.Values
+
+	tests := []struct {
+		disk DiskID
+		want QueueValues
+	}{
+		{DiskID{Type: "nvme", Model: "Samsung SSD 990"}, QueueValues{RqAffinity: Int(2), NoMerges: Int(2)}},
+		// The rule overrides nomerges and keeps the per-type rq_affinity.
+		{DiskID{Type: "nvme", Model: "INTEL SSDPE2KX040T8"}, QueueValues{RqAffinity: Int(2), NoMerges: Int(0)}},
+		{DiskID{Type: "hdd", Model: "ST16000NM001G"}, QueueValues{NrRequests: Int(256)}},
+		{DiskID{Type: "ssd", Model: "WDC WDS100T2B0A"}, QueueValues{}},
+	}
+	for _, tt := range tests {
+		if got := v.ForDisk(tt.disk).Queue; got != tt.want {
+			t.Errorf("ForDisk(%s).Queue = %+v, want %+v", tt.disk.Model, got, tt.want)
+		}
+	}
+
+	var names []string
+	for _, a := range v.QueueNVMe.Attrs() {
+		names = append(names, a.Name)
+	}
+	if got := strings.Join(names, ","); got != "rq_affinity,nomerges" {
+		t.Errorf("Attrs = %s, want rq_affinity,nomerges", got)
+	}
+}
@@ -0,0 +1,326 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This is a deliberately small TOML reader covering what profile files
+// need: comments, [tables], [[arrays of tables]], bare keys, strings,
+// integers, booleans and arrays of those. Every key keeps its line
+// number so validation errors can point at the offending line.
+
+// tomlValue is a single key's value and where it was defined.
+type tomlValue struct {
+	line int
+	val  any // string, int64, bool or []any
+}
+
+// tomlTable is a parsed [table] or the document root.
+type tomlTable struct {
+	line   int
+	keys   map[string]*tomlValue
+	order  []string
+	tables map[string]*tomlTable
+	arrays map[string][]*tomlTable
+}
+
+func newTOMLTable(line int) *tomlTable {
+	return &tomlTable{
+		line:   line,
+		keys:   make(map[string]*tomlValue),
+		tables: make(map[string]*tomlTable),
+		arrays: make(map[string][]*tomlTable),
+	}
+}
+
+// tomlError is a parse or validation error tied to a line.
+type tomlError struct {
+	line int
+	msg  string
+}
+
+func (e *tomlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+func errAt(line int, format string, args ...any) error {
+	return &tomlError{line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// parseTOML parses a TOML document.
+func parseTOML(data string) (*tomlTable, error) {
+	root := newTOMLTable(0)
+	cur := root
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		// [[array.of.tables]]
+		if strings.HasPrefix(line, "[[") {
+			if !strings.HasSuffix(line, "]]") {
+				return nil, errAt(lineNo, "unterminated table header %q", line)
+			}
+			path, err := splitTablePath(line[2:len(line)-2], lineNo)
+			if err != nil {
+				return nil, err
+			}
+			parent, err := walkTables(root, path[:len(path)-1], lineNo)
+			if err != nil {
+				return nil, err
+			}
+			last := path[len(path)-1]
+			if _, ok := parent.keys[last]; ok {
+				return nil, errAt(lineNo, "%q is already defined as a key", last)
+			}
+			if _, ok := parent.tables[last]; ok {
+				return nil, errAt(lineNo, "%q is already defined as a table", last)
+			}
+			cur = newTOMLTable(lineNo)
+			parent.arrays[last] = append(parent.arrays[last], cur)
+			continue
+		}
+
+		// [table]
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errAt(lineNo, "unterminated table header %q", line)
+			}
+			path, err := splitTablePath(line[1:len(line)-1], lineNo)
+			if err != nil {
+				return nil, err
+			}
+			parent, err := walkTables(root, path[:len(path)-1], lineNo)
+			if err != nil {
+				return nil, err
+			}
+			last := path[len(path)-1]
+			if _, ok := parent.keys[last]; ok {
+				return nil, errAt(lineNo, "%q is already defined as a key", last)
+			}
+			if _, ok := parent.arrays[last]; ok {
+				return nil, errAt(lineNo, "%q is already defined as an array of tables", last)
+			}
+			if t, ok := parent.tables[last]; ok {
+				if t.line != 0 {
+					return nil, errAt(lineNo, "table %q already defined on line %d", last, t.line)
+				}
+				t.line = lineNo
+				cur = t
+			} else {
+				cur = newTOMLTable(lineNo)
+				parent.tables[last] = cur
+			}
+			continue
+		}
+
+		// key = value
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, errAt(lineNo, "expected key = value, got %q", line)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !isBareKey(key) {
+			return nil, errAt(lineNo, "invalid key %q", key)
+		}
+		raw := strings.TrimSpace(line[eq+1:])
+
+		// Arrays may span lines until the brackets balance.
+		if strings.HasPrefix(raw, "[") {
+			for !bracketsBalanced(raw) && i+1 < len(lines) {
+				i++
+				raw += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		val, err := parseTOMLValue(raw, lineNo)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := cur.keys[key]; ok {
+			return nil, errAt(lineNo, "duplicate key %q (first defined on line %d)", key, prev.line)
+		}
+		if _, ok := cur.tables[key]; ok {
+			return nil, errAt(lineNo, "%q is already defined as a table", key)
+		}
+		cur.keys[key] = &tomlValue{line: lineNo, val: val}
+		cur.order = append(cur.order, key)
+	}
+
+	return root, nil
+}
+
+// walkTables descends into (creating if needed) the tables named by path.
+// For arrays of tables, the most recently defined element is used.
+func walkTables(t *tomlTable, path []string, line int) (*tomlTable, error) {
+	for _, name := range path {
+		if arr, ok := t.arrays[name]; ok {
+			t = arr[len(arr)-1]
+			continue
+		}
+		if _, ok := t.keys[name]; ok {
+			return nil, errAt(line, "%q is already defined as a key", name)
+		}
+		next, ok := t.tables[name]
+		if !ok {
+			next = newTOMLTable(0)
+			t.tables[name] = next
+		}
+		t = next
+	}
+	return t, nil
+}
+
+func splitTablePath(s string, line int) ([]string, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if !isBareKey(p) {
+			return nil, errAt(line, "invalid table name %q", s)
+		}
+		parts[i] = p
+	}
+	return parts, nil
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// stripComment removes a trailing # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || i == 0 || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func bracketsBalanced(s string) bool {
+	depth := 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || s[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func parseTOMLValue(raw string, line int) (any, error) {
+	switch {
+	case raw == "":
+		return nil, errAt(line, "missing value")
+	case raw == "true":
+		return true, nil
+	case raw == "false":
+		return false, nil
+	case strings.HasPrefix(raw, `"`):
+		return parseBasicString(raw, line)
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return nil, errAt(line, "unterminated string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case strings.HasPrefix(raw, "["):
+		return parseArray(raw, line)
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 0, 64)
+	if err != nil {
+		return nil, errAt(line, "invalid value %q", raw)
+	}
+	return n, nil
+}
+
+func parseBasicString(raw string, line int) (string, error) {
+	if len(raw) < 2 || !strings.HasSuffix(raw, `"`) {
+		return "", errAt(line, "unterminated string %s", raw)
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", errAt(line, "invalid string %s", raw)
+	}
+	return s, nil
+}
+
+func parseArray(raw string, line int) ([]any, error) {
+	if !strings.HasSuffix(raw, "]") {
+		return nil, errAt(line, "unterminated array %s", raw)
+	}
+	body := strings.TrimSpace(raw[1 : len(raw)-1])
+
+	var items []any
+	for body != "" {
+		var elem string
+		var quote rune
+		end := len(body)
+		for i, r := range body {
+			if quote != 0 {
+				if r == quote && (quote == '\'' || body[i-1] != '\\') {
+					quote = 0
+				}
+				continue
+			}
+			if r == '"' || r == '\'' {
+				quote = r
+			} else if r == ',' {
+				end = i
+				break
+			}
+		}
+		elem = strings.TrimSpace(body[:end])
+		if end < len(body) {
+			body = strings.TrimSpace(body[end+1:])
+		} else {
+			body = ""
+		}
+		if elem == "" {
+			// Trailing comma
+			continue
+		}
+		if strings.HasPrefix(elem, "[") {
+			return nil, errAt(line, "nested arrays are not supported")
+		}
+		v, err := parseTOMLValue(elem, line)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
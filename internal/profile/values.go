@@ -1,38 +1,106 @@
 package profile
 
 // Values holds all tuning parameters for a profile.
+// The toml tags name the keys used in profile files.
 type Values struct {
 	// CPU
-	Governor string
-	EPP      string
-	TurboOn  bool
+	Governor       string `toml:"governor" oneof:"performance powersave schedutil ondemand conservative userspace"`
+	EPP            string `toml:"epp" oneof:"default performance balance_performance balance_power power"`
+	TurboOn        bool   `toml:"turbo"`
+	IdleMaxLatency OptInt `toml:"idle_max_latency_us" min:"0"`               // disable C-states with a higher exit latency
+	MinFreqMHz     OptInt `toml:"min_freq_mhz" min:"0"`                      // scaling_min_freq, clamped to cpuinfo_min_freq..cpuinfo_max_freq
+	MaxFreqMHz     OptInt `toml:"max_freq_mhz" min:"0"`                      // scaling_max_freq, clamped likewise
+	MinPerfPct     OptInt `toml:"min_perf_pct" min:"0" max:"100"`            // intel_pstate only
+	MaxPerfPct     OptInt `toml:"max_perf_pct" min:"0" max:"100"`            // intel_pstate only
+	PStateMode     string `toml:"pstate_mode" oneof:"active passive guided"` // intel_pstate or amd-pstate status; guided is amd-pstate only
+
+	// Per core class on hybrid CPUs, [cpu_performance] and
+	// [cpu_efficiency] in profile files
+	CPUPerformance CoreValues `toml:"cpu_performance"`
+	CPUEfficiency  CoreValues `toml:"cpu_efficiency"`
 
 	// Memory
-	Swappiness       int
-	DirtyBgRatio     int
-	DirtyRatio       int
-	DirtyExpire      int // centisecs
-	DirtyWriteback   int // centisecs
-	VFSCachePressure int
-	THPEnabled       string // always, madvise, never
+	Swappiness       int    `toml:"swappiness" min:"0" max:"200"`
+	DirtyBgRatio     int    `toml:"dirty_background_ratio" min:"0" max:"100"`
+	DirtyRatio       int    `toml:"dirty_ratio" min:"0" max:"100"`
+	DirtyBgBytes     OptInt `toml:"dirty_background_bytes" min:"0"`    // replaces dirty_background_ratio when set
+	DirtyBytes       OptInt `toml:"dirty_bytes" min:"0"`               // replaces dirty_ratio when set
+	DirtyExpire      int    `toml:"dirty_expire_centisecs" min:"0"`    // centisecs
+	DirtyWriteback   int    `toml:"dirty_writeback_centisecs" min:"0"` // centisecs
+	VFSCachePressure int    `toml:"vfs_cache_pressure" min:"0"`
+	THPEnabled       string `toml:"thp_enabled" oneof:"always madvise never"`                    // always, madvise, never
+	THPDefrag        string `toml:"thp_defrag" oneof:"always defer defer+madvise madvise never"` // empty = leave alone
+	Zswap            string `toml:"zswap" oneof:"on off"`                                        // empty = leave alone
+	ZswapCompressor  string `toml:"zswap_compressor" oneof:"lzo lz4 lz4hc zstd deflate 842"`     // empty = leave alone
+	ZswapMaxPool     OptInt `toml:"zswap_max_pool_percent" min:"1" max:"100"`
+	HugepagesPercent OptInt `toml:"hugepages_percent" min:"0" max:"90"` // % of RAM as default-size huge pages
+	Hugepages        OptInt `toml:"nr_hugepages" min:"0"`               // default-size huge pages; replaces hugepages_percent
+	KSM              OptInt `toml:"ksm_run" min:"0" max:"2"`            // 0=off, 1=merge, 2=unmerge
+	NUMABalancing    OptInt `toml:"numa_balancing" min:"0" max:"3"`     // 1=on, 2=memory tiering; multi-node machines only
+	ZoneReclaim      OptInt `toml:"zone_reclaim_mode" min:"0" max:"7"`  // 0=allocate remotely, 1=reclaim locally first
+
+	// Huge page pools per size and node, [[hugepages]] in profile
+	// files, and [khugepaged]
+	HugepagePools []HugepagePool   `toml:"hugepages"`
+	Khugepaged    KhugepagedValues `toml:"khugepaged"`
 
 	// Network
-	TCPCongestion string
-	TCPFastOpen   int
-	TCPMTUProbing int
-	RmemMax       int // bytes
-	WmemMax       int // bytes
-	TCPRmem       string // "min default max"
-	TCPWmem       string // "min default max"
+	TCPCongestion string `toml:"tcp_congestion"`
+	TCPFastOpen   int    `toml:"tcp_fastopen" min:"0" max:"3"`
+	TCPMTUProbing int    `toml:"tcp_mtu_probing" min:"0" max:"2"`
+	RmemMax       int    `toml:"rmem_max" min:"0"` // bytes
+	WmemMax       int    `toml:"wmem_max" min:"0"` // bytes
+	TCPRmem       string `toml:"tcp_rmem"`         // "min default max"
+	TCPWmem       string `toml:"tcp_wmem"`         // "min default max"
+	Somaxconn     OptInt `toml:"somaxconn" min:"128"`
+	ConntrackMax  OptInt `toml:"conntrack_max" min:"0"`
+	BusyPoll      OptInt `toml:"busy_poll" min:"0"`               // usecs
+	BusyRead      OptInt `toml:"busy_read" min:"0"`               // usecs
+	IRQAffinity   string `toml:"irq_affinity" oneof:"numa-local"` // pin NIC queue IRQs; empty = leave to irqbalance
+
+	// Kernel limits
+	InotifyWatches   OptInt `toml:"inotify_max_user_watches" min:"8192"`
+	InotifyInstances OptInt `toml:"inotify_max_user_instances" min:"128"`
+	PidMax           OptInt `toml:"pid_max" min:"32768" max:"4194304"`
 
 	// Storage (scheduler recommendations by device type)
-	SchedNVMe string
-	SchedSSD  string
-	SchedHDD  string
-	ReadAhead int // KB
+	SchedNVMe string `toml:"sched_nvme" oneof:"none mq-deadline kyber bfq"`
+	SchedSSD  string `toml:"sched_ssd" oneof:"none mq-deadline kyber bfq"`
+	SchedHDD  string `toml:"sched_hdd" oneof:"none mq-deadline kyber bfq"`
+	ReadAhead int    `toml:"read_ahead_kb" min:"0"` // KB
+
+	// Queue attributes by device type, [queue_nvme] etc. in profile files
+	QueueNVMe QueueValues `toml:"queue_nvme"`
+	QueueSSD  QueueValues `toml:"queue_ssd"`
+	QueueHDD  QueueValues `toml:"queue_hdd"`
+
+	MDStripeCache OptInt `toml:"md_stripe_cache_size" min:"17" max:"32768"` // pages, md raid4/5/6
+
+	// Mount options, proposed by 'tuner suggest --fstab-diff' and never
+	// applied. Empty or unset = leave alone.
+	MountAtime    string `toml:"mount_atime" oneof:"noatime relatime"`
+	Trim          string `toml:"trim" oneof:"fstrim discard"`     // flash: fstrim.timer or online discard
+	Ext4Commit    OptInt `toml:"ext4_commit" min:"1"`             // seconds
+	XFSLogBSize   OptInt `toml:"xfs_logbsize" min:"16" max:"256"` // KB
+	BtrfsCompress string `toml:"btrfs_compress" oneof:"zstd lzo zlib no"`
+
+	// Per-device overrides, [[storage_rules]] in profile files
+	StorageRules []StorageRule `toml:"storage_rules"`
 
 	// Power
-	SkipIfTLP bool // don't touch power if TLP is active
+	SkipIfTLP bool `toml:"skip_if_tlp"` // don't touch power if TLP is active
+}
+
+// OptInt is an integer setting that is left untouched unless Set.
+// Values added for workloads use it so that 0 can be a real target.
+type OptInt struct {
+	Value int
+	Set   bool
+}
+
+// Int returns an OptInt set to v.
+func Int(v int) OptInt {
+	return OptInt{Value: v, Set: true}
 }
 
 // IOScheduler returns the recommended scheduler for a device type.
@@ -48,3 +116,34 @@ func (v Values) IOScheduler(diskType string) string {
 		return v.SchedHDD
 	}
 }
+
+// WantsHugepages returns true if the profile sets vm.nr_hugepages,
+// directly or as a share of RAM.
+func (v Values) WantsHugepages() bool {
+	return v.Hugepages.Set || v.HugepagesPercent.Set
+}
+
+// HasHugepagePool returns true if a [[hugepages]] entry sizes pages of
+// sizeKB, system-wide or on a node. For the default size such entries
+// replace nr_hugepages and hugepages_percent, which write the same pool.
+func (v Values) HasHugepagePool(sizeKB int64) bool {
+	for _, h := range v.HugepagePools {
+		if int64(h.SizeKB) == sizeKB {
+			return true
+		}
+	}
+	return false
+}
+
+// NrHugepages returns the vm.nr_hugepages count: Hugepages if set, or
+// HugepagesPercent converted for a machine with totalKB of RAM and
+// pageKB huge pages.
+func (v Values) NrHugepages(totalKB, pageKB int64) int {
+	if v.Hugepages.Set {
+		return v.Hugepages.Value
+	}
+	if !v.HugepagesPercent.Set || pageKB <= 0 {
+		return 0
+	}
+	return int(totalKB * int64(v.HugepagesPercent.Value) / 100 / pageKB)
+}
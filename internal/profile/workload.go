@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Workload describes what the machine is used for. It is layered on top
+// of the hardware Type: hardware values first, then the workload preset,
+// then any profile file keys.
+type Workload string
+
+const (
+	Database  Workload = "database"
+	Latency   Workload = "latency"
+	BuildHost Workload = "build-host"
+	KVMHost   Workload = "kvm-host"
+	K8sNode   Workload = "k8s-node"
+)
+
+// Workloads lists the built-in workload presets.
+var Workloads = []Workload{Database, Latency, BuildHost, KVMHost, K8sNode}
+
+// ParseWorkload validates a workload name. An empty name means none.
+func ParseWorkload(name string) (Workload, error) {
+	if name == "" {
+		return "", nil
+	}
+	for _, w := range Workloads {
+		if string(w) == name {
+			return w, nil
+		}
+	}
+	names := make([]string, len(Workloads))
+	for i, w := range Workloads {
+		names[i] = string(w)
+	}
+	return "", fmt.Errorf("unknown workload %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Apply overrides the values this workload cares about and leaves the
+// rest to the hardware profile.
+func (w Workload) Apply(v *Values) {
+	switch w {
+	case Database:
+		// Compaction stalls and large writeback bursts hurt query latency.
+		v.THPEnabled = "never"
+		v.THPDefrag = "never"
+		v.Swappiness = 1
+		v.DirtyBgBytes = Int(64 << 20)
+		v.DirtyBytes = Int(256 << 20)
+		v.SchedNVMe = "none"
+		v.SchedSSD = "mq-deadline"
+		v.SchedHDD = "mq-deadline"
+		v.QueueNVMe.RqAffinity = Int(2)
+		v.QueueSSD.RqAffinity = Int(2)
+		v.XFSLogBSize = Int(256)
+		v.Somaxconn = Int(65535)
+		// Balancing faults and migrations show up as query latency
+		// spikes; buffer pools are placed with numactl instead.
+		v.NUMABalancing = Int(0)
+		v.ZoneReclaim = Int(0)
+
+	case Latency:
+		v.Governor = "performance"
+		v.EPP = "performance"
+		v.TurboOn = true
+		v.IdleMaxLatency = Int(10)
+		// Complete I/O on the submitting CPU and skip merge lookups.
+		v.QueueNVMe.RqAffinity = Int(2)
+		v.QueueNVMe.NoMerges = Int(2)
+		v.BusyPoll = Int(50)
+		v.BusyRead = Int(50)
+
+	case BuildHost:
+		// Compilers and file watchers open many files; let writeback
+		// batch up since build output is reproducible.
+		v.Governor = "performance"
+		v.DirtyBgRatio = 10
+		v.DirtyRatio = 40
+		v.VFSCachePressure = 50
+		v.InotifyWatches = Int(524288)
+		v.InotifyInstances = Int(1024)
+		v.PidMax = Int(4194304)
+
+	case KVMHost:
+		v.THPEnabled = "always"
+		v.THPDefrag = "madvise"
+		v.KSM = Int(1)
+		v.Swappiness = 10
+		// Unpinned guests follow their memory across nodes.
+		v.NUMABalancing = Int(1)
+
+	case K8sNode:
+		v.ConntrackMax = Int(1048576)
+		v.InotifyWatches = Int(524288)
+		v.InotifyInstances = Int(8192)
+		v.PidMax = Int(4194304)
+		v.Somaxconn = Int(32768)
+	}
+}
@@ -0,0 +1,101 @@
+package profile
+
+import "testing"
+
+func TestResolveWorkloadLayers(t *testing.T) {
+	dir := t.TempDir()
+	path := writeProfile(t, dir, "pg", `
+type = "server"
+workload = "database"
+swappiness = 5
+`)
+
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	p, settings, err := Resolve("pg", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Workload != Database {
+		t.Errorf("workload = %q, want database", p.Workload)
+	}
+	// hardware < workload < file
+	if p.Values.THPEnabled != "never" || !p.Values.DirtyBytes.Set {
+		t.Errorf("database preset not applied: %+v", p.Values)
+	}
+	if p.Values.Swappiness != 5 {
+		t.Errorf("swappiness = %d, want file value 5", p.Values.Swappiness)
+	}
+
+	sources := make(map[string]string)
+	for _, s := range settings {
+		sources[s.Key] = s.Source
+	}
+	for key, want := range map[string]string{
+		"thp_enabled":   "workload database",
+		"swappiness":    path + ":4",
+		"tcp_fastopen":  "built-in server",
+		"pid_max":       "built-in server",
+		"dirty_bytes":   "workload database",
+		"sched_nvme":    "built-in server", // database keeps none
+		"rmem_max":      "built-in server",
+		"governor":      "built-in server",
+		"conntrack_max": "built-in server",
+	} {
+		if sources[key] != want {
+			t.Errorf("%s source = %q, want %q", key, sources[key], want)
+		}
+	}
+
+	// An explicit workload replaces the file's.
+	p, err = Load("pg", K8sNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Workload != K8sNode || p.Values.THPEnabled == "never" || !p.Values.PidMax.Set {
+		t.Errorf("Load(pg, k8s-node) = %s %+v", p.Workload, p.Values)
+	}
+}
+
+func TestParseWorkload(t *testing.T) {
+	if w, err := ParseWorkload("kvm-host"); err != nil || w != KVMHost {
+		t.Errorf("ParseWorkload(kvm-host) = %q, %v", w, err)
+	}
+	if _, err := ParseWorkload("web"); err == nil {
+		t.Error("ParseWorkload(web) succeeded, want error")
+	}
+}
+
+func TestOptIntFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "edge", `
+extends = "server"
+busy_poll = 0
+pid_max = 10
+`)
+	old := ProfileDirs
+	ProfileDirs = []string{dir}
+	defer func() { ProfileDirs = old }()
+
+	_, err := FindFile("edge")
+	if err == nil {
+		t.Fatal("pid_max = 10 accepted, want minimum error")
+	}
+
+	writeProfile(t, dir, "edge", `
+extends = "server"
+busy_poll = 0
+`)
+	p, err := Load("edge", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Values.BusyPoll != Int(0) {
+		t.Errorf("busy_poll = %+v, want set to 0", p.Values.BusyPoll)
+	}
+	if p.Values.BusyRead.Set {
+		t.Error("busy_read set without appearing in the file")
+	}
+}
@@ -0,0 +1,418 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/platform"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+const (
+	manifestName = "manifest.json"
+	commandsName = "commands.json"
+	rootPrefix   = "root"
+	formatV1     = 1
+)
+
+// Manifest describes where and when a snapshot was taken.
+type Manifest struct {
+	Format   int    `json:"format"`
+	Hostname string `json:"hostname"`
+	Created  string `json:"created"`
+	Version  string `json:"tuner_version"`
+	Files    int    `json:"files"`
+	Commands int    `json:"commands"`
+}
+
+// Command is the recorded result of an external command.
+type Command struct {
+	Name   string   `json:"name"`
+	Args   []string `json:"args"`
+	Stdout string   `json:"stdout"`
+	Err    string   `json:"error,omitempty"`
+}
+
+func (c Command) key() string {
+	return commandKey(c.Name, c.Args)
+}
+
+func commandKey(name string, args []string) string {
+	return strings.Join(append([]string{name}, args...), "\x00")
+}
+
+// recording collects everything detection touches during a capture.
+type recording struct {
+	paths    map[string]sysfs.Access
+	commands []Command
+}
+
+func (r *recording) recordPath(p string, kind sysfs.Access) {
+	// A directory listing or file read says more than a stat or readlink,
+	// so never downgrade an already-recorded path.
+	if prev, ok := r.paths[p]; ok && prev < kind {
+		return
+	}
+	r.paths[p] = kind
+}
+
+func (r *recording) run(name string, args ...string) ([]byte, error) {
+	out, err := exec.Command(name, args...).Output()
+	c := Command{Name: name, Args: args, Stdout: string(out)}
+	if err != nil {
+		c.Err = err.Error()
+	}
+	r.commands = append(r.commands, c)
+	return out, err
+}
+
+// Capture runs every detector against the live system and writes a
+// gzip-compressed tarball of the files and command outputs they read.
+func Capture(w io.Writer, version string) (*Manifest, error) {
+	rec := &recording{paths: make(map[string]sysfs.Access)}
+
+	sysfs.SetRecorder(rec.recordPath)
+	platform.SetRunner(rec.run)
+	runDetectors()
+	sysfs.SetRecorder(nil)
+	platform.SetRunner(nil)
+
+	hostname, _ := os.Hostname()
+	m := &Manifest{
+		Format:   formatV1,
+		Hostname: hostname,
+		Created:  time.Now().Format(time.RFC3339),
+		Version:  version,
+		Commands: len(rec.commands),
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files, err := writeTree(tw, rec.paths)
+	if err != nil {
+		return nil, err
+	}
+	m.Files = files
+
+	if err := writeJSON(tw, commandsName, rec.commands); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(tw, manifestName, m); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return m, gz.Close()
+}
+
+// runDetectors calls everything diagnose and suggest read from the system.
+func runDetectors() {
+	profile.AutoDetect()
+	detect.DetectKernel()
+	detect.DetectCPU()
+	detect.DetectIdle()
+	detect.DetectMemory()
+	detect.DetectNUMA()
+	detect.DetectHugePages()
+	detect.WithSMART(detect.DetectStorage())
+	detect.DetectNetwork()
+	detect.DetectIRQ()
+	detect.DetectPower()
+	detect.DetectServices()
+	detect.DetectGPU()
+	detect.DetectServer()
+}
+
+// writeTree archives the recorded paths under root/, creating parent
+// directories first so extraction never depends on entry order.
+func writeTree(tw *tar.Writer, paths map[string]sysfs.Access) (int, error) {
+	names := make([]string, 0, len(paths))
+	for p := range paths {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	// Anything that has recorded children must be a directory in the
+	// archive, even if it is a symlink on the live system.
+	parents := make(map[string]bool)
+	for _, p := range names {
+		for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+
+	written := make(map[string]bool)
+	mkdir := func(dir string) error {
+		if written[dir] {
+			return nil
+		}
+		written[dir] = true
+		return tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     rootPrefix + dir + "/",
+			Mode:     0755,
+		})
+	}
+	mkdirAll := func(p string) error {
+		var dirs []string
+		for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			dirs = append(dirs, dir)
+		}
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if err := mkdir(dirs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	files := 0
+	for _, p := range names {
+		if err := mkdirAll(p); err != nil {
+			return files, err
+		}
+
+		live := sysfs.Path(p)
+		st, err := os.Stat(live)
+		isDir := parents[p] || (paths[p] != sysfs.AccessLink && err == nil && st.IsDir())
+
+		switch {
+		case isDir:
+			if err := mkdir(p); err != nil {
+				return files, err
+			}
+		case paths[p] == sysfs.AccessLink:
+			target, err := os.Readlink(live)
+			if err != nil {
+				continue
+			}
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeSymlink,
+				Name:     rootPrefix + p,
+				Linkname: target,
+			}); err != nil {
+				return files, err
+			}
+		default:
+			// Unreadable files (write-only knobs, root-only counters) are
+			// kept as empty files so existence checks still succeed.
+			data, _ := os.ReadFile(live)
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     rootPrefix + p,
+				Mode:     0644,
+				Size:     int64(len(data)),
+			}); err != nil {
+				return files, err
+			}
+			if _, err := tw.Write(data); err != nil {
+				return files, err
+			}
+			files++
+		}
+	}
+	return files, nil
+}
+
+func writeJSON(tw *tar.Writer, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(b)),
+	}); err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+// Snapshot is an extracted capture ready to be replayed.
+type Snapshot struct {
+	Manifest Manifest
+	dir      string
+	commands map[string]Command
+}
+
+// Open extracts a snapshot tarball into a temporary directory.
+func Open(file string) (*Snapshot, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a tuner snapshot: %w", file, err)
+	}
+	defer gz.Close()
+
+	dir, err := os.MkdirTemp("", "tuner-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{dir: dir, commands: make(map[string]Command)}
+
+	if err := s.extract(tar.NewReader(gz)); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if s.Manifest.Format != formatV1 {
+		s.Close()
+		return nil, fmt.Errorf("%s: unsupported snapshot format %d", file, s.Manifest.Format)
+	}
+	return s, nil
+}
+
+// extract unpacks the archive into s.dir. Every write goes through an
+// os.Root, no entry is created beneath a symlink, and links may only
+// point inside the captured tree, so a crafted archive cannot reach
+// files outside the snapshot.
+func (s *Snapshot) extract(tr *tar.Reader) error {
+	root, err := os.OpenRoot(s.dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Name {
+		case manifestName:
+			if err := json.NewDecoder(tr).Decode(&s.Manifest); err != nil {
+				return fmt.Errorf("bad manifest: %w", err)
+			}
+			continue
+		case commandsName:
+			var cmds []Command
+			if err := json.NewDecoder(tr).Decode(&cmds); err != nil {
+				return fmt.Errorf("bad command log: %w", err)
+			}
+			for _, c := range cmds {
+				s.commands[c.key()] = c
+			}
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if name != rootPrefix && !strings.HasPrefix(name, rootPrefix+"/") {
+			continue
+		}
+		if strings.Contains(name, "..") {
+			return fmt.Errorf("unsafe path %q", hdr.Name)
+		}
+		// Captured symlinked directories are stored as directories, so
+		// a legitimate archive never places anything beneath a link.
+		if err := noLinkedParent(root, name); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !insideRoot(name, hdr.Linkname) {
+				return fmt.Errorf("unsafe link %q -> %q", hdr.Name, hdr.Linkname)
+			}
+			if err := root.MkdirAll(path.Dir(name), 0755); err != nil {
+				return err
+			}
+			if err := root.Symlink(hdr.Linkname, name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(path.Dir(name), 0755); err != nil {
+				return err
+			}
+			out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// noLinkedParent returns an error if any existing parent directory of
+// name is a symlink.
+func noLinkedParent(root *os.Root, name string) error {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		st, err := root.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if st.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("unsafe path %q: %s is a symlink", name, dir)
+		}
+	}
+	return nil
+}
+
+// insideRoot returns true if a link at name pointing to target stays
+// within the captured tree.
+func insideRoot(name, target string) bool {
+	if target == "" || path.IsAbs(target) {
+		return false
+	}
+	resolved := path.Join(path.Dir(name), target)
+	return resolved == rootPrefix || strings.HasPrefix(resolved, rootPrefix+"/")
+}
+
+// Activate points sysfs reads and diagnostic commands at the snapshot.
+func (s *Snapshot) Activate() {
+	sysfs.SetRoot(filepath.Join(s.dir, rootPrefix))
+	platform.SetRunner(s.run)
+}
+
+// run replays a recorded command. Commands that were never recorded
+// behave as if the tool is not installed.
+func (s *Snapshot) run(name string, args ...string) ([]byte, error) {
+	c, ok := s.commands[commandKey(name, args)]
+	if !ok {
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	if c.Err != "" {
+		return []byte(c.Stdout), fmt.Errorf("%s", c.Err)
+	}
+	return []byte(c.Stdout), nil
+}
+
+// Close restores the live system and removes the extracted files.
+func (s *Snapshot) Close() error {
+	sysfs.SetRoot("")
+	platform.SetRunner(nil)
+	return os.RemoveAll(s.dir)
+}
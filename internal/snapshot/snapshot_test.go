@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func TestCaptureAndReplay(t *testing.T) {
+	fixture := t.TempDir()
+	files := map[string]string{
+		sysfs.VMSwappiness:                "35\n",
+		sysfs.THPEnabled:                  "always madvise [never]\n",
+		"/sys/block/sda/queue/rotational": "1\n",
+		"/sys/block/sda/queue/scheduler":  "mq-deadline [bfq] none\n",
+	}
+	for p, content := range files {
+		full := filepath.Join(fixture, p)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+
+	sysfs.SetRoot(fixture)
+	out := filepath.Join(t.TempDir(), "snap.tar.gz")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Capture(f, "test")
+	f.Close()
+	sysfs.SetRoot("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Files < len(files) {
+		t.Errorf("captured %d files, want at least %d", m.Files, len(files))
+	}
+
+	s, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Activate()
+
+	mem := detect.DetectMemory()
+	if mem.Swappiness != 35 {
+		t.Errorf("replayed swappiness = %d, want 35", mem.Swappiness)
+	}
+	if mem.THPEnabled != "never" {
+		t.Errorf("replayed THP = %q, want never", mem.THPEnabled)
+	}
+
+	storage := detect.DetectStorage()
+	if len(storage.Disks) != 1 || storage.Disks[0].Scheduler != "bfq" {
+		t.Errorf("replayed disks = %+v, want sda with bfq", storage.Disks)
+	}
+
+	if _, err := s.run("definitely-not-recorded"); err == nil {
+		t.Error("unrecorded command should fail")
+	}
+}
+
+func TestOpenRejectsEscapes(t *testing.T) {
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim")
+
+	tests := []struct {
+		name    string
+		entries []tar.Header
+	}{
+		{"absolute-link", []tar.Header{
+			{Typeflag: tar.TypeSymlink, Name: "root/sys/evil", Linkname: outside},
+			{Typeflag: tar.TypeReg, Name: "root/sys/evil/victim"},
+		}},
+		{"relative-link", []tar.Header{
+			{Typeflag: tar.TypeSymlink, Name: "root/sys/evil", Linkname: "../../../../../../../.." + outside},
+			{Typeflag: tar.TypeReg, Name: "root/sys/evil/victim"},
+		}},
+		{"file-under-link", []tar.Header{
+			{Typeflag: tar.TypeDir, Name: "root/sys/block/"},
+			{Typeflag: tar.TypeSymlink, Name: "root/sys/l", Linkname: "block"},
+			{Typeflag: tar.TypeReg, Name: "root/sys/l/victim"},
+		}},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), tt.name+".tar.gz")
+		f, err := os.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		for _, hdr := range tt.entries {
+			hdr.Mode = 0644
+			content := "pwned"
+			if hdr.Typeflag == tar.TypeReg {
+				hdr.Size = int64(len(content))
+			}
+			if err := tw.WriteHeader(&hdr); err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Typeflag == tar.TypeReg {
+				tw.Write([]byte(content))
+			}
+		}
+		tw.Close()
+		gz.Close()
+		f.Close()
+
+		s, err := Open(file)
+		if err == nil {
+			s.Close()
+			t.Errorf("%s: opened a malicious snapshot", tt.name)
+		} else if !strings.Contains(err.Error(), "unsafe") {
+			t.Errorf("%s: error = %v, want an unsafe path or link", tt.name, err)
+		}
+		if _, err := os.Stat(victim); err == nil {
+			t.Fatalf("%s: wrote outside the snapshot", tt.name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sysfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUList parses a kernel CPU list such as "0-3,8,10-11".
+func ParseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("bad CPU list %q", s)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("bad CPU list %q", s)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// ReadCPUList reads a file holding a kernel CPU list.
+func ReadCPUList(path string) ([]int, error) {
+	s, err := ReadString(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCPUList(s)
+}
+
+// ParseCPUMask parses a hex CPU mask such as "00000000,000000f0", as in
+// rps_cpus and xps_cpus: 32-bit words, most significant first.
+func ParseCPUMask(s string) ([]int, error) {
+	words := strings.Split(strings.TrimSpace(s), ",")
+	var cpus []int
+	for i := len(words) - 1; i >= 0; i-- {
+		w, err := strconv.ParseUint(words[i], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad CPU mask %q", s)
+		}
+		base := (len(words) - 1 - i) * 32
+		for bit := 0; bit < 32; bit++ {
+			if w&(1<<bit) != 0 {
+				cpus = append(cpus, base+bit)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// FormatCPUList formats sorted CPU numbers as a kernel CPU list,
+// collapsing runs into ranges.
+func FormatCPUList(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,47 @@
+package sysfs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCPUList(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		cpus []int
+	}{
+		{"0", []int{0}},
+		{"0-3", []int{0, 1, 2, 3}},
+		{"0-1,4,6-7\n", []int{0, 1, 4, 6, 7}},
+		{"", nil},
+	} {
+		got, err := ParseCPUList(tc.in)
+		if err != nil {
+			t.Errorf("ParseCPUList(%q): %v", tc.in, err)
+			continue
+		}
+		if !slices.Equal(got, tc.cpus) {
+			t.Errorf("ParseCPUList(%q) = %v, want %v", tc.in, got, tc.cpus)
+		}
+	}
+	for _, bad := range []string{"a", "3-1", "0-x"} {
+		if _, err := ParseCPUList(bad); err == nil {
+			t.Errorf("ParseCPUList(%q) accepted", bad)
+		}
+	}
+
+	if got := FormatCPUList([]int{0, 1, 2, 3, 8, 10, 11}); got != "0-3,8,10-11" {
+		t.Errorf("FormatCPUList = %q", got)
+	}
+
+	mask, err := ParseCPUMask("00000001,000000f0\n")
+	if err != nil || !slices.Equal(mask, []int{4, 5, 6, 7, 32}) {
+		t.Errorf("ParseCPUMask = %v, %v", mask, err)
+	}
+	if mask, err := ParseCPUMask("0"); err != nil || mask != nil {
+		t.Errorf("ParseCPUMask(0) = %v, %v", mask, err)
+	}
+	if _, err := ParseCPUMask("xyz"); err == nil {
+		t.Error("ParseCPUMask accepted a bad mask")
+	}
+}
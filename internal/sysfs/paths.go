@@ -14,9 +14,20 @@ const (
 	CPUFreqCur       = "/sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq"
 	CPUFreqBaseFreq  = "/sys/devices/system/cpu/cpu0/cpufreq/base_frequency"
 	CPUBoost         = "/sys/devices/system/cpu/cpufreq/boost"
+	CPUFreqPolicies  = "/sys/devices/system/cpu/cpufreq" // policy0, policy1, ...
+	IntelCoreCPUs    = "/sys/devices/cpu_core/cpus" // hybrid P-cores
+	IntelAtomCPUs    = "/sys/devices/cpu_atom/cpus" // hybrid E-cores
 	IntelNoTurbo     = "/sys/devices/system/cpu/intel_pstate/no_turbo"
 	IntelPStateStatus = "/sys/devices/system/cpu/intel_pstate/status"
+	IntelMinPerfPct  = "/sys/devices/system/cpu/intel_pstate/min_perf_pct"
+	IntelMaxPerfPct  = "/sys/devices/system/cpu/intel_pstate/max_perf_pct"
+	AMDPStateStatus  = "/sys/devices/system/cpu/amd_pstate/status"
 	ProcCPUInfo      = "/proc/cpuinfo"
+	CPUOnline        = "/sys/devices/system/cpu/online"
+	CPUIdleBase      = "/sys/devices/system/cpu/cpu0/cpuidle"
+	CPUIdleDriver    = "/sys/devices/system/cpu/cpuidle/current_driver"
+	CPUIdleGovernor  = "/sys/devices/system/cpu/cpuidle/current_governor_ro"
+	CPUDMALatency    = "/dev/cpu_dma_latency" // PM QoS CPU latency limit
 
 	// Memory
 	ProcMemInfo   = "/proc/meminfo"
@@ -26,14 +37,26 @@ const (
 	VMDirtyExpire = "/proc/sys/vm/dirty_expire_centisecs"
 	VMDirtyWriteback = "/proc/sys/vm/dirty_writeback_centisecs"
 	VMVFSCachePressure = "/proc/sys/vm/vfs_cache_pressure"
+	VMDirtyBytes  = "/proc/sys/vm/dirty_bytes"
+	VMDirtyBgBytes = "/proc/sys/vm/dirty_background_bytes"
+	VMNrHugepages = "/proc/sys/vm/nr_hugepages"
+	KSMRun        = "/sys/kernel/mm/ksm/run"
 	THPEnabled    = "/sys/kernel/mm/transparent_hugepage/enabled"
 	THPDefrag     = "/sys/kernel/mm/transparent_hugepage/defrag"
+	KhugepagedBase = "/sys/kernel/mm/transparent_hugepage/khugepaged"
+	HugepagesBase = "/sys/kernel/mm/hugepages" // hugepages-<size>kB/, also under each NUMA node
 	ZswapEnabled  = "/sys/module/zswap/parameters/enabled"
 	ZswapCompressor = "/sys/module/zswap/parameters/compressor"
 	ZswapMaxPool  = "/sys/module/zswap/parameters/max_pool_percent"
+	NodeBase      = "/sys/devices/system/node" // node<n>/cpulist, meminfo, numastat, distance
+	NUMABalancing = "/proc/sys/kernel/numa_balancing"
+	VMZoneReclaim = "/proc/sys/vm/zone_reclaim_mode"
 
 	// Storage
 	BlockBase     = "/sys/block"
+	ProcMounts    = "/proc/mounts"
+	ProcSwaps     = "/proc/swaps"
+	Fstab         = "/etc/fstab"
 
 	// Network
 	NetBase       = "/sys/class/net"
@@ -45,12 +68,19 @@ const (
 	TCPMTUProbing = "/proc/sys/net/ipv4/tcp_mtu_probing"
 	NetCoreBufMax = "/proc/sys/net/core/rmem_max"
 	NetCoreWBufMax = "/proc/sys/net/core/wmem_max"
+	NetCoreBusyPoll = "/proc/sys/net/core/busy_poll"
+	NetCoreBusyRead = "/proc/sys/net/core/busy_read"
+	ProcInterrupts = "/proc/interrupts"
+	ProcIRQBase    = "/proc/irq" // <n>/smp_affinity_list, <n>/<action name>/
 
 	// Server
 	FileMax      = "/proc/sys/fs/file-max"
 	Somaxconn    = "/proc/sys/net/core/somaxconn"
 	ConntrackMax = "/proc/sys/net/netfilter/nf_conntrack_max"
 	PortRange    = "/proc/sys/net/ipv4/ip_local_port_range"
+	PidMax       = "/proc/sys/kernel/pid_max"
+	InotifyMaxWatches   = "/proc/sys/fs/inotify/max_user_watches"
+	InotifyMaxInstances = "/proc/sys/fs/inotify/max_user_instances"
 
 	// Power
 	PowerSupplyBase = "/sys/class/power_supply"
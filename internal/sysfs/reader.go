@@ -8,19 +8,34 @@ import (
 
 // Exists returns true if the path exists on the filesystem.
 func Exists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+	_, err := os.Stat(Path(path))
+	if err != nil {
+		return false
+	}
+	record(path, AccessStat)
+	return true
 }
 
 // ReadString reads a sysfs/procfs file and returns its trimmed content.
 func ReadString(path string) (string, error) {
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(Path(path))
 	if err != nil {
 		return "", err
 	}
+	record(path, AccessFile)
 	return strings.TrimSpace(string(data)), nil
 }
 
+// ReadFile reads a file under the current root unmodified.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(Path(path))
+	if err != nil {
+		return nil, err
+	}
+	record(path, AccessFile)
+	return data, nil
+}
+
 // ReadInt reads a sysfs/procfs file and parses it as an integer.
 func ReadInt(path string) (int, error) {
 	s, err := ReadString(path)
@@ -58,10 +73,11 @@ func ReadBool(path string) (bool, error) {
 
 // ReadLines reads a file and returns non-empty lines.
 func ReadLines(path string) ([]string, error) {
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(Path(path))
 	if err != nil {
 		return nil, err
 	}
+	record(path, AccessFile)
 	var lines []string
 	for _, line := range strings.Split(string(data), "\n") {
 		line = strings.TrimSpace(line)
@@ -0,0 +1,31 @@
+package sysfs
+
+// Access describes how a path was read.
+type Access int
+
+const (
+	AccessFile Access = iota // file contents
+	AccessDir                // directory listing
+	AccessLink               // symlink target
+	AccessStat               // existence check only
+)
+
+// recorder, if set, is called with every path successfully read through
+// this package. Snapshots use it to capture exactly what detection reads.
+var recorder func(path string, kind Access)
+
+// SetRecorder installs fn to observe reads. Pass nil to stop recording.
+func SetRecorder(fn func(path string, kind Access)) {
+	recorder = fn
+}
+
+// Recording returns true while a recorder is installed.
+func Recording() bool {
+	return recorder != nil
+}
+
+func record(path string, kind Access) {
+	if recorder != nil {
+		recorder(path, kind)
+	}
+}
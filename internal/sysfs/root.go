@@ -0,0 +1,58 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// root is the directory all sysfs/procfs paths are resolved against.
+// It is "/" on a live system and a fixture or captured tree otherwise.
+var root = "/"
+
+// SetRoot changes the directory that all path constants are resolved
+// against. An empty root resets to the live filesystem.
+func SetRoot(dir string) {
+	if dir == "" {
+		dir = "/"
+	}
+	root = dir
+}
+
+// Root returns the current filesystem root.
+func Root() string {
+	return root
+}
+
+// IsLive returns true when reading from the running kernel rather than
+// a fixture or captured tree.
+func IsLive() bool {
+	return root == "/"
+}
+
+// Path maps an absolute sysfs/procfs path onto the current root.
+func Path(path string) string {
+	if root == "/" {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// ReadDir lists a directory under the current root.
+func ReadDir(path string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(Path(path))
+	if err != nil {
+		return nil, err
+	}
+	record(path, AccessDir)
+	return entries, nil
+}
+
+// Readlink returns the target of a symlink under the current root.
+func Readlink(path string) (string, error) {
+	target, err := os.Readlink(Path(path))
+	if err != nil {
+		return "", err
+	}
+	record(path, AccessLink)
+	return target, nil
+}
@@ -0,0 +1,58 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetRoot(t *testing.T) {
+	dir := t.TempDir()
+	defer SetRoot("")
+
+	os.MkdirAll(filepath.Join(dir, "proc/sys/vm"), 0755)
+	os.WriteFile(filepath.Join(dir, "proc/sys/vm/swappiness"), []byte("60\n"), 0644)
+
+	SetRoot(dir)
+	if IsLive() {
+		t.Error("IsLive returned true with a fixture root")
+	}
+
+	got, err := ReadInt(VMSwappiness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 60 {
+		t.Errorf("ReadInt = %d, want 60", got)
+	}
+
+	if err := WriteSysctl("vm.swappiness", "10"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "proc/sys/vm/swappiness"))
+	if string(data) != "10" {
+		t.Errorf("WriteSysctl wrote %q, want %q", data, "10")
+	}
+
+	SetRoot("")
+	if Root() != "/" || !IsLive() {
+		t.Errorf("SetRoot(\"\") root = %q, want /", Root())
+	}
+}
+
+func TestReadDirUnderRoot(t *testing.T) {
+	dir := t.TempDir()
+	defer SetRoot("")
+
+	os.MkdirAll(filepath.Join(dir, "sys/block/sda"), 0755)
+	os.MkdirAll(filepath.Join(dir, "sys/block/nvme0n1"), 0755)
+
+	SetRoot(dir)
+	entries, err := ReadDir(BlockBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("ReadDir returned %d entries, want 2", len(entries))
+	}
+}
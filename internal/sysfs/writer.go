@@ -9,7 +9,7 @@ import (
 
 // WriteString writes a string value to a sysfs/procfs path.
 func WriteString(path, value string) error {
-	return os.WriteFile(path, []byte(value), 0644)
+	return os.WriteFile(Path(path), []byte(value), 0644)
 }
 
 // WriteInt writes an integer value to a sysfs/procfs path.
@@ -33,23 +33,37 @@ func sysCtlKeyToPath(key string) string {
 	return strings.ReplaceAll(key, ".", "/")
 }
 
-// WriteAllCPUs writes a value to a per-CPU sysfs attribute for all CPUs.
+// WriteAllCPUs writes a value to a per-CPU cpufreq attribute for all CPUs.
 func WriteAllCPUs(attr, value string) error {
-	entries, err := os.ReadDir(CPUBase)
+	paths, err := EachCPU("cpufreq/" + attr)
 	if err != nil {
 		return err
 	}
 	var lastErr error
+	for _, path := range paths {
+		if err := WriteString(path, value); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// EachCPU returns rel joined to every cpuN directory that has it, such
+// as cpu0/cpufreq/scaling_governor for rel "cpufreq/scaling_governor".
+func EachCPU(rel string) ([]string, error) {
+	entries, err := ReadDir(CPUBase)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
 	for _, e := range entries {
 		name := e.Name()
 		if len(name) > 3 && name[:3] == "cpu" && name[3] >= '0' && name[3] <= '9' {
-			path := fmt.Sprintf("%s/%s/cpufreq/%s", CPUBase, name, attr)
+			path := fmt.Sprintf("%s/%s/%s", CPUBase, name, rel)
 			if Exists(path) {
-				if err := WriteString(path, value); err != nil {
-					lastErr = err
-				}
+				paths = append(paths, path)
 			}
 		}
 	}
-	return lastErr
+	return paths, nil
 }
@@ -1,6 +1,13 @@
 package tune
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/profile"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
@@ -8,34 +15,45 @@ import (
 func computeCPUChanges(v profile.Values) []Change {
 	var changes []Change
 
-	// Governor
-	if cur, err := sysfs.ReadString(sysfs.CPUGovernor); err == nil && cur != v.Governor {
-		target := v.Governor
+	// P-state mode first: switching it re-registers the driver, which
+	// resets the governor and limits of every policy.
+	pstate := detect.DetectPState()
+	mode := targetMode(v, pstate)
+	if mode != "" {
 		changes = append(changes, Change{
 			Subsystem: "cpu",
-			Parameter: "CPU Governor",
-			OldValue:  cur,
-			NewValue:  target,
-			Path:      sysfs.CPUGovernor,
-			ApplyFunc: func() error {
-				return sysfs.WriteAllCPUs("scaling_governor", target)
-			},
+			Parameter: "P-state Mode",
+			OldValue:  pstate.Status,
+			NewValue:  mode,
+			Writes:    []Write{{Path: pstate.StatusPath, Value: mode, Old: pstate.Status}},
 		})
 	}
 
-	// EPP
-	if sysfs.Exists(sysfs.CPUEPP) {
-		if cur, err := sysfs.ReadString(sysfs.CPUEPP); err == nil && cur != v.EPP {
-			target := v.EPP
+	// Governor, EPP and frequency limits, per core class
+	policies := detect.DetectCPUPolicies()
+	for _, class := range detect.CoreClasses(policies) {
+		changes = append(changes, computeCoreChanges(class, detect.PoliciesOf(policies, class), v.Cores(class), pstate, mode)...)
+	}
+
+	// intel_pstate performance limits, max first as for frequencies
+	for _, pct := range []struct {
+		param, path string
+		value       profile.OptInt
+	}{
+		{"Max Perf Percent", sysfs.IntelMaxPerfPct, v.MaxPerfPct},
+		{"Min Perf Percent", sysfs.IntelMinPerfPct, v.MinPerfPct},
+	} {
+		if !pct.value.Set {
+			continue
+		}
+		cur, err := sysfs.ReadString(pct.path)
+		if want := strconv.Itoa(pct.value.Value); err == nil && cur != want {
 			changes = append(changes, Change{
 				Subsystem: "cpu",
-				Parameter: "Energy Perf Pref",
-				OldValue:  cur,
-				NewValue:  target,
-				Path:      sysfs.CPUEPP,
-				ApplyFunc: func() error {
-					return sysfs.WriteAllCPUs("energy_performance_preference", target)
-				},
+				Parameter: pct.param,
+				OldValue:  cur + "%",
+				NewValue:  want + "%",
+				Writes:    []Write{{Path: pct.path, Value: want, Old: cur}},
 			})
 		}
 	}
@@ -43,13 +61,14 @@ func computeCPUChanges(v profile.Values) []Change {
 	// Turbo boost
 	type turboSource struct {
 		path  string
+		raw   string
 		curOn bool
 	}
 	var turbo *turboSource
 	if val, err := sysfs.ReadInt(sysfs.IntelNoTurbo); err == nil {
-		turbo = &turboSource{path: sysfs.IntelNoTurbo, curOn: val == 0}
+		turbo = &turboSource{path: sysfs.IntelNoTurbo, raw: fmt.Sprintf("%d", val), curOn: val == 0}
 	} else if val, err := sysfs.ReadInt(sysfs.CPUBoost); err == nil {
-		turbo = &turboSource{path: sysfs.CPUBoost, curOn: val == 1}
+		turbo = &turboSource{path: sysfs.CPUBoost, raw: fmt.Sprintf("%d", val), curOn: val == 1}
 	}
 
 	if turbo != nil && turbo.curOn != v.TurboOn {
@@ -66,22 +85,228 @@ func computeCPUChanges(v profile.Values) []Change {
 				newVal = "1"
 			}
 		}
-		path := turbo.path
 		changes = append(changes, Change{
 			Subsystem: "cpu",
 			Parameter: "Turbo Boost",
 			OldValue:  boolToOnOff(turbo.curOn),
 			NewValue:  boolToOnOff(v.TurboOn),
-			Path:      path,
-			ApplyFunc: func() error {
-				return sysfs.WriteString(path, newVal)
-			},
+			Writes:    []Write{{Path: turbo.path, Value: newVal, Old: turbo.raw}},
 		})
 	}
 
+	// C-states deeper than the allowed exit latency
+	if v.IdleMaxLatency.Set {
+		changes = append(changes, computeIdleChanges(v.IdleMaxLatency.Value)...)
+	}
+
+	return changes
+}
+
+// computeIdleChanges disables every cpuidle state whose exit latency
+// exceeds maxLatency microseconds, on each CPU that has it enabled.
+// States are matched by name, as hybrid CPUs may number them differently.
+func computeIdleChanges(maxLatency int) []Change {
+	var changes []Change
+	byName := make(map[string]int)
+
+	dirs, _ := sysfs.EachCPU("cpuidle")
+	for _, dir := range dirs {
+		for n := 0; ; n++ {
+			state := fmt.Sprintf("%s/state%d", dir, n)
+			name, err := sysfs.ReadString(state + "/name")
+			if err != nil {
+				break
+			}
+			latency, err := sysfs.ReadInt(state + "/latency")
+			if err != nil || latency <= maxLatency {
+				continue
+			}
+			disabled, err := sysfs.ReadString(state + "/disable")
+			if err != nil || disabled == "1" {
+				continue
+			}
+			i, ok := byName[name]
+			if !ok {
+				i = len(changes)
+				byName[name] = i
+				changes = append(changes, Change{
+					Subsystem: "cpu",
+					Parameter: fmt.Sprintf("C-state %s (%dus)", name, latency),
+					OldValue:  "enabled",
+					NewValue:  "disabled",
+				})
+			}
+			changes[i].Writes = append(changes[i].Writes, Write{Path: state + "/disable", Value: "1", Old: disabled})
+		}
+	}
+
 	return changes
 }
 
+// CPUProblems explains the CPU values this machine cannot take as the
+// profile gives them. computeCPUChanges clamps frequency limits to the
+// cores' cpuinfo range and skips the rest.
+func CPUProblems(v profile.Values) []string {
+	var problems []string
+	pstate := detect.DetectPState()
+	if v.PStateMode != "" {
+		switch {
+		case pstate.Driver == "":
+			problems = append(problems, "pstate_mode is set, but neither intel_pstate nor amd-pstate drives this CPU")
+		case !slices.Contains(pstate.Modes(), v.PStateMode):
+			problems = append(problems, fmt.Sprintf("pstate_mode %q is not a %s mode (%s)",
+				v.PStateMode, pstate.Driver, strings.Join(pstate.Modes(), ", ")))
+		}
+	}
+	if (v.MinPerfPct.Set || v.MaxPerfPct.Set) && !pstate.PerfPctKnown {
+		problems = append(problems, "min_perf_pct and max_perf_pct need intel_pstate")
+	}
+
+	mode := targetMode(v, pstate)
+	effective := cmp.Or(mode, pstate.Status)
+	policies := detect.DetectCPUPolicies()
+	for _, class := range detect.CoreClasses(policies) {
+		group := detect.PoliciesOf(policies, class)
+		c := v.Cores(class)
+		p := group[0]
+		cores := "this CPU"
+		if class != "" {
+			cores = "the " + class + " cores"
+		}
+
+		if c.MinFreqMHz.Set && c.MaxFreqMHz.Set && c.MinFreqMHz.Value > c.MaxFreqMHz.Value {
+			problems = append(problems, fmt.Sprintf("min_freq_mhz %d is above max_freq_mhz %d for %s",
+				c.MinFreqMHz.Value, c.MaxFreqMHz.Value, cores))
+		}
+		for _, f := range []struct {
+			key string
+			mhz profile.OptInt
+		}{{"min_freq_mhz", c.MinFreqMHz}, {"max_freq_mhz", c.MaxFreqMHz}} {
+			if f.mhz.Set && p.HWMaxFreqMHz > 0 && (f.mhz.Value < p.HWMinFreqMHz || f.mhz.Value > p.HWMaxFreqMHz) {
+				problems = append(problems, fmt.Sprintf("%s %d is outside the %d-%d MHz range of %s; using %d",
+					f.key, f.mhz.Value, p.HWMinFreqMHz, p.HWMaxFreqMHz, cores, p.ClampFreq(f.mhz.Value)/1000))
+			}
+		}
+
+		if c.Governor != "" && p.Governor != "" && !governorOK(p, pstate, mode, c.Governor) {
+			msg := fmt.Sprintf("governor %s is not available on %s", c.Governor, cores)
+			if pstate.Driver != "" && effective == "active" {
+				msg = fmt.Sprintf("governor %s is not available with %s in active mode; set pstate_mode = \"passive\" to use it",
+					c.Governor, pstate.Driver)
+			}
+			if !slices.Contains(problems, msg) {
+				problems = append(problems, msg)
+			}
+		}
+	}
+	return problems
+}
+
+// targetMode returns the P-state mode to switch to, or "" if the profile
+// sets none, the driver is already in it or cannot take it.
+func targetMode(v profile.Values, pstate detect.PStateInfo) string {
+	if v.PStateMode == "" || v.PStateMode == pstate.Status || !slices.Contains(pstate.Modes(), v.PStateMode) {
+		return ""
+	}
+	return v.PStateMode
+}
+
+// GovernorAvailable returns true if the policy offers gov once the
+// profile's P-state mode is in effect.
+func GovernorAvailable(v profile.Values, pstate detect.PStateInfo, p detect.CPUPolicy, gov string) bool {
+	return governorOK(p, pstate, targetMode(v, pstate), gov)
+}
+
+// governorOK returns true if the governor is available once the driver
+// is in mode, the current mode when mode is "".
+func governorOK(p detect.CPUPolicy, pstate detect.PStateInfo, mode, gov string) bool {
+	avail := p.AvailGovs
+	if mode != "" {
+		avail = pstate.Governors(mode)
+	}
+	return len(avail) == 0 || slices.Contains(avail, gov)
+}
+
+// computeCoreChanges sets the governor, then EPP (intel_pstate rejects
+// most EPP values under the performance governor), then the frequency
+// limits of one class of cores. mode is the P-state mode being switched
+// to, if any. The switch resets every policy to driver defaults, so
+// while switching all values are written, even those that match now.
+// Governors the mode does not offer are skipped, as is EPP when leaving
+// active mode, which removes it; entering active mode creates the EPP
+// files, so EPP follows on the next run.
+func computeCoreChanges(class string, group []detect.CPUPolicy, c profile.CoreValues, pstate detect.PStateInfo, mode string) []Change {
+	var changes []Change
+	add := func(param, attr string, value func(detect.CPUPolicy) string) {
+		writes, olds := policyWrites(group, attr, value, mode != "")
+		if len(writes) == 0 {
+			return
+		}
+		if class != "" {
+			param = fmt.Sprintf("%s (%s cores)", param, class)
+		}
+		newValue := writes[0].Value
+		if strings.HasSuffix(attr, "_freq") {
+			for i := range olds {
+				olds[i] = khzToMHz(olds[i])
+			}
+			newValue = khzToMHz(newValue)
+		}
+		changes = append(changes, Change{
+			Subsystem: "cpu",
+			Parameter: param,
+			OldValue:  strings.Join(olds, ", "),
+			NewValue:  newValue,
+			Writes:    writes,
+		})
+	}
+
+	if c.Governor != "" && governorOK(group[0], pstate, mode, c.Governor) {
+		add("CPU Governor", "scaling_governor", func(detect.CPUPolicy) string { return c.Governor })
+	}
+	if c.EPP != "" && (mode == "" || mode == "active") {
+		add("Energy Perf Pref", "energy_performance_preference", func(detect.CPUPolicy) string { return c.EPP })
+	}
+	// Max before min suits raising both; since freq QoS (5.4) the kernel
+	// takes either order.
+	if c.MaxFreqMHz.Set {
+		add("Max Frequency", "scaling_max_freq", func(p detect.CPUPolicy) string { return strconv.Itoa(p.ClampFreq(c.MaxFreqMHz.Value)) })
+	}
+	if c.MinFreqMHz.Set {
+		add("Min Frequency", "scaling_min_freq", func(p detect.CPUPolicy) string { return strconv.Itoa(p.ClampFreq(c.MinFreqMHz.Value)) })
+	}
+	return changes
+}
+
+func khzToMHz(khz string) string {
+	n, err := strconv.Atoi(khz)
+	if err != nil {
+		return khz
+	}
+	return fmt.Sprintf("%d MHz", n/1000)
+}
+
+// policyWrites writes value to attr in every policy that has it and
+// differs, or in every policy with force, recording each policy's current
+// value so reset restores them one by one. It also returns the distinct
+// current values it replaces.
+func policyWrites(group []detect.CPUPolicy, attr string, value func(detect.CPUPolicy) string, force bool) ([]Write, []string) {
+	var writes []Write
+	var olds []string
+	for _, p := range group {
+		path := p.Dir + "/" + attr
+		old, err := sysfs.ReadString(path)
+		if err != nil || (old == value(p) && !force) {
+			continue
+		}
+		writes = append(writes, Write{Path: path, Value: value(p), Old: old})
+		if !slices.Contains(olds, old) {
+			olds = append(olds, old)
+		}
+	}
+	return writes, olds
+}
+
 func boolToOnOff(b bool) string {
 	if b {
 		return "on"
@@ -2,25 +2,48 @@ package tune
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/fatih/color"
 	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
 )
 
-// Change represents a single tuning change.
+// Change represents a single tuning change. OldValue and NewValue are
+// for display; Writes lists the exact file writes that make the change.
 type Change struct {
-	Subsystem   string
-	Parameter   string
-	OldValue    string
-	NewValue    string
-	Path        string // sysfs/procfs path (for backup)
-	ApplyFunc   func() error
+	Subsystem string
+	Parameter string
+	OldValue  string
+	NewValue  string
+	Writes    []Write
+}
+
+// Write is one sysfs/procfs write, performed in order by Apply.
+type Write struct {
+	Path  string
+	Value string
+	Old   string // value read before the write, restored by reset
+
+	// RestorePath, if set, is where Old is written back instead of
+	// Path. Used when the kernel couples two files, such as
+	// vm.dirty_bytes and vm.dirty_ratio.
+	RestorePath string
+}
+
+// Restore returns the path and value that undo the write.
+func (w Write) Restore() (path, value string) {
+	if w.RestorePath != "" {
+		return w.RestorePath, w.Old
+	}
+	return w.Path, w.Old
 }
 
 // Engine computes and applies tuning changes.
 type Engine struct {
 	Profile profile.Profile
+	Quiet   bool // suppress warnings; the daemon computes changes every interval
 }
 
 // NewEngine creates a tuning engine for the given profile.
@@ -32,53 +55,194 @@ func NewEngine(p profile.Profile) *Engine {
 func (e *Engine) ComputeChanges() []Change {
 	var changes []Change
 
-	changes = append(changes, computeCPUChanges(e.Profile.Values)...)
+	// TLP owns governor, EPP and turbo when it is enabled (even if not
+	// currently active), including the AC/battery switch.
+	// Warnings go to stderr, so machine-readable output on stdout stays
+	// parseable.
+	yellow := color.New(color.FgYellow)
+	if e.TLPOwnsCPU() {
+		if !e.Quiet {
+			yellow.Fprintln(os.Stderr, "Warning: TLP is enabled. Skipping power-related tuning.")
+		}
+	} else {
+		if !e.Quiet {
+			for _, p := range CPUProblems(e.Profile.Values) {
+				yellow.Fprintln(os.Stderr, "Warning: "+p)
+			}
+		}
+		changes = append(changes, computeCPUChanges(e.Profile.Values)...)
+	}
+	if !e.Quiet {
+		for _, p := range HugepageProblems(e.Profile.Values) {
+			yellow.Fprintln(os.Stderr, "Warning: "+p)
+		}
+	}
 	changes = append(changes, computeMemoryChanges(e.Profile.Values)...)
 	changes = append(changes, computeStorageChanges(e.Profile.Values)...)
 	changes = append(changes, computeNetworkChanges(e.Profile.Values)...)
-
-	// Skip power tuning if TLP is enabled (even if not currently active)
-	powerInfo := detect.DetectPower()
-	if e.Profile.Values.SkipIfTLP && powerInfo.TLP.Enabled {
-		yellow := color.New(color.FgYellow)
-		yellow.Println("Warning: TLP is enabled. Skipping power-related tuning.")
+	if e.Profile.Values.IRQAffinity != "" {
+		// irqbalance rewrites every IRQ's affinity every few seconds.
+		if irq := detect.DetectIRQ(); irq.IRQBalance.Active || irq.IRQBalance.Enabled {
+			if !e.Quiet {
+				yellow.Fprintln(os.Stderr, "Warning: irq_affinity is set but irqbalance is running or enabled and would move the IRQs back. Skipping IRQ affinity; run: sudo systemctl disable --now irqbalance")
+			}
+		} else {
+			changes = append(changes, computeIRQChanges(irq.NICs)...)
+		}
 	}
+	changes = append(changes, computeKernelChanges(e.Profile.Values)...)
 
 	return changes
 }
 
-// Apply executes all computed changes. Returns (success, failed) counts.
-func (e *Engine) Apply(changes []Change, auto bool) (int, int) {
-	success, failed := 0, 0
+// TLPOwnsCPU returns true if the profile defers CPU power settings to
+// TLP and TLP is enabled.
+func (e *Engine) TLPOwnsCPU() bool {
+	return e.Profile.Values.SkipIfTLP && detect.DetectPower().TLP.Enabled
+}
+
+// Result reports what Apply did.
+type Result struct {
+	Applied    []Change  // changes fully written, in order
+	Failed     []Failure // changes that could not be written
+	RolledBack bool      // writes were undone after a failure
+	Reverted   []Write   // writes undone, in the order they were undone
+	RevertErrs []error   // restores that themselves failed
+}
+
+// Failure is a change whose writes did not all succeed.
+type Failure struct {
+	Change Change
+	Err    error
+}
+
+// Apply executes the changes in order. The old value of every target
+// was read when the changes were computed; with rollback, Apply first
+// checks each path still exists so nothing is written for a stale plan.
+// With rollback, the first failure stops the run and every write made so
+// far, including earlier writes of the failing change, is restored in
+// reverse order. Without rollback, failures are recorded and the
+// remaining changes still run.
+func (e *Engine) Apply(changes []Change, auto, rollback bool) Result {
+	var res Result
+
+	if rollback {
+		if f := Check(changes); f != nil {
+			res.Failed = append(res.Failed, *f)
+			if !auto {
+				color.Red("  %s: %v", f.Change.Parameter, f.Err)
+			}
+			return res
+		}
+	}
 
+	var done []Write
 	for _, c := range changes {
 		if !auto {
 			fmt.Printf("  %s: %s → %s ... ", c.Parameter, c.OldValue, c.NewValue)
 		}
 
-		err := c.ApplyFunc()
+		written, err := c.apply()
+		done = append(done, written...)
 		if err != nil {
-			failed++
+			res.Failed = append(res.Failed, Failure{Change: c, Err: err})
 			if !auto {
 				color.Red("FAILED (%v)", err)
 			}
-		} else {
-			success++
-			if !auto {
-				color.Green("OK")
+			if rollback {
+				res.RolledBack = true
+				res.Reverted, res.RevertErrs = revert(done)
+				return res
 			}
+			continue
+		}
+
+		res.Applied = append(res.Applied, c)
+		if !auto {
+			color.Green("OK")
+		}
+	}
+
+	return res
+}
+
+// Check returns the first change with a path that no longer exists, or
+// nil if every change can be written.
+func Check(changes []Change) *Failure {
+	for _, c := range changes {
+		if err := c.check(); err != nil {
+			return &Failure{Change: c, Err: err}
 		}
 	}
+	return nil
+}
 
-	return success, failed
+// check confirms every path the change writes still exists.
+func (c Change) check() error {
+	for _, w := range c.Writes {
+		if !sysfs.Exists(w.Path) {
+			return fmt.Errorf("%s: no longer exists", w.Path)
+		}
+	}
+	return nil
 }
 
-// Backup returns a map of path -> original value for all changes.
+// revert restores writes newest first and returns the ones restored.
+func revert(done []Write) ([]Write, []error) {
+	var reverted []Write
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		path, old := done[i].Restore()
+		if err := sysfs.WriteString(path, old); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s: %w", path, err))
+			continue
+		}
+		reverted = append(reverted, done[i])
+	}
+	return reverted, errs
+}
+
+// apply performs every write of the change, stopping at the first
+// error. It returns the writes that succeeded.
+func (c Change) apply() ([]Write, error) {
+	for i, w := range c.Writes {
+		if err := sysfs.WriteString(w.Path, w.Value); err != nil {
+			return c.Writes[:i], fmt.Errorf("%s: %w", w.Path, err)
+		}
+	}
+	return c.Writes, nil
+}
+
+// appendOptInt adds a change moving path to want when want is set, the
+// path exists and its value differs.
+func appendOptInt(changes []Change, subsystem, param, path string, want profile.OptInt) []Change {
+	if !want.Set {
+		return changes
+	}
+	cur, err := sysfs.ReadInt(path)
+	if err != nil || cur == want.Value {
+		return changes
+	}
+	target := want.Value
+	return append(changes, Change{
+		Subsystem: subsystem,
+		Parameter: param,
+		OldValue:  fmt.Sprintf("%d", cur),
+		NewValue:  fmt.Sprintf("%d", target),
+		Writes:    []Write{{Path: path, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
+	})
+}
+
+// Backup returns a map of path -> original value for all changes. When
+// several writes touch the same path the first, oldest value wins.
 func Backup(changes []Change) map[string]string {
 	backup := make(map[string]string)
 	for _, c := range changes {
-		if c.Path != "" {
-			backup[c.Path] = c.OldValue
+		for _, w := range c.Writes {
+			path, old := w.Restore()
+			if _, ok := backup[path]; !ok {
+				backup[path] = old
+			}
 		}
 	}
 	return backup
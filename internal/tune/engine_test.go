@@ -0,0 +1,531 @@
+package tune
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// writeFixture creates a file under dir with the given content.
+func writeFixture(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryChangesAgainstFixture(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, sysfs.VMSwappiness, "60\n")
+	writeFixture(t, dir, sysfs.VMDirtyRatio, "5\n")
+	writeFixture(t, dir, sysfs.THPEnabled, "always [madvise] never\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	changes := computeMemoryChanges(v)
+
+	want := map[string]string{
+		"Swappiness": "10",
+		"THP":        "always",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for _, c := range changes {
+		if want[c.Parameter] != c.NewValue {
+			t.Errorf("%s → %q, want %q", c.Parameter, c.NewValue, want[c.Parameter])
+		}
+	}
+
+	e := NewEngine(profile.ForType(profile.Server))
+	res := e.Apply(changes, true, true)
+	if len(res.Applied) != 2 || len(res.Failed) != 0 {
+		t.Errorf("Apply = (%d, %d), want (2, 0)", len(res.Applied), len(res.Failed))
+	}
+	if got, _ := sysfs.ReadInt(sysfs.VMSwappiness); got != 10 {
+		t.Errorf("swappiness after apply = %d, want 10", got)
+	}
+	if rest := computeMemoryChanges(v); len(rest) != 0 {
+		t.Errorf("expected no changes after apply, got %+v", rest)
+	}
+}
+
+func TestWorkloadChangesAgainstFixture(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, sysfs.VMDirtyRatio, "20\n")
+	writeFixture(t, dir, sysfs.VMDirtyBytes, "0\n")
+	writeFixture(t, dir, sysfs.Somaxconn, "4096\n")
+	writeFixture(t, dir, sysfs.PidMax, "32768\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	// Unset optional values never produce changes.
+	if got := computeKernelChanges(profile.ServerValues()); len(got) != 0 {
+		t.Errorf("server values changed kernel limits: %+v", got)
+	}
+
+	v := profile.ServerValues()
+	profile.Database.Apply(&v)
+	var dirty *Change
+	for _, c := range computeMemoryChanges(v) {
+		if c.Parameter == "Dirty Bytes" {
+			dirty = &c
+		}
+	}
+	if dirty == nil {
+		t.Fatal("no Dirty Bytes change for the database workload")
+	}
+	// Switching from ratio to bytes backs up the ratio.
+	if path, old := dirty.Writes[0].Restore(); path != sysfs.VMDirtyRatio || old != "20" {
+		t.Errorf("Dirty Bytes restore = %s=%s, want %s=20", path, old, sysfs.VMDirtyRatio)
+	}
+
+	net := computeNetworkChanges(v)
+	if len(net) != 1 || net[0].Parameter != "Somaxconn" || net[0].NewValue != "65535" {
+		t.Errorf("network changes = %+v, want Somaxconn → 65535", net)
+	}
+}
+
+func TestPerCPUWrites(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, sysfs.CPUBase+"/cpu0/cpufreq/scaling_governor", "powersave\n")
+	writeFixture(t, dir, sysfs.CPUBase+"/cpu1/cpufreq/scaling_governor", "schedutil\n")
+	writeFixture(t, dir, sysfs.CPUBase+"/cpufreq/boost", "1\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.TurboOn = false
+	changes := computeCPUChanges(v)
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want governor and turbo: %+v", len(changes), changes)
+	}
+
+	gov := changes[0]
+	if len(gov.Writes) != 2 {
+		t.Fatalf("governor writes = %+v, want one per CPU", gov.Writes)
+	}
+	backup := Backup(changes)
+	for path, want := range map[string]string{
+		sysfs.CPUBase + "/cpu0/cpufreq/scaling_governor": "powersave",
+		sysfs.CPUBase + "/cpu1/cpufreq/scaling_governor": "schedutil",
+		sysfs.CPUBoost: "1", // raw value, not "on"
+	} {
+		if backup[path] != want {
+			t.Errorf("backup[%s] = %q, want %q", path, backup[path], want)
+		}
+	}
+
+	e := NewEngine(profile.ForType(profile.Server))
+	if res := e.Apply(changes, true, true); len(res.Applied) != 2 || len(res.Failed) != 0 {
+		t.Errorf("Apply = (%d, %d), want (2, 0)", len(res.Applied), len(res.Failed))
+	}
+	if got, _ := sysfs.ReadString(sysfs.CPUBase + "/cpu1/cpufreq/scaling_governor"); got != "performance" {
+		t.Errorf("cpu1 governor = %q, want performance", got)
+	}
+}
+
+func TestCoreChanges(t *testing.T) {
+	dir := t.TempDir()
+	for policy, attrs := range map[string][]string{
+		"policy0": {"0", "performance", "5400000", "5400000"},
+		"policy1": {"1", "performance", "5400000", "5400000"},
+		"policy2": {"2", "performance", "4300000", "4300000"},
+		"policy3": {"3", "powersave", "4300000", "3000000"},
+	} {
+		base := sysfs.CPUFreqPolicies + "/" + policy
+		writeFixture(t, dir, base+"/related_cpus", attrs[0])
+		writeFixture(t, dir, base+"/scaling_governor", attrs[1])
+		writeFixture(t, dir, base+"/cpuinfo_max_freq", attrs[2])
+		writeFixture(t, dir, base+"/scaling_max_freq", attrs[3])
+		writeFixture(t, dir, base+"/cpuinfo_min_freq", "800000")
+	}
+	writeFixture(t, dir, sysfs.IntelAtomCPUs, "2-3")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.CPUEfficiency = profile.CoreValues{Governor: "powersave", MaxFreqMHz: profile.Int(9000)}
+	var got []string
+	for _, c := range computeCPUChanges(v) {
+		got = append(got, fmt.Sprintf("%s: %s → %s (%d writes)", c.Parameter, c.OldValue, c.NewValue, len(c.Writes)))
+	}
+	want := []string{
+		// P-cores already run the profile-wide governor; one E-core
+		// still has to switch. Max frequency is capped at cpuinfo_max_freq.
+		"CPU Governor (efficiency cores): performance → powersave (1 writes)",
+		"Max Frequency (efficiency cores): 3000 MHz → 4300 MHz (1 writes)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPStateChanges(t *testing.T) {
+	dir := t.TempDir()
+	base := sysfs.CPUFreqPolicies + "/policy0"
+	writeFixture(t, dir, base+"/scaling_governor", "powersave")
+	writeFixture(t, dir, base+"/scaling_available_governors", "performance powersave")
+	writeFixture(t, dir, base+"/energy_performance_preference", "balance_power")
+	writeFixture(t, dir, base+"/cpuinfo_min_freq", "400000")
+	writeFixture(t, dir, base+"/cpuinfo_max_freq", "4700000")
+	writeFixture(t, dir, base+"/scaling_max_freq", "4700000")
+	writeFixture(t, dir, sysfs.IntelPStateStatus, "active")
+	writeFixture(t, dir, sysfs.IntelMinPerfPct, "9")
+	writeFixture(t, dir, sysfs.IntelMaxPerfPct, "100")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.LaptopValues(profile.OnAC) // schedutil
+	v.MaxFreqMHz = profile.Int(9999)
+
+	// Active mode has no schedutil; the governor is skipped, with a hint.
+	problems := strings.Join(CPUProblems(v), "\n")
+	for _, want := range []string{
+		`governor schedutil is not available with intel_pstate in active mode; set pstate_mode = "passive"`,
+		"max_freq_mhz 9999 is outside the 400-4700 MHz range of this CPU; using 4700",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems missing %q:\n%s", want, problems)
+		}
+	}
+	for _, c := range computeCPUChanges(v) {
+		if c.Parameter == "CPU Governor" {
+			t.Errorf("governor change %+v in active mode", c)
+		}
+	}
+
+	// Passive mode goes first, brings schedutil and drops EPP. The max
+	// frequency is already at the clamped 4700 MHz, but the switch resets
+	// it, so it is written again.
+	v.PStateMode = "passive"
+	v.MaxPerfPct = profile.Int(80)
+	var got []string
+	for _, c := range computeCPUChanges(v) {
+		got = append(got, fmt.Sprintf("%s: %s → %s", c.Parameter, c.OldValue, c.NewValue))
+	}
+	want := []string{
+		"P-state Mode: active → passive",
+		"CPU Governor: powersave → schedutil",
+		"Max Frequency: 4700 MHz → 4700 MHz",
+		"Max Perf Percent: 100% → 80%",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Without a switch, matching values are left alone.
+	writeFixture(t, dir, sysfs.IntelPStateStatus, "passive")
+	writeFixture(t, dir, base+"/scaling_governor", "schedutil")
+	for _, c := range computeCPUChanges(v) {
+		if strings.HasPrefix(c.Parameter, "Max Frequency") || c.Parameter == "CPU Governor" {
+			t.Errorf("unexpected change %+v after the switch", c)
+		}
+	}
+}
+
+func TestIdleChanges(t *testing.T) {
+	dir := t.TempDir()
+	// cpu1 numbers C6 differently; cpu2 already has it disabled.
+	for state, attrs := range map[string][]string{
+		"cpu0/cpuidle/state1": {"C1", "2", "0"},
+		"cpu0/cpuidle/state2": {"C6", "170", "0"},
+		"cpu1/cpuidle/state1": {"C6", "170", "0"},
+		"cpu2/cpuidle/state1": {"C6", "170", "1"},
+	} {
+		base := sysfs.CPUBase + "/" + state
+		writeFixture(t, dir, base+"/name", attrs[0])
+		writeFixture(t, dir, base+"/latency", attrs[1])
+		writeFixture(t, dir, base+"/disable", attrs[2])
+	}
+	for _, cpu := range []string{"cpu0", "cpu1", "cpu2"} {
+		writeFixture(t, dir, sysfs.CPUBase+"/"+cpu+"/cpuidle/state0/name", "POLL")
+		writeFixture(t, dir, sysfs.CPUBase+"/"+cpu+"/cpuidle/state0/latency", "0")
+		writeFixture(t, dir, sysfs.CPUBase+"/"+cpu+"/cpuidle/state0/disable", "0")
+	}
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	changes := computeIdleChanges(10)
+	if len(changes) != 1 || changes[0].Parameter != "C-state C6 (170us)" {
+		t.Fatalf("changes = %+v, want C6 only", changes)
+	}
+	var paths []string
+	for _, w := range changes[0].Writes {
+		paths = append(paths, strings.TrimPrefix(w.Path, sysfs.CPUBase+"/"))
+	}
+	if got := strings.Join(paths, " "); got != "cpu0/cpuidle/state2/disable cpu1/cpuidle/state1/disable" {
+		t.Errorf("writes = %s", got)
+	}
+}
+
+func TestIRQChanges(t *testing.T) {
+	sysfs.SetRoot(t.TempDir())
+	defer sysfs.SetRoot("")
+
+	nics := []detect.NICQueues{{
+		Name:      "eth0",
+		LocalCPUs: []int{0, 1},
+		IRQs: []detect.QueueIRQ{
+			{IRQ: 25, Affinity: []int{0}},
+			{IRQ: 26, Affinity: []int{0, 1, 2, 3}},
+		},
+	}}
+	changes := computeIRQChanges(nics)
+	if len(changes) != 1 {
+		t.Fatalf("changes = %+v, want one for eth0", changes)
+	}
+	c := changes[0]
+	// IRQ 25 is already where it belongs.
+	if len(c.Writes) != 1 || c.Writes[0].Path != sysfs.ProcIRQBase+"/26/smp_affinity_list" ||
+		c.Writes[0].Value != "1" || c.Writes[0].Old != "0-3" {
+		t.Errorf("writes = %+v", c.Writes)
+	}
+	if c.OldValue != "CPUs 0-3" || c.NewValue != "2 queues over CPUs 0-1" {
+		t.Errorf("change shows %s → %s", c.OldValue, c.NewValue)
+	}
+
+	nics[0].IRQs[1].Affinity = []int{1}
+	if changes := computeIRQChanges(nics); len(changes) != 0 {
+		t.Errorf("pinned IRQs changed again: %+v", changes)
+	}
+}
+
+func TestHugepageChanges(t *testing.T) {
+	dir := t.TempDir()
+	system := detect.HugePagePoolDir(2048, -1) + "/nr_hugepages"
+	node1 := detect.HugePagePoolDir(1048576, 1) + "/nr_hugepages"
+	writeFixture(t, dir, system, "0\n")
+	writeFixture(t, dir, node1, "0\n")
+	writeFixture(t, dir, sysfs.ProcMemInfo, "MemTotal:       32768000 kB\nHugepagesize:       2048 kB\n")
+	writeFixture(t, dir, sysfs.VMNrHugepages, "0\n")
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.Values{HugepagePools: []profile.HugepagePool{
+		{SizeKB: 1048576, Count: 4, Node: profile.Int(1)},
+		{SizeKB: 2048, Count: 1024},
+		{SizeKB: 32768, Count: 8},
+	}}
+	if problems := HugepageProblems(v); len(problems) != 1 || !strings.Contains(problems[0], "32768 kB pages") {
+		t.Errorf("problems = %v, want the missing 32 MB pool", problems)
+	}
+	changes := computeHugepageChanges(v.HugepagePools)
+	if len(changes) != 2 || changes[0].Writes[0].Path != node1 || changes[1].Writes[0].Path != system {
+		t.Fatalf("changes = %+v, want node1 and the system-wide pool", changes)
+	}
+
+	// The 2048 kB pool is the default size, so it replaces nr_hugepages.
+	v.Hugepages = profile.Int(64)
+	if problems := HugepageProblems(v); len(problems) != 2 || !strings.Contains(problems[0], "replace nr_hugepages") {
+		t.Errorf("problems = %v, want nr_hugepages reported as replaced", problems)
+	}
+	for _, c := range computeMemoryChanges(v) {
+		if c.Writes[0].Path == sysfs.VMNrHugepages {
+			t.Errorf("vm.nr_hugepages written next to a default-size pool: %+v", c)
+		}
+	}
+
+	// The kernel found room for only part of the system-wide pool.
+	writeFixture(t, dir, system, "700\n")
+	writeFixture(t, dir, node1, "4\n")
+	short := HugepageShortfalls(changes)
+	if len(short) != 1 || short[0].Path != system || short[0].String() != "Huge Pages 2048 kB pages: the kernel reserved 700 of 1024 pages" {
+		t.Errorf("shortfalls = %v", short)
+	}
+}
+
+func TestApplyRollsBackOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, sysfs.VMSwappiness, "60")
+	writeFixture(t, dir, sysfs.VMDirtyRatio, "20")
+	// A directory exists but cannot be written, so the third change fails.
+	if err := os.MkdirAll(filepath.Join(dir, sysfs.TCPFastOpen), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	changes := []Change{
+		{Parameter: "Swappiness", Writes: []Write{{Path: sysfs.VMSwappiness, Value: "10", Old: "60"}}},
+		{Parameter: "Dirty Ratio", Writes: []Write{{Path: sysfs.VMDirtyRatio, Value: "5", Old: "20"}}},
+		{Parameter: "TCP Fast Open", Writes: []Write{{Path: sysfs.TCPFastOpen, Value: "3", Old: "1"}}},
+	}
+
+	e := NewEngine(profile.ForType(profile.Server))
+
+	// A path that vanished fails the check before anything is written.
+	gone := append([]Change{{Parameter: "Gone", Writes: []Write{{Path: "/sys/gone", Value: "1", Old: "0"}}}}, changes...)
+	if f := Check(gone); f == nil || f.Change.Parameter != "Gone" {
+		t.Errorf("Check = %+v, want Gone", f)
+	}
+	res := e.Apply(gone, true, true)
+	if res.RolledBack || len(res.Applied) != 0 || len(res.Failed) != 1 {
+		t.Errorf("Apply of a stale plan = %+v, want one failure and no writes", res)
+	}
+	if got, _ := sysfs.ReadString(sysfs.VMSwappiness); got != "60" {
+		t.Errorf("swappiness = %q after a failed check, want 60", got)
+	}
+
+	res = e.Apply(changes, true, true)
+	if !res.RolledBack || len(res.Failed) != 1 || res.Failed[0].Change.Parameter != "TCP Fast Open" {
+		t.Fatalf("Apply = %+v, want rollback after TCP Fast Open fails", res)
+	}
+	if len(res.Reverted) != 2 || res.Reverted[0].Path != sysfs.VMDirtyRatio || res.Reverted[1].Path != sysfs.VMSwappiness {
+		t.Errorf("reverted = %+v, want dirty_ratio then swappiness", res.Reverted)
+	}
+	for path, want := range map[string]string{sysfs.VMSwappiness: "60", sysfs.VMDirtyRatio: "20"} {
+		if got, _ := sysfs.ReadString(path); got != want {
+			t.Errorf("%s = %q after rollback, want %q", path, got, want)
+		}
+	}
+
+	// Without rollback the failure is recorded and the rest stays applied.
+	res = e.Apply(changes, true, false)
+	if res.RolledBack || len(res.Applied) != 2 || len(res.Failed) != 1 {
+		t.Errorf("Apply without rollback = %+v", res)
+	}
+	if got, _ := sysfs.ReadString(sysfs.VMSwappiness); got != "10" {
+		t.Errorf("swappiness = %q, want 10 kept", got)
+	}
+}
+
+func TestStorageRuleChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "/sys/block/sda/queue/rotational", "1\n")
+	writeFixture(t, dir, "/sys/block/sda/queue/scheduler", "[bfq] mq-deadline none\n")
+	writeFixture(t, dir, "/sys/block/sda/queue/read_ahead_kb", "128\n")
+	writeFixture(t, dir, "/sys/block/sda/queue/nr_requests", "64\n")
+	writeFixture(t, dir, "/sys/block/sda/device/model", "ST16000NM001G-2K\n")
+	writeFixture(t, dir, "/sys/block/sdb/queue/rotational", "1\n")
+	writeFixture(t, dir, "/sys/block/sdb/queue/scheduler", "[bfq] mq-deadline none\n")
+	writeFixture(t, dir, "/sys/block/sdb/queue/read_ahead_kb", "128\n")
+	writeFixture(t, dir, "/sys/block/sdb/device/model", "WDC WD40EFRX\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.ReadAhead = 0
+	v.StorageRules = []profile.StorageRule{
+		{Model: "ST16000*", Scheduler: "mq-deadline", ReadAhead: profile.Int(4096), QueueValues: profile.QueueValues{NrRequests: profile.Int(256)}},
+	}
+
+	var got []string
+	for _, c := range computeStorageChanges(v) {
+		got = append(got, c.Parameter+"="+c.NewValue)
+	}
+	// Only the matching disk changes, scheduler before nr_requests.
+	want := []string{"sda scheduler=mq-deadline", "sda read_ahead_kb=4096", "sda nr_requests=256"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestQueueChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/rotational", "0\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/scheduler", "[none] mq-deadline\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/nr_requests", "1023\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/max_sectors_kb", "128\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/max_hw_sectors_kb", "256\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/rq_affinity", "1\n")
+	writeFixture(t, dir, "/sys/block/nvme0n1/queue/nomerges", "0\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	v := profile.ServerValues()
+	v.ReadAhead = 0
+	v.QueueNVMe = profile.QueueValues{
+		NrRequests:   profile.Int(1023),
+		MaxSectorsKB: profile.Int(1024),
+		RqAffinity:   profile.Int(2),
+		NoMerges:     profile.Int(2),
+		WBTLatUsec:   profile.Int(0),
+	}
+	v.QueueSSD.RqAffinity = profile.Int(1)
+
+	var got []string
+	for _, c := range computeStorageChanges(v) {
+		got = append(got, c.Parameter+"="+c.NewValue)
+	}
+	// max_sectors_kb is capped at the hardware limit; wbt_lat_usec is
+	// not exposed and nr_requests already matches.
+	want := []string{"nvme0n1 max_sectors_kb=256", "nvme0n1 rq_affinity=2", "nvme0n1 nomerges=2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestStackChanges(t *testing.T) {
+	dir := t.TempDir()
+	// md0 (raid5) over sda1, sdb1, sdc1; LVM volume vg0-data on md0.
+	for _, d := range []string{"sda", "sdb", "sdc"} {
+		writeFixture(t, dir, "/sys/block/"+d+"/queue/rotational", "1\n")
+		writeFixture(t, dir, "/sys/block/"+d+"/queue/scheduler", "[bfq] none\n")
+		writeFixture(t, dir, "/sys/block/"+d+"/queue/read_ahead_kb", "4096\n")
+		writeFixture(t, dir, "/sys/block/"+d+"/"+d+"1/partition", "1\n")
+		writeFixture(t, dir, "/sys/block/"+d+"/"+d+"1/holders/md0", "")
+		writeFixture(t, dir, "/sys/block/md0/slaves/"+d+"1", "")
+	}
+	writeFixture(t, dir, "/sys/block/md0/md/level", "raid5\n")
+	writeFixture(t, dir, "/sys/block/md0/md/stripe_cache_size", "256\n")
+	writeFixture(t, dir, "/sys/block/md0/queue/read_ahead_kb", "128\n")
+	writeFixture(t, dir, "/sys/block/md0/holders/dm-0", "")
+	writeFixture(t, dir, "/sys/block/dm-0/slaves/md0", "")
+	writeFixture(t, dir, "/sys/block/dm-0/dm/name", "vg0-data\n")
+	writeFixture(t, dir, "/sys/block/dm-0/dm/uuid", "LVM-abc\n")
+	writeFixture(t, dir, "/sys/block/dm-0/queue/read_ahead_kb", "128\n")
+
+	sysfs.SetRoot(dir)
+	defer sysfs.SetRoot("")
+
+	info := detect.DetectStorage()
+	if len(info.Disks) != 3 || len(info.Stack) != 2 {
+		t.Fatalf("got %d disks and %d stack devices, want 3 and 2", len(info.Disks), len(info.Stack))
+	}
+	for _, dev := range info.Stack {
+		if got := strings.Join(dev.Disks, ","); got != "sda,sdb,sdc" {
+			t.Errorf("%s disks = %s, want sda,sdb,sdc", dev.Name, got)
+		}
+	}
+	if got := info.Disks[0].Holders; len(got) != 1 || got[0] != "md0" {
+		t.Errorf("sda holders = %v, want [md0]", got)
+	}
+
+	v := profile.ServerValues()
+	v.ReadAhead = 4096
+	v.MDStripeCache = profile.Int(4096)
+
+	var got []string
+	for _, c := range computeStackChanges(v, info) {
+		got = append(got, c.Parameter+"="+c.NewValue)
+	}
+	// Read-ahead only on the top of the stack, stripe cache on the array.
+	want := []string{"vg0-data (dm-0) read_ahead_kb=4096", "md0 stripe_cache_size=4096"}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package tune
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// computeHugepageChanges sizes each huge page pool.
+func computeHugepageChanges(pools []profile.HugepagePool) []Change {
+	var changes []Change
+	for _, h := range pools {
+		dir := detect.HugePagePoolDir(int64(h.SizeKB), h.NodeID())
+		changes = appendOptInt(changes, "memory", "Huge Pages "+h.String(), dir+"/nr_hugepages", profile.Int(h.Count))
+	}
+	return changes
+}
+
+// HugepageProblems explains the pools of v this machine does not have,
+// an unsupported page size or a missing node, and an nr_hugepages or
+// hugepages_percent that a pool of the default size overrides.
+func HugepageProblems(v profile.Values) []string {
+	var problems []string
+	if v.WantsHugepages() {
+		if size := detect.DetectMemory().HugePageSizeKB; v.HasHugepagePool(size) {
+			problems = append(problems, fmt.Sprintf("hugepages: the %d kB pools replace nr_hugepages and hugepages_percent; skipping vm.nr_hugepages", size))
+		}
+	}
+	for _, h := range v.HugepagePools {
+		if !sysfs.Exists(detect.HugePagePoolDir(int64(h.SizeKB), h.NodeID())) {
+			problems = append(problems, fmt.Sprintf("hugepages: this machine has no pool of %s; skipping", h))
+		}
+	}
+	return problems
+}
+
+// Shortfall is a huge page pool the kernel filled only in part.
+type Shortfall struct {
+	Parameter string
+	Path      string
+	Got, Want int
+}
+
+func (s Shortfall) String() string {
+	return fmt.Sprintf("%s: the kernel reserved %d of %d pages", s.Parameter, s.Got, s.Want)
+}
+
+// HugepageShortfalls re-reads every nr_hugepages file the applied
+// changes wrote. The kernel accepts any count but reserves only the
+// pages it finds contiguous memory for, so a write that succeeded can
+// still fall short.
+func HugepageShortfalls(applied []Change) []Shortfall {
+	var out []Shortfall
+	for _, c := range applied {
+		for _, w := range c.Writes {
+			if path.Base(w.Path) != "nr_hugepages" {
+				continue
+			}
+			want, err := strconv.Atoi(w.Value)
+			if err != nil {
+				continue
+			}
+			if got, err := sysfs.ReadInt(w.Path); err == nil && got < want {
+				out = append(out, Shortfall{Parameter: c.Parameter, Path: w.Path, Got: got, Want: want})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package tune
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/krisk248/tuner/internal/detect"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+// computeIRQChanges pins each NIC's queue interrupts to its NUMA-local
+// CPUs, one queue per core where there are enough. One change per NIC;
+// interrupts already pinned where they belong are left alone.
+func computeIRQChanges(nics []detect.NICQueues) []Change {
+	var changes []Change
+	for _, n := range nics {
+		targets := n.SpreadTargets()
+		if targets == nil {
+			continue
+		}
+		var writes []Write
+		var old []int
+		for i, q := range n.IRQs {
+			for _, cpu := range q.Affinity {
+				if !slices.Contains(old, cpu) {
+					old = append(old, cpu)
+				}
+			}
+			if slices.Equal(q.Affinity, []int{targets[i]}) {
+				continue
+			}
+			writes = append(writes, Write{
+				Path:  fmt.Sprintf("%s/%d/smp_affinity_list", sysfs.ProcIRQBase, q.IRQ),
+				Value: strconv.Itoa(targets[i]),
+				Old:   sysfs.FormatCPUList(q.Affinity),
+			})
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		slices.Sort(old)
+		used := slices.Clone(targets)
+		slices.Sort(used)
+		changes = append(changes, Change{
+			Subsystem: "network",
+			Parameter: "IRQ Affinity " + n.Name,
+			OldValue:  "CPUs " + sysfs.FormatCPUList(old),
+			NewValue:  fmt.Sprintf("%d queues over CPUs %s", len(n.IRQs), sysfs.FormatCPUList(slices.Compact(used))),
+			Writes:    writes,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,16 @@
+package tune
+
+import (
+	"github.com/krisk248/tuner/internal/profile"
+	"github.com/krisk248/tuner/internal/sysfs"
+)
+
+func computeKernelChanges(v profile.Values) []Change {
+	var changes []Change
+
+	changes = appendOptInt(changes, "kernel", "Inotify Watches", sysfs.InotifyMaxWatches, v.InotifyWatches)
+	changes = appendOptInt(changes, "kernel", "Inotify Instances", sysfs.InotifyMaxInstances, v.InotifyInstances)
+	changes = appendOptInt(changes, "kernel", "PID Max", sysfs.PidMax, v.PidMax)
+
+	return changes
+}
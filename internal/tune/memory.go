@@ -3,6 +3,7 @@ package tune
 import (
 	"fmt"
 
+	"github.com/krisk248/tuner/internal/detect"
 	"github.com/krisk248/tuner/internal/profile"
 	"github.com/krisk248/tuner/internal/sysfs"
 )
@@ -18,40 +19,35 @@ func computeMemoryChanges(v profile.Values) []Change {
 			Parameter: "Swappiness",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMSwappiness,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMSwappiness, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMSwappiness, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
-	// Dirty background ratio
-	if cur, err := sysfs.ReadInt(sysfs.VMDirtyBgRatio); err == nil && cur != v.DirtyBgRatio {
+	// Dirty background limit, as bytes or a ratio
+	if v.DirtyBgBytes.Set {
+		changes = appendDirtyBytes(changes, "Dirty BG Bytes", sysfs.VMDirtyBgBytes, sysfs.VMDirtyBgRatio, v.DirtyBgBytes.Value)
+	} else if cur, err := sysfs.ReadInt(sysfs.VMDirtyBgRatio); err == nil && cur != v.DirtyBgRatio {
 		target := v.DirtyBgRatio
 		changes = append(changes, Change{
 			Subsystem: "memory",
 			Parameter: "Dirty BG Ratio",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMDirtyBgRatio,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMDirtyBgRatio, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMDirtyBgRatio, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
-	// Dirty ratio
-	if cur, err := sysfs.ReadInt(sysfs.VMDirtyRatio); err == nil && cur != v.DirtyRatio {
+	// Dirty limit, as bytes or a ratio
+	if v.DirtyBytes.Set {
+		changes = appendDirtyBytes(changes, "Dirty Bytes", sysfs.VMDirtyBytes, sysfs.VMDirtyRatio, v.DirtyBytes.Value)
+	} else if cur, err := sysfs.ReadInt(sysfs.VMDirtyRatio); err == nil && cur != v.DirtyRatio {
 		target := v.DirtyRatio
 		changes = append(changes, Change{
 			Subsystem: "memory",
 			Parameter: "Dirty Ratio",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMDirtyRatio,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMDirtyRatio, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMDirtyRatio, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -63,10 +59,7 @@ func computeMemoryChanges(v profile.Values) []Change {
 			Parameter: "Dirty Expire",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMDirtyExpire,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMDirtyExpire, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMDirtyExpire, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -78,10 +71,7 @@ func computeMemoryChanges(v profile.Values) []Change {
 			Parameter: "Dirty Writeback",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMDirtyWriteback,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMDirtyWriteback, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMDirtyWriteback, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -93,10 +83,7 @@ func computeMemoryChanges(v profile.Values) []Change {
 			Parameter: "VFS Cache Pressure",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.VMVFSCachePressure,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.VMVFSCachePressure, target)
-			},
+			Writes:    []Write{{Path: sysfs.VMVFSCachePressure, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -108,12 +95,104 @@ func computeMemoryChanges(v profile.Values) []Change {
 			Parameter: "THP",
 			OldValue:  cur,
 			NewValue:  target,
-			Path:      sysfs.THPEnabled,
-			ApplyFunc: func() error {
-				return sysfs.WriteString(sysfs.THPEnabled, target)
-			},
+			Writes:    []Write{{Path: sysfs.THPEnabled, Value: target, Old: cur}},
 		})
 	}
 
+	// THP defrag
+	if v.THPDefrag != "" {
+		if cur, err := sysfs.ReadBracketedValue(sysfs.THPDefrag); err == nil && cur != v.THPDefrag {
+			target := v.THPDefrag
+			changes = append(changes, Change{
+				Subsystem: "memory",
+				Parameter: "THP Defrag",
+				OldValue:  cur,
+				NewValue:  target,
+				Writes:    []Write{{Path: sysfs.THPDefrag, Value: target, Old: cur}},
+			})
+		}
+	}
+
+	// zswap
+	if v.Zswap != "" {
+		if cur, err := sysfs.ReadString(sysfs.ZswapEnabled); err == nil {
+			target := "N"
+			if v.Zswap == "on" {
+				target = "Y"
+			}
+			if cur != target {
+				changes = append(changes, Change{
+					Subsystem: "memory",
+					Parameter: "Zswap",
+					OldValue:  cur,
+					NewValue:  target,
+					Writes:    []Write{{Path: sysfs.ZswapEnabled, Value: target, Old: cur}},
+				})
+			}
+		}
+	}
+	if v.ZswapCompressor != "" {
+		if cur, err := sysfs.ReadString(sysfs.ZswapCompressor); err == nil && cur != v.ZswapCompressor {
+			target := v.ZswapCompressor
+			changes = append(changes, Change{
+				Subsystem: "memory",
+				Parameter: "Zswap Compressor",
+				OldValue:  cur,
+				NewValue:  target,
+				Writes:    []Write{{Path: sysfs.ZswapCompressor, Value: target, Old: cur}},
+			})
+		}
+	}
+	changes = appendOptInt(changes, "memory", "Zswap Max Pool", sysfs.ZswapMaxPool, v.ZswapMaxPool)
+
+	// Huge pages
+	if v.WantsHugepages() {
+		if mem := detect.DetectMemory(); !v.HasHugepagePool(mem.HugePageSizeKB) {
+			target := v.NrHugepages(mem.TotalKB, mem.HugePageSizeKB)
+			changes = appendOptInt(changes, "memory", "Huge Pages", sysfs.VMNrHugepages, profile.Int(target))
+		}
+	}
+	changes = append(changes, computeHugepageChanges(v.HugepagePools)...)
+	for _, a := range v.Khugepaged.Attrs() {
+		changes = appendOptInt(changes, "memory", "Khugepaged "+a.Name, sysfs.KhugepagedBase+"/"+a.Name, profile.Int(a.Value))
+	}
+
+	// Kernel samepage merging
+	changes = appendOptInt(changes, "memory", "KSM", sysfs.KSMRun, v.KSM)
+
+	// NUMA policy; a single node has nothing to balance or reclaim.
+	if detect.DetectNUMA().MultiNode() {
+		changes = appendOptInt(changes, "memory", "NUMA Balancing", sysfs.NUMABalancing, v.NUMABalancing)
+		changes = appendOptInt(changes, "memory", "Zone Reclaim", sysfs.VMZoneReclaim, v.ZoneReclaim)
+	}
+
 	return changes
 }
+
+// appendDirtyBytes switches a dirty limit to its byte form. The kernel
+// keeps only one of each bytes/ratio pair non-zero, so when the limit is
+// currently a ratio the write restores the ratio: writing it back also
+// clears the bytes value.
+func appendDirtyBytes(changes []Change, param, bytesPath, ratioPath string, target int) []Change {
+	cur, err := sysfs.ReadInt(bytesPath)
+	if err != nil || cur == target {
+		return changes
+	}
+	c := Change{
+		Subsystem: "memory",
+		Parameter: param,
+		OldValue:  fmt.Sprintf("%d", cur),
+		NewValue:  fmt.Sprintf("%d", target),
+		Writes:    []Write{{Path: bytesPath, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
+	}
+	if cur == 0 {
+		ratio, err := sysfs.ReadInt(ratioPath)
+		if err != nil {
+			return changes
+		}
+		c.OldValue = fmt.Sprintf("ratio %d%%", ratio)
+		c.Writes[0].Old = fmt.Sprintf("%d", ratio)
+		c.Writes[0].RestorePath = ratioPath
+	}
+	return append(changes, c)
+}
@@ -18,10 +18,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "TCP Congestion",
 			OldValue:  cur,
 			NewValue:  target,
-			Path:      sysfs.TCPCongestion,
-			ApplyFunc: func() error {
-				return sysfs.WriteString(sysfs.TCPCongestion, target)
-			},
+			Writes:    []Write{{Path: sysfs.TCPCongestion, Value: target, Old: cur}},
 		})
 	}
 
@@ -33,10 +30,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "TCP Fast Open",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.TCPFastOpen,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.TCPFastOpen, target)
-			},
+			Writes:    []Write{{Path: sysfs.TCPFastOpen, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -48,10 +42,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "TCP MTU Probing",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.TCPMTUProbing,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.TCPMTUProbing, target)
-			},
+			Writes:    []Write{{Path: sysfs.TCPMTUProbing, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -63,10 +54,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "Recv Buffer Max",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.NetCoreBufMax,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.NetCoreBufMax, target)
-			},
+			Writes:    []Write{{Path: sysfs.NetCoreBufMax, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -78,10 +66,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "Send Buffer Max",
 			OldValue:  fmt.Sprintf("%d", cur),
 			NewValue:  fmt.Sprintf("%d", target),
-			Path:      sysfs.NetCoreWBufMax,
-			ApplyFunc: func() error {
-				return sysfs.WriteInt(sysfs.NetCoreWBufMax, target)
-			},
+			Writes:    []Write{{Path: sysfs.NetCoreWBufMax, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", cur)}},
 		})
 	}
 
@@ -93,10 +78,7 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "TCP Rmem",
 			OldValue:  cur,
 			NewValue:  target,
-			Path:      sysfs.TCPRmem,
-			ApplyFunc: func() error {
-				return sysfs.WriteString(sysfs.TCPRmem, target)
-			},
+			Writes:    []Write{{Path: sysfs.TCPRmem, Value: target, Old: cur}},
 		})
 	}
 
@@ -108,12 +90,14 @@ func computeNetworkChanges(v profile.Values) []Change {
 			Parameter: "TCP Wmem",
 			OldValue:  cur,
 			NewValue:  target,
-			Path:      sysfs.TCPWmem,
-			ApplyFunc: func() error {
-				return sysfs.WriteString(sysfs.TCPWmem, target)
-			},
+			Writes:    []Write{{Path: sysfs.TCPWmem, Value: target, Old: cur}},
 		})
 	}
 
+	changes = appendOptInt(changes, "network", "Somaxconn", sysfs.Somaxconn, v.Somaxconn)
+	changes = appendOptInt(changes, "network", "Conntrack Max", sysfs.ConntrackMax, v.ConntrackMax)
+	changes = appendOptInt(changes, "network", "Busy Poll", sysfs.NetCoreBusyPoll, v.BusyPoll)
+	changes = appendOptInt(changes, "network", "Busy Read", sysfs.NetCoreBusyRead, v.BusyRead)
+
 	return changes
 }
@@ -15,42 +15,103 @@ func computeStorageChanges(v profile.Values) []Change {
 	storageInfo := detect.DetectStorage()
 
 	for _, disk := range storageInfo.Disks {
-		recommended := v.IOScheduler(disk.Type)
+		dv := v.ForDisk(disk.ID())
 
-		// Scheduler
-		if disk.Scheduler != recommended {
+		// Scheduler first: switching schedulers resets nr_requests.
+		if disk.Scheduler != dv.Scheduler {
 			schedPath := filepath.Join(sysfs.BlockBase, disk.Name, "queue/scheduler")
-			target := recommended
+			target := dv.Scheduler
 			old := disk.Scheduler
 			changes = append(changes, Change{
 				Subsystem: "storage",
 				Parameter: fmt.Sprintf("%s scheduler", disk.Name),
 				OldValue:  old,
 				NewValue:  target,
-				Path:      schedPath,
-				ApplyFunc: func() error {
-					return sysfs.WriteString(schedPath, target)
-				},
+				Writes:    []Write{{Path: schedPath, Value: target, Old: old}},
 			})
 		}
 
 		// Read ahead
-		if v.ReadAhead > 0 && disk.ReadAhead != v.ReadAhead {
+		if dv.ReadAhead > 0 && disk.ReadAhead != dv.ReadAhead {
 			raPath := filepath.Join(sysfs.BlockBase, disk.Name, "queue/read_ahead_kb")
-			target := v.ReadAhead
+			target := dv.ReadAhead
 			old := disk.ReadAhead
 			changes = append(changes, Change{
 				Subsystem: "storage",
 				Parameter: fmt.Sprintf("%s read_ahead_kb", disk.Name),
 				OldValue:  fmt.Sprintf("%d", old),
 				NewValue:  fmt.Sprintf("%d", target),
-				Path:      raPath,
-				ApplyFunc: func() error {
-					return sysfs.WriteInt(raPath, target)
-				},
+				Writes:    []Write{{Path: raPath, Value: fmt.Sprintf("%d", target), Old: fmt.Sprintf("%d", old)}},
 			})
 		}
+
+		changes = append(changes, computeQueueChanges(disk, dv.Queue)...)
+	}
+
+	changes = append(changes, computeStackChanges(v, storageInfo)...)
+	return changes
+}
+
+// computeQueueChanges sets the queue attributes for one disk, skipping
+// attributes the kernel does not expose for it.
+func computeQueueChanges(disk detect.DiskInfo, q profile.QueueValues) []Change {
+	var changes []Change
+	for _, a := range q.Attrs() {
+		cur, ok := disk.Queue[a.Name]
+		if !ok {
+			continue
+		}
+		target := a.Value
+		// Writes above the hardware limit fail with EINVAL.
+		if a.Name == "max_sectors_kb" && disk.MaxHWSectorsKB > 0 {
+			target = min(target, disk.MaxHWSectorsKB)
+		}
+		if target == cur {
+			continue
+		}
+		old := fmt.Sprintf("%d", cur)
+		value := fmt.Sprintf("%d", target)
+		changes = append(changes, Change{
+			Subsystem: "storage",
+			Parameter: fmt.Sprintf("%s %s", disk.Name, a.Name),
+			OldValue:  old,
+			NewValue:  value,
+			Writes:    []Write{{Path: filepath.Join(sysfs.BlockBase, disk.Name, "queue", a.Name), Value: value, Old: old}},
+		})
 	}
+	return changes
+}
 
+// computeStackChanges sets read-ahead on top-level dm/md devices, where
+// filesystem reads actually happen, and the md RAID stripe cache.
+// Schedulers stay on the physical disks above.
+func computeStackChanges(v profile.Values, info detect.StorageInfo) []Change {
+	var changes []Change
+	for _, dev := range info.Stack {
+		if ra := v.StackReadAhead(info.DiskIDs(dev)); dev.TopLevel() && ra > 0 && dev.ReadAhead != ra {
+			old := fmt.Sprintf("%d", dev.ReadAhead)
+			target := fmt.Sprintf("%d", ra)
+			changes = append(changes, Change{
+				Subsystem: "storage",
+				Parameter: fmt.Sprintf("%s read_ahead_kb", dev.Label()),
+				OldValue:  old,
+				NewValue:  target,
+				Writes:    []Write{{Path: filepath.Join(sysfs.BlockBase, dev.Name, "queue/read_ahead_kb"), Value: target, Old: old}},
+			})
+		}
+
+		// Only raid4/5/6 have a stripe cache.
+		if v.MDStripeCache.Set && dev.StripeCache > 0 && dev.StripeCache != v.MDStripeCache.Value {
+			old := fmt.Sprintf("%d", dev.StripeCache)
+			target := fmt.Sprintf("%d", v.MDStripeCache.Value)
+			changes = append(changes, Change{
+				Subsystem: "storage",
+				Parameter: fmt.Sprintf("%s stripe_cache_size", dev.Name),
+				OldValue:  old,
+				NewValue:  target,
+				Writes:    []Write{{Path: filepath.Join(sysfs.BlockBase, dev.Name, "md/stripe_cache_size"), Value: target, Old: old}},
+			})
+		}
+	}
 	return changes
 }
@@ -179,7 +179,7 @@ func networkLines(prevRx, prevTx int64, first bool) ([]string, int64, int64) {
 }
 
 func getNetBytes() (rx, tx int64) {
-	entries, err := os.ReadDir(sysfs.NetBase)
+	entries, err := sysfs.ReadDir(sysfs.NetBase)
 	if err != nil {
 		return 0, 0
 	}